@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// baseModelFields are the fields of mdl.BaseModel, which isn't parsed as it's of another package
+var baseModelFields = []string{"ID", "CreatedAt", "UpdatedAt", "DeletedAt"}
+
+// field is a field of a model, which is a relation if it's (a slice of or pointer to) another model
+type field struct {
+	name     string
+	relation string // the type of the other model, "" if it's not a relation
+}
+
+// models are the struct types of a package, by their names
+type models map[string]*ast.StructType
+
+// parseModels returns the struct types of the files of a package
+func parseModels(files []*ast.File) models {
+	m := make(models)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					if st, ok := ts.Type.(*ast.StructType); ok && ts.TypeParams == nil {
+						m[ts.Name.Name] = st
+					}
+				}
+			}
+		}
+	}
+	return m
+}
+
+// isModel is whether the struct type of name embeds mdl.BaseModel, itself or by another struct
+func (m models) isModel(name string) bool {
+	return m.embedsBaseModel(name, make(map[string]bool))
+}
+
+func (m models) embedsBaseModel(name string, visited map[string]bool) bool {
+	st, ok := m[name]
+	if !ok || visited[name] {
+		return false
+	}
+	visited[name] = true
+
+	for _, f := range st.Fields.List {
+		if len(f.Names) != 0 {
+			continue
+		}
+		switch typ := stripPointer(f.Type).(type) {
+		case *ast.SelectorExpr:
+			if typ.Sel.Name == "BaseModel" {
+				return true
+			}
+		case *ast.Ident:
+			if typ.Name == "BaseModel" || m.embedsBaseModel(typ.Name, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldsOf returns the fields of the model of name, with those of embedded structs
+func (m models) fieldsOf(name string) []field {
+	fields := make([]field, 0)
+	for _, f := range m[name].Fields.List {
+		if isOmitted(f) {
+			continue
+		}
+
+		if len(f.Names) == 0 { // embedded
+			switch typ := stripPointer(f.Type).(type) {
+			case *ast.SelectorExpr:
+				if typ.Sel.Name == "BaseModel" {
+					for _, name := range baseModelFields {
+						fields = append(fields, field{name: name})
+					}
+				}
+			case *ast.Ident:
+				if _, ok := m[typ.Name]; ok {
+					fields = append(fields, m.fieldsOf(typ.Name)...)
+				}
+			}
+			continue
+		}
+
+		relation := ""
+		if typ, ok := stripSliceAndPointer(f.Type).(*ast.Ident); ok && m.isModel(typ.Name) {
+			relation = typ.Name
+		}
+		for _, n := range f.Names {
+			if n.IsExported() {
+				fields = append(fields, field{name: n.Name, relation: relation})
+			}
+		}
+	}
+	return fields
+}
+
+// isOmitted is whether f is not a column or a relation, by gorm:"-"
+func isOmitted(f *ast.Field) bool {
+	if f.Tag == nil {
+		return false
+	}
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(reflect.StructTag(tag).Get("gorm")) == "-"
+}
+
+func stripPointer(expr ast.Expr) ast.Expr {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		return star.X
+	}
+	return expr
+}
+
+func stripSliceAndPointer(expr ast.Expr) ast.Expr {
+	for {
+		switch typ := expr.(type) {
+		case *ast.StarExpr:
+			expr = typ.X
+		case *ast.ArrayType:
+			expr = typ.Elt
+		default:
+			return expr
+		}
+	}
+}
+
+// generate returns the source of the fields of the models of names (every model if none) of
+// the package pkg, as <model>F
+func generate(pkg string, m models, names []string) ([]byte, error) {
+	if len(names) == 0 {
+		for name := range m {
+			if m.isModel(name) && ast.IsExported(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var decls, inits bytes.Buffer
+	for _, name := range names {
+		if !m.isModel(name) {
+			return nil, fmt.Errorf("%s is not a model (which embeds mdl.BaseModel)", name)
+		}
+		v := name + "F"
+		fmt.Fprintf(&decls, "// %s is the fields of %s, whose predicates are the criteria of qry.C\n", v, name)
+		fmt.Fprintf(&decls, "var %s ", v)
+		m.writeFields(&decls, &inits, name, v, "", map[string]bool{name: true})
+		decls.WriteString("\n\n")
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by qrygen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", pkg)
+	src.WriteString("import \"github.com/t2wu/qry\"\n\n")
+	src.Write(decls.Bytes())
+	if inits.Len() > 0 {
+		src.WriteString("func init() {\n")
+		src.Write(inits.Bytes())
+		src.WriteString("}\n")
+	}
+	return format.Source(src.Bytes())
+}
+
+// writeFields writes the struct type of the fields of the model of name to decls, and the
+// assignment of their designators to inits. A relation to a model which it's nested in
+// (on path) is left out, so the type is not recursive.
+func (m models) writeFields(decls, inits *bytes.Buffer, name, v, designator string, path map[string]bool) {
+	decls.WriteString("struct {\n")
+	for _, f := range m.fieldsOf(name) {
+		d := f.name
+		if designator != "" {
+			d = designator + "." + f.name
+		}
+
+		if f.relation == "" {
+			fmt.Fprintf(decls, "%s qry.Field\n", f.name)
+			fmt.Fprintf(inits, "%s.%s = %q\n", v, d, d)
+			continue
+		}
+
+		if path[f.relation] {
+			continue
+		}
+		path[f.relation] = true
+		fmt.Fprintf(decls, "%s ", f.name)
+		m.writeFields(decls, inits, f.relation, v, d, path)
+		decls.WriteString("\n")
+		delete(path, f.relation)
+	}
+	decls.WriteString("}")
+}
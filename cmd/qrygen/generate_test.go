@@ -0,0 +1,124 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const modelsSrc = `package models
+
+import (
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+)
+
+type TestModel struct {
+	mdl.BaseModel
+	Name string ` + "`gorm:\"column:real_name_column\" json:\"name\"`" + `
+	Age  int
+
+	Dogs        []Dog ` + "`betterrest:\"peg\" json:\"dogs\"`" + `
+	FavoriteDog *Dog  ` + "`betterrest:\"peg\" json:\"favoriteDog\"`" + `
+	Note        string ` + "`gorm:\"-\"`" + `
+	secret      string
+}
+
+type Dog struct {
+	mdl.BaseModel
+	Name    string
+	DogToys []DogToy
+	Owner   *TestModel
+
+	TestModelID *datatype.UUID
+}
+
+type DogToy struct {
+	mdl.BaseModel
+	ToyName string
+}
+
+type notAModel struct {
+	Name string
+}
+`
+
+func parseSrc(t *testing.T, src string) models {
+	file, err := parser.ParseFile(token.NewFileSet(), "models.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseModels([]*ast.File{file})
+}
+
+const wantDogToy = `// DogToyF is the fields of DogToy, whose predicates are the criteria of qry.C
+var DogToyF struct {
+	ID        qry.Field
+	CreatedAt qry.Field
+	UpdatedAt qry.Field
+	DeletedAt qry.Field
+	ToyName   qry.Field
+}
+`
+
+func TestGenerate_ShouldHaveFieldsOfRelations(t *testing.T) {
+	src, err := generate("models", parseSrc(t, modelsSrc), nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	got := string(src)
+	assert.Contains(t, got, "// Code generated by qrygen. DO NOT EDIT.\n\npackage models\n\nimport \"github.com/t2wu/qry\"\n")
+	assert.Contains(t, got, wantDogToy)
+	assert.Contains(t, got, "var TestModelF struct {\n")
+	assert.Contains(t, got, "\tTestModelF.Name = \"Name\"\n")
+	assert.Contains(t, got, "\tTestModelF.Dogs.DogToys.ToyName = \"Dogs.DogToys.ToyName\"\n")
+	assert.Contains(t, got, "\tTestModelF.FavoriteDog.ID = \"FavoriteDog.ID\"\n")
+	assert.Contains(t, got, "\tDogF.Owner.Age = \"Owner.Age\"\n")
+	assert.NotContains(t, got, "DogF.Owner.Dogs") // which Owner is nested in
+	assert.NotContains(t, got, "TestModelF.Dogs.Owner")
+	assert.NotContains(t, got, "Note")
+	assert.NotContains(t, got, "secret")
+	assert.NotContains(t, got, "notAModel")
+}
+
+func TestGenerate_Types_ShouldOnlyBeThoseModels(t *testing.T) {
+	src, err := generate("models", parseSrc(t, modelsSrc), []string{"DogToy"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "// Code generated by qrygen. DO NOT EDIT.\n\npackage models\n\nimport \"github.com/t2wu/qry\"\n\n"+
+			wantDogToy+"\nfunc init() {\n"+
+			"\tDogToyF.ID = \"ID\"\n\tDogToyF.CreatedAt = \"CreatedAt\"\n\tDogToyF.UpdatedAt = \"UpdatedAt\"\n"+
+			"\tDogToyF.DeletedAt = \"DeletedAt\"\n\tDogToyF.ToyName = \"ToyName\"\n}\n", string(src))
+	}
+
+	_, err = generate("models", parseSrc(t, modelsSrc), []string{"notAModel"})
+	assert.Error(t, err)
+}
+
+func TestRun_ShouldWriteTheOutputOfThePackage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(modelsSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "models_test.go"), []byte("package models_test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// run again, so the output isn't parsed
+	for i := 0; i < 2; i++ {
+		if err := run(dir, "fields.go", "Dog,DogToy"); !assert.Nil(t, err) {
+			return
+		}
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, "fields.go"))
+	if assert.Nil(t, err) {
+		assert.Contains(t, string(src), "var DogF struct")
+		assert.NotContains(t, string(src), "var TestModelF struct")
+	}
+}
@@ -0,0 +1,79 @@
+// Qrygen generates the fields of the models of a package, so the criteria of qry are checked
+// at compile time rather than when they're run:
+//
+//	qry.Q(db, qry.C("Dogs.Name =", "Doggie1"))
+//	qry.Q(db, TestModelF.Dogs.Name.Eq("Doggie1"))
+//
+// A model is a struct which embeds mdl.BaseModel. Its fields are <model>F, with a qry.Field of
+// each field, and the fields of the models it's related to (such as by betterrest:"peg"), other
+// than those it's nested in. Fields tagged gorm:"-" are left out.
+//
+// Usage, in the package of the models:
+//
+//	//go:generate go run github.com/t2wu/qry/cmd/qrygen -output qry_fields.go
+//
+// Flags:
+//
+//	-dir     the directory of the package (default ".")
+//	-output  the file written in it (default "qry_fields.go")
+//	-type    the models, separated by commas (default every one)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "the directory of the package of the models")
+	output := flag.String("output", "qry_fields.go", "the file written in the directory")
+	types := flag.String("type", "", "the models, separated by commas (default every one)")
+	flag.Parse()
+
+	if err := run(*dir, *output, *types); err != nil {
+		fmt.Fprintf(os.Stderr, "qrygen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output, types string) error {
+	fset := token.NewFileSet()
+	filter := func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != output
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, 0)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("%d packages in %s, rather than one", len(pkgs), dir)
+	}
+
+	for name, pkg := range pkgs {
+		files := make([]*ast.File, 0, len(pkg.Files))
+		for _, file := range pkg.Files {
+			files = append(files, file)
+		}
+
+		var names []string
+		if types != "" {
+			for _, t := range strings.Split(types, ",") {
+				names = append(names, strings.TrimSpace(t))
+			}
+		}
+
+		src, err := generate(name, parseModels(files), names)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, output), src, 0644)
+	}
+	return nil
+}
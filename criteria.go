@@ -2,8 +2,10 @@ package qry
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
+	"github.com/t2wu/qry/dialect"
 	"github.com/t2wu/qry/mdl"
 )
 
@@ -27,6 +29,10 @@ const (
 	PredicateCondIN PredicateCond = "IN"
 	// PredicateCondBETWEEN is between two values
 	PredicateCondBETWEEN PredicateCond = "BETWEEN"
+	// PredicateCondISNULL is NULL, which has no value (see Field.IsNull)
+	PredicateCondISNULL PredicateCond = "IS NULL"
+	// PredicateCondISNOTNULL is not NULL, which has no value (see Field.IsNotNull)
+	PredicateCondISNOTNULL PredicateCond = "IS NOT NULL"
 )
 
 func StringToPredicateCond(s string) (PredicateCond, error) {
@@ -48,7 +54,7 @@ func StringToPredicateCond(s string) (PredicateCond, error) {
 		return PredicateCondBETWEEN, nil
 	}
 
-	return PredicateCondEQ, fmt.Errorf("not a PredicateCond string")
+	return PredicateCondEQ, newBuilderError("not a PredicateCond string")
 }
 
 type PredicateLogic string
@@ -97,7 +103,13 @@ type Escape struct {
 // BuildQuryStringAndValues output proper query conditionals and the correponding values
 // which field those fields
 // Because this then is given to the database, the output needs to match the column names
+// Table names are quoted as the dialect in use (dialect.Current()), not the one of the db it's
+// given to, which qry's own statements are quoted as
 func (p *Predicate) BuildQueryStringAndValues(modelObj mdl.IModel) (string, []interface{}, error) {
+	return p.buildQueryStringAndValues(modelObj, dialect.Current())
+}
+
+func (p *Predicate) buildQueryStringAndValues(modelObj mdl.IModel, d dialect.Dialect) (string, []interface{}, error) {
 	// Check if it's inner
 	var err error
 	col := ""
@@ -115,7 +127,7 @@ func (p *Predicate) BuildQueryStringAndValues(modelObj mdl.IModel) (string, []in
 		}
 	}
 
-	tblName = mdl.GetTableNameFromIModel(currModelObj)
+	tblName = d.Quote(mdl.GetTableNameFromIModel(currModelObj))
 	col, err = fieldToColumn(currModelObj, field)
 	if err != nil {
 		return "", nil, err
@@ -123,17 +135,25 @@ func (p *Predicate) BuildQueryStringAndValues(modelObj mdl.IModel) (string, []in
 
 	// The "IN" case, where p.Value is a slice, only one question mark is needed
 	if p.Cond == PredicateCondIN {
-		return fmt.Sprintf("\"%s\".%s %s (?)", tblName, col, p.Cond), []interface{}{p.Value}, nil
+		return fmt.Sprintf("%s.%s %s (?)", tblName, col, p.Cond), []interface{}{p.Value}, nil
 	}
 
 	if p.Cond == PredicateCondBETWEEN {
-		return fmt.Sprintf("\"%s\".%s BETWEEN ? AND ?", tblName, col), []interface{}{p.Value}, nil
+		v := reflect.ValueOf(p.Value)
+		if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Len() != 2 {
+			return "", nil, newBuilderError("value of \"%s BETWEEN\" is not a slice of two", p.Field)
+		}
+		return fmt.Sprintf("%s.%s BETWEEN ? AND ?", tblName, col), []interface{}{v.Index(0).Interface(), v.Index(1).Interface()}, nil
+	}
+
+	if p.Cond == PredicateCondISNULL || p.Cond == PredicateCondISNOTNULL {
+		return fmt.Sprintf("%s.%s %s", tblName, col, p.Cond), []interface{}{}, nil
 	}
 
 	if escape, ok := p.Value.(*Escape); ok {
-		return fmt.Sprintf("\"%s\".%s %s %s", tblName, col, p.Cond, escape.Value), []interface{}{}, nil
+		return fmt.Sprintf("%s.%s %s %s", tblName, col, p.Cond, escape.Value), []interface{}{}, nil
 	} else {
-		return fmt.Sprintf("\"%s\".%s %s ?", tblName, col, p.Cond), []interface{}{p.Value}, nil
+		return fmt.Sprintf("%s.%s %s ?", tblName, col, p.Cond), []interface{}{p.Value}, nil
 	}
 }
 
@@ -182,7 +202,7 @@ func (p *Predicate) GetNestedLevel() int {
 func NewPredicateFromStringAndVal(s string, value interface{}) (*Predicate, error) {
 	toks := strings.Split(strings.TrimSpace(s), " ")
 	if len(toks) != 2 {
-		return nil, fmt.Errorf("PredicateFromString format incorrect")
+		return nil, newBuilderError("PredicateFromString format incorrect")
 	}
 
 	cond, err := StringToPredicateCond(toks[1])
@@ -216,12 +236,17 @@ type PredicateRelation struct {
 	Logics     []PredicateLogic // AND or OR. The number of Logic operators is one less than the number of predicates
 }
 
+// BuildQueryStringAndValues is as Predicate's, joined by the logics
 func (pr *PredicateRelation) BuildQueryStringAndValues(modelObj mdl.IModel) (string, []interface{}, error) {
+	return pr.buildQueryStringAndValues(modelObj, dialect.Current())
+}
+
+func (pr *PredicateRelation) buildQueryStringAndValues(modelObj mdl.IModel, d dialect.Dialect) (string, []interface{}, error) {
 	operand := pr.PredOrRels[0]
 	values := make([]interface{}, 0)
 	isPred := false
 
-	str, vals, err := operand.BuildQueryStringAndValues(modelObj)
+	str, vals, err := buildCriteria(operand, modelObj, d)
 	if err != nil {
 		return "", nil, err
 	}
@@ -241,7 +266,7 @@ func (pr *PredicateRelation) BuildQueryStringAndValues(modelObj mdl.IModel) (str
 	for i, operand := range pr.PredOrRels[1:] {
 		var s string
 
-		s, vals, err = operand.BuildQueryStringAndValues(modelObj)
+		s, vals, err = buildCriteria(operand, modelObj, d)
 		if err != nil {
 			return "", nil, err
 		}
@@ -287,6 +312,17 @@ func (pr *PredicateRelation) GetAllUnqueStructFieldDesignator() map[string]inter
 	return m
 }
 
+// buildCriteria builds criteria of the dialect d
+func buildCriteria(criteria Criteria, modelObj mdl.IModel, d dialect.Dialect) (string, []interface{}, error) {
+	switch c := criteria.(type) {
+	case *Predicate:
+		return c.buildQueryStringAndValues(modelObj, d)
+	case *PredicateRelation:
+		return c.buildQueryStringAndValues(modelObj, d)
+	}
+	return criteria.BuildQueryStringAndValues(modelObj)
+}
+
 // normalize query to column name query
 func fieldToColumn(obj mdl.IModel, field string) (string, error) {
 	col, err := mdl.FieldNameToColumn(obj, field) // this traverses the inner struct as well
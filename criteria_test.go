@@ -1,6 +1,7 @@
 package qry
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -308,11 +309,12 @@ func TestBuildQueryStringAndValueForBetweenClause_Works(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, test.want.s, s)
 
-		if assert.Equal(t, 1, len(vals)) {
-			v2, ok := vals[0].([]time.Time)
-			if ok {
-				assert.Equal(t, test.want.v[0].UnixNano(), v2[0].UnixNano())
-				assert.Equal(t, test.want.v[1].UnixNano(), v2[1].UnixNano())
+		if assert.Equal(t, 2, len(vals)) {
+			lo, ok1 := vals[0].(time.Time)
+			hi, ok2 := vals[1].(time.Time)
+			if ok1 && ok2 {
+				assert.Equal(t, test.want.v[0].UnixNano(), lo.UnixNano())
+				assert.Equal(t, test.want.v[1].UnixNano(), hi.UnixNano())
 			} else {
 				assert.Fail(t, "wrong type")
 			}
@@ -776,3 +778,18 @@ func TestRelation_GetAllUnqueStructFieldDesignator_Works(t *testing.T) {
 	}
 
 }
+
+func TestQ_IncorrectArguments_ShouldGiveBuilderError(t *testing.T) {
+	err := Q(nil, "Name =", "same").Error()
+	var builderErr *BuilderError
+	assert.True(t, errors.As(err, &builderErr))
+}
+
+func TestQ_NonExistingField_ShouldGiveInvalidFieldError(t *testing.T) {
+	err := Q(db, C("Bogus =", "same")).First(&TestModel{}).Error()
+	var fieldErr *InvalidFieldError
+	if assert.True(t, errors.As(err, &fieldErr)) {
+		assert.Equal(t, "TestModel", fieldErr.Model)
+		assert.Equal(t, "Bogus", fieldErr.Field)
+	}
+}
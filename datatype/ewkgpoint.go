@@ -2,10 +2,11 @@ package datatype
 
 import (
 	"database/sql/driver"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 
+	"github.com/t2wu/qry/dialect"
+
 	"github.com/twpayne/go-geom"
 	"github.com/twpayne/go-geom/encoding/wkb"
 	// Might be able to use ewkb library with PostGis if I could figure out how to work with go-geom
@@ -31,19 +32,14 @@ type EWKBPoint struct {
 func (m *EWKBPoint) Value() (driver.Value, error) {
 	// When updating a pegassoc but not give any value, it runs into this
 	if m == nil { // for gormv1
-		return "SRID=0;POINT(0 0)", nil
+		return dialect.Current().GeometryValue(geom.NewPoint(geom.XY).SetSRID(0))
 	}
 	if m.Point.Point == nil { // for gormv2
 		return nil, nil
 	}
 
-	pt := m.Point
-	pos := pt.Coords()
-
-	// How is it stored? I can't tell.
-
-	// return fmt.Sprintf("SRID=32632;POINT(%f %f)", pos[0], pos[1]), nil
-	return fmt.Sprintf("SRID=0;POINT(%f %f)", pos[0], pos[1]), nil
+	// How it's stored depends on the database
+	return dialect.Current().GeometryValue(m.Point.Point)
 }
 
 // Scan satisfies the Scanner interace and is responsible for reading data from the database
@@ -60,12 +56,12 @@ func (m *EWKBPoint) Scan(src interface{}) error {
 	// 	return err
 	// }
 
-	hexdata, err := hex.DecodeString(string(src.([]byte)))
+	data, err := dialect.Current().ScanGeometry(src)
 	if err != nil {
 		return err
 	}
 
-	m.Point.Scan(hexdata)
+	m.Point.Scan(data)
 
 	// Determine the geometery type
 	// var byteOrder binary.ByteOrder
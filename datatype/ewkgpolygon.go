@@ -2,10 +2,10 @@ package datatype
 
 import (
 	"database/sql/driver"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"strings"
+
+	"github.com/t2wu/qry/dialect"
 
 	"github.com/twpayne/go-geom"
 	"github.com/twpayne/go-geom/encoding/ewkb"
@@ -18,32 +18,8 @@ type EWKBPolygon struct {
 
 // Value satisfies the Valuer interace and is responsible for writing data to the database
 func (m *EWKBPolygon) Value() (driver.Value, error) {
-
-	// value, err := m.Polygon.Value()
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	var str strings.Builder
-	str.WriteString("POLYGON(")
-	// "POLYGON(("
-	for _, poly := range m.Polygon.Coords() {
-		str.WriteString("(")
-		for _, coords := range poly { // looping through a two-d coordinate polygon, for example
-			for _, coord := range coords {
-				str.WriteString(fmt.Sprintf("%f ", coord))
-			}
-			str.WriteString(",")
-		}
-		s := str.String()
-		s = strings.TrimRight(s, ",") + ")"
-		str.Reset()
-		str.WriteString(s)
-	}
-	str.WriteString(")")
-	s := str.String()
-
-	return s, nil
+	// How it's stored depends on the database
+	return dialect.Current().GeometryValue(m.Polygon.Polygon)
 }
 
 // Scan satisfies the Scanner interace and is responsible for reading data from the database
@@ -52,11 +28,7 @@ func (m *EWKBPolygon) Scan(src interface{}) error {
 		return nil
 	}
 
-	mysqlEncoding, ok := src.([]byte)
-	if !ok {
-		return fmt.Errorf("did not scan: expected []byte but was %T", src)
-	}
-	hexdata, err := hex.DecodeString(string(mysqlEncoding))
+	data, err := dialect.Current().ScanGeometry(src)
 	if err != nil {
 		return nil
 	}
@@ -64,7 +36,7 @@ func (m *EWKBPolygon) Scan(src interface{}) error {
 	// srid := binary.LittleEndian.Uint32(mysqlEncoding[0:4]) // uint32
 	// err := m.Polygon.Scan(mysqlEncoding[4:])
 	// m.Polygon.SetSRID(int(srid))
-	m.Polygon.Scan(hexdata)
+	m.Polygon.Scan(data)
 
 	return nil
 }
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/t2wu/qry/dialect"
+
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -44,16 +47,23 @@ func (u *UUID) String() string {
 	return u.UUID.String()
 }
 
-// For Postgresql, Value()  can be string or uint8
-// But when Scan, it is string. So I make it consistent to uint8
-
 // Value satisfies the Valuer interace and is responsible for writing data to the database
 func (u *UUID) Value() (driver.Value, error) {
 	if u == nil {
 		return nil, nil
 	}
 
-	return []uint8(u.UUID.String()), nil
+	// How it's stored depends on the database, of the dialect in use for the process (dialect.Use)
+	return dialect.Current().UUIDValue(u.UUID), nil
+}
+
+// GormDataType is the column type of the dialect, which is used by Gorm instead of
+// the "type" tag
+func (u UUID) GormDataType(d gorm.Dialect) string {
+	if dd := dialect.Get(d.GetName()); dd != nil {
+		return dd.UUIDType()
+	}
+	return dialect.Current().UUIDType()
 }
 
 // Scan satisfies the Scanner interace and is responsible for reading data from the database
@@ -65,6 +75,10 @@ func (u *UUID) Scan(src interface{}) error {
 	// Huh? Sometimes []uint8 sometimes string?
 	var err error
 	s, ok := src.([]uint8)
+	if ok && len(s) == uuid.Size { // binary(16) of MySQL
+		u.UUID, err = uuid.FromBytes(s)
+		return err
+	}
 	if ok {
 		u.UUID, err = uuid.FromString(string(s))
 		return err
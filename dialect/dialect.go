@@ -0,0 +1,124 @@
+// Package dialect is what differs between the databases qry works with.
+//
+// Statements built by qry are quoted by the dialect of the Gorm db they run on. Values of
+// datatype (such as datatype.UUID) don't know which db they're written to, so they are
+// written as the dialect in use, set with Use() (Postgres by default):
+//
+//	db, err := gorm.Open("sqlite3", "/tmp/test.db?_foreign_keys=1")
+//	dialect.Use(dialect.SQLite)
+//
+// The dialect in use is one for the whole process, so a process can't work with databases of
+// more than one dialect where values are written differently (such as UUIDs on Postgres and
+// MySQL), even though the statements are built for each.
+package dialect
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sync"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/twpayne/go-geom"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Dialect is what differs between databases
+type Dialect interface {
+	// GetName is the name of the dialect, the same as given to gorm.Open()
+	GetName() string
+
+	// Quote quotes an identifier, such as a table name
+	Quote(key string) string
+
+	// NoLimit is the LIMIT which means no limit, given along with OFFSET when there is no limit,
+	// for databases which can't have OFFSET alone (nil if they can)
+	NoLimit() interface{}
+
+	// Explain is the statement which explains how stmt would be run, without running it, in JSON
+	// if the database can (such as EXPLAIN (FORMAT JSON) on Postgres)
+	Explain(stmt string) string
+
+	// Returning is whether UPDATE and DELETE can have RETURNING, otherwise the rows are read
+	// separately
+	Returning() bool
+
+	// UpdateJoin is whether an UPDATE joins with what it's updated from (UPDATE t JOIN v ON ...
+	// SET t.c = v.c, as MySQL does) rather than UPDATE t SET c = v.c FROM v WHERE .... Such database
+	// can't select from the table updated or deleted from in a sub query either, unless it's
+	// selected from a derived table.
+	UpdateJoin() bool
+
+	// UUIDType is the column type of datatype.UUID
+	UUIDType() string
+
+	// UUIDValue is a datatype.UUID as written to the database
+	UUIDValue(u uuid.UUID) driver.Value
+
+	// GeometryValue is a geometry (such as a datatype.EWKBPoint) as written to the database
+	GeometryValue(g geom.T) (driver.Value, error)
+
+	// ScanGeometry returns the (E)WKB of a geometry read from the database
+	ScanGeometry(src interface{}) ([]byte, error)
+}
+
+var (
+	mu       sync.RWMutex
+	dialects = map[string]Dialect{
+		Postgres.GetName(): Postgres,
+		SQLite.GetName():   SQLite,
+		MySQL.GetName():    MySQL,
+	}
+	current Dialect = Postgres
+)
+
+// Register registers d under its name, so it's used for Gorm db of that name
+func Register(d Dialect) {
+	mu.Lock()
+	defer mu.Unlock()
+	dialects[d.GetName()] = d
+}
+
+// Get returns the dialect registered under name, or nil if none
+func Get(name string) Dialect {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dialects[name]
+}
+
+// Use sets the dialect values are written as, and the public builders (such as
+// qry.Predicate's BuildQueryStringAndValues) quote as, which is the same for every db of the
+// process. It should be set once at start, before any statement runs.
+func Use(d Dialect) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = d
+}
+
+// Current returns the dialect values are written as
+func Current() Dialect {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Of returns the dialect of the Gorm db, or the current one if db is of an unknown dialect
+func Of(db *gorm.DB) Dialect {
+	if db != nil {
+		if d := Get(db.Dialect().GetName()); d != nil {
+			return d
+		}
+	}
+	return Current()
+}
+
+// bytesOf returns src read from the database as bytes
+func bytesOf(src interface{}) ([]byte, error) {
+	switch src := src.(type) {
+	case []byte:
+		return src, nil
+	case string:
+		return []byte(src), nil
+	}
+	return nil, fmt.Errorf("did not scan: expected []byte but was %T", src)
+}
@@ -0,0 +1,146 @@
+package dialect_test
+
+import (
+	"testing"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/dialect"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet_ShouldReturnRegisteredDialects(t *testing.T) {
+	assert.Equal(t, dialect.Postgres, dialect.Get("postgres"))
+	assert.Equal(t, dialect.SQLite, dialect.Get("sqlite3"))
+	assert.Nil(t, dialect.Get("unknown"))
+}
+
+func TestOf_WithoutDB_ShouldBeCurrent(t *testing.T) {
+	assert.Equal(t, dialect.Postgres, dialect.Of(nil))
+
+	dialect.Use(dialect.SQLite)
+	defer dialect.Use(dialect.Postgres)
+	assert.Equal(t, dialect.SQLite, dialect.Of(nil))
+}
+
+func TestQuote_ShouldQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, `"test_model"`, dialect.Postgres.Quote("test_model"))
+	assert.Equal(t, `"test_model"`, dialect.SQLite.Quote("test_model"))
+	assert.Equal(t, "`test_model`", dialect.MySQL.Quote("test_model"))
+}
+
+func TestExplain_ShouldBeInJSONIfTheDatabaseCan(t *testing.T) {
+	stmt := "SELECT * FROM t"
+	assert.Equal(t, "EXPLAIN (FORMAT JSON) SELECT * FROM t", dialect.Postgres.Explain(stmt))
+	assert.Equal(t, "EXPLAIN QUERY PLAN SELECT * FROM t", dialect.SQLite.Explain(stmt))
+	assert.Equal(t, "EXPLAIN FORMAT=JSON SELECT * FROM t", dialect.MySQL.Explain(stmt))
+}
+
+func TestUUIDType_ShouldBeOfTheGormDialect(t *testing.T) {
+	for name, typ := range map[string]string{"postgres": "uuid", "sqlite3": "text", "mysql": "binary(16)"} {
+		d, ok := gorm.GetDialect(name)
+		if assert.True(t, ok) {
+			assert.Equal(t, typ, datatype.UUID{}.GormDataType(d))
+		}
+	}
+}
+
+func TestUUIDValue_ShouldBeOfTheDialectInUse(t *testing.T) {
+	u := datatype.NewUUIDFromStringNoErr("1e98bfc3-2721-492a-bfd3-09f7dd3c1565")
+
+	v, err := u.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, []uint8("1e98bfc3-2721-492a-bfd3-09f7dd3c1565"), v)
+
+	dialect.Use(dialect.SQLite)
+	defer dialect.Use(dialect.Postgres)
+
+	v, err = u.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "1e98bfc3-2721-492a-bfd3-09f7dd3c1565", v)
+
+	u2 := datatype.UUID{}
+	if assert.Nil(t, u2.Scan(v)) {
+		assert.Equal(t, u.String(), u2.String())
+	}
+}
+
+func TestUUIDValue_MySQL_ShouldBeBytesInStringOrder(t *testing.T) {
+	dialect.Use(dialect.MySQL)
+	defer dialect.Use(dialect.Postgres)
+
+	u := datatype.NewUUIDFromStringNoErr("1e98bfc3-2721-492a-bfd3-09f7dd3c1565")
+	v, err := u.Value()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []byte{0x1e, 0x98, 0xbf, 0xc3, 0x27, 0x21, 0x49, 0x2a,
+		0xbf, 0xd3, 0x09, 0xf7, 0xdd, 0x3c, 0x15, 0x65}, v)
+
+	u2 := datatype.UUID{}
+	if assert.Nil(t, u2.Scan(v)) {
+		assert.Equal(t, u.String(), u2.String())
+	}
+}
+
+func TestGeometryValue_Postgres_ShouldBeEWKT(t *testing.T) {
+	pt := datatype.NewEWKBPoint([]float64{1, 2})
+	v, err := pt.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "SRID=0;POINT(1.000000 2.000000)", v)
+
+	poly := datatype.EWKBPolygon{}
+	if !assert.Nil(t, poly.UnmarshalJSON([]byte(`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}`))) {
+		return
+	}
+	v, err = poly.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "POLYGON((0.000000 0.000000 ,1.000000 0.000000 ,1.000000 1.000000 ,0.000000 0.000000 ))", v)
+}
+
+func TestGeometryValue_SQLite_ShouldBeWKBWhichScansBack(t *testing.T) {
+	dialect.Use(dialect.SQLite)
+	defer dialect.Use(dialect.Postgres)
+
+	pt := datatype.NewEWKBPoint([]float64{1, 2})
+	v, err := pt.Value()
+	if !assert.Nil(t, err) || !assert.IsType(t, []byte{}, v) {
+		return
+	}
+
+	pt2 := datatype.EWKBPoint{}
+	if assert.Nil(t, pt2.Scan(v)) {
+		assert.Equal(t, []float64{1, 2}, []float64(pt2.Point.Coords()))
+	}
+
+	poly := datatype.EWKBPolygon{}
+	if !assert.Nil(t, poly.UnmarshalJSON([]byte(`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}`))) {
+		return
+	}
+	v, err = poly.Value()
+	if !assert.Nil(t, err) {
+		return
+	}
+	poly2 := datatype.EWKBPolygon{}
+	if assert.Nil(t, poly2.Scan(v)) {
+		assert.Equal(t, poly.Polygon.FlatCoords(), poly2.Polygon.FlatCoords())
+	}
+}
+
+func TestGeometryValue_MySQL_ShouldBeSRIDAndWKBWhichScansBack(t *testing.T) {
+	dialect.Use(dialect.MySQL)
+	defer dialect.Use(dialect.Postgres)
+
+	pt := datatype.NewEWKBPoint([]float64{1, 2})
+	v, err := pt.Value()
+	if !assert.Nil(t, err) || !assert.IsType(t, []byte{}, v) {
+		return
+	}
+	assert.Equal(t, []byte{0, 0, 0, 0, 1}, v.([]byte)[:5]) // SRID 0, then little-endian WKB
+
+	pt2 := datatype.EWKBPoint{}
+	if assert.Nil(t, pt2.Scan(v)) {
+		assert.Equal(t, []float64{1, 2}, []float64(pt2.Point.Coords()))
+	}
+}
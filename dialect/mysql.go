@@ -0,0 +1,85 @@
+package dialect
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/wkb"
+)
+
+// MySQL stores UUIDs as binary(16) and geometries in its internal format
+var MySQL Dialect = mysql{}
+
+type mysql struct{}
+
+func (mysql) GetName() string {
+	return "mysql"
+}
+
+func (mysql) Quote(key string) string {
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(key, "`", "``"))
+}
+
+// NoLimit is the largest LIMIT Gorm takes (MySQL suggests the largest uint64)
+func (mysql) NoLimit() interface{} {
+	return int64(math.MaxInt64)
+}
+
+func (mysql) Explain(stmt string) string {
+	return "EXPLAIN FORMAT=JSON " + stmt
+}
+
+func (mysql) Returning() bool {
+	return false
+}
+
+func (mysql) UpdateJoin() bool {
+	return true
+}
+
+func (mysql) UUIDType() string {
+	return "binary(16)"
+}
+
+// UUIDValue is the UUID's 16 bytes in the order of its string form (RFC 4122), which is
+// what UUID_TO_BIN() gives without swapping and BIN_TO_UUID() reads back
+func (mysql) UUIDValue(u uuid.UUID) driver.Value {
+	return u.Bytes()
+}
+
+// GeometryValue is in MySQL's internal format, the SRID (4 bytes, little-endian) followed by
+// the WKB, which is what ST_GeomFromText() stores. It's not written as ST_GeomFromText(?), since
+// a value of Gorm v1 is bound as it is and can't be wrapped in a function. Bound as it is, the
+// internal format is taken by a geometry column as is.
+func (mysql) GeometryValue(g geom.T) (driver.Value, error) {
+	if g == nil || reflect.ValueOf(g).IsNil() {
+		return nil, nil
+	}
+
+	b, err := wkb.Marshal(g, binary.LittleEndian)
+	if err != nil {
+		return nil, err
+	}
+
+	srid := make([]byte, 4, 4+len(b))
+	binary.LittleEndian.PutUint32(srid, uint32(g.SRID()))
+	return append(srid, b...), nil
+}
+
+// ScanGeometry strips the SRID off MySQL's internal format
+func (mysql) ScanGeometry(src interface{}) ([]byte, error) {
+	b, err := bytesOf(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("did not scan: geometry of %d bytes", len(b))
+	}
+	return b[4:], nil
+}
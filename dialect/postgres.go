@@ -0,0 +1,94 @@
+package dialect
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/wkt"
+)
+
+// Postgres (with PostGIS for geometries)
+var Postgres Dialect = postgres{}
+
+type postgres struct{}
+
+func (postgres) GetName() string {
+	return "postgres"
+}
+
+func (postgres) Quote(key string) string {
+	return fmt.Sprintf("\"%s\"", key)
+}
+
+func (postgres) NoLimit() interface{} {
+	return nil
+}
+
+func (postgres) Explain(stmt string) string {
+	return "EXPLAIN (FORMAT JSON) " + stmt
+}
+
+func (postgres) Returning() bool {
+	return true
+}
+
+func (postgres) UpdateJoin() bool {
+	return false
+}
+
+func (postgres) UUIDType() string {
+	return "uuid"
+}
+
+// For Postgresql, Value()  can be string or uint8
+// But when Scan, it is string. So I make it consistent to uint8
+func (postgres) UUIDValue(u uuid.UUID) driver.Value {
+	return []uint8(u.String())
+}
+
+// GeometryValue is the (E)WKT which PostGIS parses
+func (postgres) GeometryValue(g geom.T) (driver.Value, error) {
+	switch g := g.(type) {
+	case *geom.Point:
+		if g == nil {
+			return nil, nil
+		}
+		pos := g.Coords()
+		// return fmt.Sprintf("SRID=32632;POINT(%f %f)", pos[0], pos[1]), nil
+		return fmt.Sprintf("SRID=0;POINT(%f %f)", pos[0], pos[1]), nil
+	case *geom.Polygon:
+		if g == nil {
+			return nil, nil
+		}
+		var str strings.Builder
+		str.WriteString("POLYGON(")
+		for _, ring := range g.Coords() {
+			str.WriteString("(")
+			for _, coord := range ring {
+				for _, v := range coord {
+					str.WriteString(fmt.Sprintf("%f ", v))
+				}
+				str.WriteString(",")
+			}
+			s := strings.TrimRight(str.String(), ",") + ")"
+			str.Reset()
+			str.WriteString(s)
+		}
+		str.WriteString(")")
+		return str.String(), nil
+	}
+	return wkt.Marshal(g)
+}
+
+// ScanGeometry decodes the hex encoded EWKB which PostGIS returns
+func (postgres) ScanGeometry(src interface{}) ([]byte, error) {
+	b, err := bytesOf(src)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(string(b))
+}
@@ -0,0 +1,64 @@
+package dialect
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/wkb"
+)
+
+// SQLite stores UUIDs as text and geometries as WKB blobs
+var SQLite Dialect = sqlite{}
+
+type sqlite struct{}
+
+func (sqlite) GetName() string {
+	return "sqlite3"
+}
+
+func (sqlite) Quote(key string) string {
+	return fmt.Sprintf("\"%s\"", key)
+}
+
+// NoLimit is the largest LIMIT, since Gorm drops a negative one
+func (sqlite) NoLimit() interface{} {
+	return int64(math.MaxInt64)
+}
+
+// Explain is EXPLAIN QUERY PLAN, which is in rows instead of JSON
+func (sqlite) Explain(stmt string) string {
+	return "EXPLAIN QUERY PLAN " + stmt
+}
+
+// Returning is since SQLite 3.35
+func (sqlite) Returning() bool {
+	return true
+}
+
+func (sqlite) UpdateJoin() bool {
+	return false
+}
+
+func (sqlite) UUIDType() string {
+	return "text"
+}
+
+func (sqlite) UUIDValue(u uuid.UUID) driver.Value {
+	return u.String()
+}
+
+func (sqlite) GeometryValue(g geom.T) (driver.Value, error) {
+	if g == nil || reflect.ValueOf(g).IsNil() {
+		return nil, nil
+	}
+	return wkb.Marshal(g, binary.LittleEndian)
+}
+
+func (sqlite) ScanGeometry(src interface{}) ([]byte, error) {
+	return bytesOf(src)
+}
@@ -0,0 +1,234 @@
+package qry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Dry run
+// A terminal is run as it is, but on a database connection which only records the statements
+// and their arguments. Nothing is returned from a query, and every exec affects one row.
+
+// statement is an SQL statement with its bound arguments
+type statement struct {
+	SQL  string
+	Vars []interface{}
+}
+
+const dryRunDriverName = "qry-dryrun"
+
+var (
+	dryRunOnce      sync.Once
+	dryRunRecorders sync.Map // dsn -> *dryRunRecorder
+	dryRunCounter   uint64
+)
+
+type dryRunRecorder struct {
+	mu         sync.Mutex
+	statements []statement
+}
+
+func (r *dryRunRecorder) record(query string, args []driver.NamedValue) {
+	vars := make([]interface{}, len(args))
+	for i, arg := range args {
+		if b, ok := arg.Value.([]byte); ok {
+			vars[i] = string(b) // more readable
+		} else {
+			vars[i] = arg.Value
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, statement{SQL: query, Vars: vars})
+}
+
+func (r *dryRunRecorder) getStatements() []statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]statement{}, r.statements...)
+}
+
+// newDryRunConn returns a connection which only records the statements instead of running them
+// Call the returned function when done
+func newDryRunConn() (*sql.DB, *dryRunRecorder, func(), error) {
+	dryRunOnce.Do(func() {
+		sql.Register(dryRunDriverName, &dryRunDriver{})
+	})
+
+	dsn := "dryrun-" + strconv.FormatUint(atomic.AddUint64(&dryRunCounter, 1), 10)
+	rec := &dryRunRecorder{}
+	dryRunRecorders.Store(dsn, rec)
+
+	sqlDB, err := sql.Open(dryRunDriverName, dsn)
+	if err != nil {
+		dryRunRecorders.Delete(dsn)
+		return nil, nil, nil, err
+	}
+
+	done := func() {
+		sqlDB.Close()
+		dryRunRecorders.Delete(dsn)
+	}
+	return sqlDB, rec, done, nil
+}
+
+// sqlCommonField is the index of the unexported field of gorm.DB which is its connection
+// It's looked up once, so a version of Gorm v1 without it has each query fail with
+// errSQLCommonField instead of running on the connection which should have been swapped.
+var sqlCommonField, errSQLCommonField = findSQLCommonField()
+
+func findSQLCommonField() ([]int, error) {
+	f, ok := reflect.TypeOf((*gorm.DB)(nil)).Elem().FieldByName("db")
+	if !ok || f.Type != reflect.TypeOf((*gorm.SQLCommon)(nil)).Elem() {
+		return nil, errors.New("qry: gorm.DB has no field db of gorm.SQLCommon, this version of Gorm v1 is not supported")
+	}
+	return f.Index, nil
+}
+
+// setSQLCommon swaps the connection of a Gorm db (there is no exported way in Gorm v1)
+// The rest of it, such as the dialect, singular table and callbacks, stays the same, which
+// gorm.Open on the connection would not keep. If it can't, the error is added to db, so nothing
+// runs on it.
+func setSQLCommon(db *gorm.DB, sqlCommon gorm.SQLCommon) {
+	if errSQLCommonField != nil {
+		db.AddError(errSQLCommonField)
+		return
+	}
+	f := reflect.ValueOf(db).Elem().FieldByIndex(sqlCommonField)
+	reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(reflect.ValueOf(sqlCommon))
+}
+
+// --- database/sql driver ---
+
+type dryRunDriver struct{}
+
+func (d *dryRunDriver) Open(dsn string) (driver.Conn, error) {
+	rec, ok := dryRunRecorders.Load(dsn)
+	if !ok {
+		return nil, fmt.Errorf("dry run \"%s\" no longer exists", dsn)
+	}
+	return &dryRunConn{rec: rec.(*dryRunRecorder)}, nil
+}
+
+type dryRunConn struct {
+	rec *dryRunRecorder
+}
+
+func (c *dryRunConn) Prepare(query string) (driver.Stmt, error) {
+	return &dryRunStmt{conn: c, query: query}, nil
+}
+
+func (c *dryRunConn) Close() error {
+	return nil
+}
+
+func (c *dryRunConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *dryRunConn) Commit() error {
+	return nil
+}
+
+func (c *dryRunConn) Rollback() error {
+	return nil
+}
+
+func (c *dryRunConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.rec.record(query, args)
+	return dryRunResult{}, nil
+}
+
+func (c *dryRunConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.rec.record(query, args)
+	return &dryRunRows{}, nil
+}
+
+// CheckNamedValue takes any argument (after driver.Valuer) as it is, since it isn't sent anywhere
+func (c *dryRunConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if valuer, ok := nv.Value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		nv.Value = v
+	}
+	return nil
+}
+
+type dryRunStmt struct {
+	conn  *dryRunConn
+	query string
+}
+
+func (s *dryRunStmt) Close() error {
+	return nil
+}
+
+func (s *dryRunStmt) NumInput() int {
+	return -1
+}
+
+func (s *dryRunStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, valuesToNamedValues(args))
+}
+
+func (s *dryRunStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, valuesToNamedValues(args))
+}
+
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return nvs
+}
+
+type dryRunResult struct{}
+
+func (r dryRunResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (r dryRunResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+type dryRunRows struct{}
+
+func (r *dryRunRows) Columns() []string {
+	return []string{}
+}
+
+func (r *dryRunRows) Close() error {
+	return nil
+}
+
+func (r *dryRunRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
+
+// joinStatements puts multiple statements into one string
+func joinStatements(statements []statement) (string, []interface{}) {
+	sqls := make([]string, len(statements))
+	vars := make([]interface{}, 0)
+	for i, stmt := range statements {
+		sqls[i] = stmt.SQL
+		vars = append(vars, stmt.Vars...)
+	}
+	return strings.Join(sqls, ";\n"), vars
+}
@@ -0,0 +1,93 @@
+package qry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Use errors.Is() or errors.As() for the errors below, since they can be wrapped
+
+// ErrNotFound is when no record is found, such as with First() or Take()
+// This is Gorm's, so checking with gorm.ErrRecordNotFound works as well.
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// InvalidFieldError is when a field does not exist in the mdl
+type InvalidFieldError = mdl.InvalidFieldError
+
+// DuplicatePeggedIDError is when creating pegged mdls whose IDs already exist
+type DuplicatePeggedIDError struct {
+	Table string
+	IDs   []*datatype.UUID
+}
+
+func (e *DuplicatePeggedIDError) Error() string {
+	ids := make([]string, len(e.IDs))
+	for i, id := range e.IDs {
+		ids[i] = id.String()
+	}
+	return fmt.Sprintf("id of embedded pegged object already exists (\"%s\" %s)", e.Table, strings.Join(ids, ", "))
+}
+
+// UnsafeDeleteError is when Delete() has neither a modelID nor criteria, which would otherwise
+// delete every record in the table
+type UnsafeDeleteError struct {
+	Table string
+}
+
+func (e *UnsafeDeleteError) Error() string {
+	return "delete must have a modelID or include at least one PredicateRelationBuilder"
+}
+
+// UnsafeUpdateError is the same as UnsafeDeleteError for UpdateFields (Update updates every
+// record, as it always has)
+type UnsafeUpdateError struct {
+	Table string
+}
+
+func (e *UnsafeUpdateError) Error() string {
+	return "update must have a modelID or include at least one PredicateRelationBuilder"
+}
+
+// BuilderError is when the query is not built correctly, such as incorrect arguments
+// or unsupported dot notation
+type BuilderError struct {
+	Reason string
+}
+
+func (e *BuilderError) Error() string {
+	return e.Reason
+}
+
+func newBuilderError(format string, a ...interface{}) *BuilderError {
+	return &BuilderError{Reason: fmt.Sprintf(format, a...)}
+}
+
+// ErrStaleObject is when a versioned mdl is saved or updated but the record has been changed
+// by someone else since it was read (so the version no longer matches)
+// Use errors.Is(err, ErrStaleObject) to check for it.
+var ErrStaleObject = errors.New("stale object")
+
+// StaleObjectError is the ErrStaleObject on a specific record
+type StaleObjectError struct {
+	Table   string
+	ID      *datatype.UUID
+	Version int // version we expect the record to be at
+}
+
+func (e *StaleObjectError) Error() string {
+	if e.ID == nil {
+		return fmt.Sprintf("stale object in \"%s\" at version %d", e.Table, e.Version)
+	}
+	return fmt.Sprintf("stale object \"%s\" %s at version %d", e.Table, e.ID.String(), e.Version)
+}
+
+// Is makes errors.Is(err, ErrStaleObject) true
+func (e *StaleObjectError) Is(target error) bool {
+	return target == ErrStaleObject
+}
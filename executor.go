@@ -0,0 +1,84 @@
+package qry
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/t2wu/qry/dialect"
+
+	"github.com/jinzhu/gorm"
+	gormv2 "gorm.io/gorm"
+)
+
+// executor is what Query runs on, so it's not tied to one version of Gorm
+// Like Gorm, each chained call returns a new executor and leaves the one it's called on as it is.
+// Statements given to it (such as in Where) are with "?" placeholders, and "IN (?)" takes a slice
+// or a sub query.
+type executor interface {
+	Model(value interface{}) executor
+	Table(name string) executor
+	Where(query string, args ...interface{}) executor
+	Joins(query string, args ...interface{}) executor
+	Select(query string) executor
+	Order(value string) executor
+	Limit(limit interface{}) executor
+	Offset(offset int) executor
+	Unscoped() executor
+
+	// Preload loads every association (nested ones included) when reading
+	Preload() executor
+
+	// WithoutAssociations saves or creates the mdl's own columns only
+	WithoutAssociations() executor
+
+	Take(out interface{}) error
+	First(out interface{}) error
+	Find(out interface{}) error
+	Count(no *int) error
+	Pluck(column string, out interface{}) error
+
+	// SubQuery is what's selected so far, as a value to "IN (?)"
+	SubQuery() interface{}
+
+	Create(value interface{}) (int64, error)
+	Save(value interface{}) error
+
+	// Update sets column of the mdl (given by Model) to value, nil for NULL
+	Update(column string, value interface{}) error
+
+	// Delete deletes value, or if ids are given, the records of value's table with those ids
+	Delete(value interface{}, ids []interface{}) (int64, error)
+
+	Exec(sql string, vals ...interface{}) (int64, error)
+	RawScan(out interface{}, sql string, vals ...interface{}) (int64, error)
+	Transaction(fc func(tx executor) error) error
+
+	// Rows runs sql on the connection as it is (with the placeholders of the database), which is not
+	// reported to the run of the terminal
+	Rows(sql string, vals ...interface{}) (*sql.Rows, error)
+
+	// Dialect is the dialect of the database
+	Dialect() dialect.Dialect
+
+	// WithLogger returns an executor whose statements are logged by l
+	WithLogger(l *Logger) executor
+
+	// WithContext returns an executor which runs statements with ctx, each one stopping as soon
+	// as ctx is done
+	WithContext(ctx context.Context) executor
+
+	// WithConn returns an executor which runs on conn instead (such as a dry run)
+	WithConn(conn *sql.DB) executor
+}
+
+// newExecutor returns the executor of db, which is either a *gorm.DB of Gorm v1
+// (github.com/jinzhu/gorm) or of Gorm v2 (gorm.io/gorm), or nil if it's neither
+func newExecutor(db interface{}) executor {
+	switch db := db.(type) {
+	case *gorm.DB:
+		return newGormV1(db)
+	case *gormv2.DB:
+		return newGormV2(db)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package qry
+
+import "strings"
+
+// Field is the designator of a field of a model, such as "Dogs.Name", whose predicates are the
+// criteria of C. cmd/qrygen generates them for the fields of models, so a typo is a compile error
+//
+//	qry.Q(db, TestModelF.Dogs.Name.Eq("Doggie1").And(TestModelF.Age.Gte(18)))
+type Field string
+
+// F is the Field of the designator field, whose predicates are built directly rather than
+// parsed from a string, and compose with C, And and Or
+//
+//	qry.Q(db, qry.F("Age").Gte(18).And(qry.F("Dogs.Color").IsNull()))
+func F(field string) Field {
+	return Field(strings.TrimSpace(field))
+}
+
+func (f Field) String() string {
+	return string(f)
+}
+
+// Eq is C(f + " =", value)
+func (f Field) Eq(value interface{}) *PredicateRelationBuilder {
+	return f.predicate(PredicateCondEQ, value)
+}
+
+// Lt is C(f + " <", value)
+func (f Field) Lt(value interface{}) *PredicateRelationBuilder {
+	return f.predicate(PredicateCondLT, value)
+}
+
+// Lte is C(f + " <=", value)
+func (f Field) Lte(value interface{}) *PredicateRelationBuilder {
+	return f.predicate(PredicateCondLTEQ, value)
+}
+
+// Gt is C(f + " >", value)
+func (f Field) Gt(value interface{}) *PredicateRelationBuilder {
+	return f.predicate(PredicateCondGT, value)
+}
+
+// Gte is C(f + " >=", value)
+func (f Field) Gte(value interface{}) *PredicateRelationBuilder {
+	return f.predicate(PredicateCondGTEQ, value)
+}
+
+// In is C(f + " IN", values), values being a slice
+func (f Field) In(values interface{}) *PredicateRelationBuilder {
+	return f.predicate(PredicateCondIN, values)
+}
+
+// Between is C(f + " BETWEEN", []interface{}{lo, hi}), lo and hi inclusive
+func (f Field) Between(lo, hi interface{}) *PredicateRelationBuilder {
+	return f.predicate(PredicateCondBETWEEN, []interface{}{lo, hi})
+}
+
+// IsNull is the predicate that f is NULL, which C has no string of
+func (f Field) IsNull() *PredicateRelationBuilder {
+	return f.predicate(PredicateCondISNULL, nil)
+}
+
+// IsNotNull is the predicate that f is not NULL, which C has no string of
+func (f Field) IsNotNull() *PredicateRelationBuilder {
+	return f.predicate(PredicateCondISNOTNULL, nil)
+}
+
+// predicate returns the builder of the predicate of f, as C does, erring if f is not a designator
+func (f Field) predicate(cond PredicateCond, value interface{}) *PredicateRelationBuilder {
+	b := NewPredicateRelationBuilder()
+	if f == "" || strings.ContainsAny(string(f), " \t\n") {
+		b.Error = newBuilderError("field \"%s\" is not a designator", f)
+		return b
+	}
+	b.Rel.PredOrRels = append(b.Rel.PredOrRels, &Predicate{Field: string(f), Cond: cond, Value: value})
+	return b
+}
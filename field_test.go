@@ -0,0 +1,100 @@
+package qry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testModelF is as qrygen generates for TestModel, in part (in the package qry)
+var testModelF struct {
+	Name Field
+	Age  Field
+	Dogs struct {
+		Name  Field
+		Color Field
+	}
+}
+
+func init() {
+	testModelF.Name = "Name"
+	testModelF.Age = "Age"
+	testModelF.Dogs.Name = "Dogs.Name"
+	testModelF.Dogs.Color = "Dogs.Color"
+}
+
+func TestField_ShouldBeThePredicatesOfC(t *testing.T) {
+	tests := []struct {
+		got  *PredicateRelationBuilder
+		want *PredicateRelationBuilder
+	}{
+		{testModelF.Name.Eq("same"), C("Name =", "same")},
+		{testModelF.Age.Lt(3), C("Age <", 3)},
+		{testModelF.Age.Lte(3), C("Age <=", 3)},
+		{testModelF.Age.Gt(3), C("Age >", 3)},
+		{testModelF.Age.Gte(3), C("Age >=", 3)},
+		{testModelF.Dogs.Color.In([]string{"red", "green"}), C("Dogs.Color IN", []string{"red", "green"})},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.got)
+	}
+	assert.Equal(t, "Dogs.Name", testModelF.Dogs.Name.String())
+}
+
+func TestField_ShouldBeQueried(t *testing.T) {
+	tms := make([]TestModel, 0)
+	err := Q(db, testModelF.Dogs.Color.Eq("green").And(testModelF.Age.Gte(4))).Find(&tms).Error()
+	if assert.Nil(t, err) && assert.Len(t, tms, 1) {
+		assert.Equal(t, uuid5, tms[0].ID.String())
+	}
+}
+
+func TestF_ShouldBuildThePredicatesOfC(t *testing.T) {
+	assert.Equal(t, C("Age >=", 18), F("Age").Gte(18))
+	assert.Equal(t, C("Name IN", []string{"first", "same"}), F(" Name ").In([]string{"first", "same"}))
+	assert.Equal(t, testModelF.Dogs.Color.Eq("red"), F("Dogs.Color").Eq("red"))
+	assert.Equal(t, C("Age BETWEEN", []interface{}{3, 4}), F("Age").Between(3, 4))
+
+	rel, err := F("Dogs.Color").IsNull().GetPredicateRelation()
+	if assert.Nil(t, err) && assert.Len(t, rel.PredOrRels, 1) {
+		assert.Equal(t, &Predicate{Field: "Dogs.Color", Cond: PredicateCondISNULL}, rel.PredOrRels[0])
+	}
+}
+
+func TestF_WhenNotADesignator_ShouldErr(t *testing.T) {
+	for _, field := range []string{"", "Age >="} {
+		_, err := F(field).Gte(18).GetPredicateRelation()
+		assert.Error(t, err, field)
+	}
+
+	// as with C, the error is carried by what it's composed with
+	assert.Error(t, C("Name =", "same").And(F("").Eq(3)).Error)
+	assert.Error(t, Q(db, F("Age >=").Gte(3)).Find(&[]TestModel{}).Error())
+}
+
+func TestF_ShouldComposeWithCAndOr(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *PredicateRelationBuilder
+		want []string
+	}{
+		{"Between", F("Age").Between(3, 4), []string{uuid2, uuid3, uuid4, uuid5}},
+		{"C and F", C("Name =", "same").And(F("Age").Gte(4)), []string{uuid4, uuid5}},
+		{"F or C", F("Age").Lt(2).Or("Name =", "second"), []string{uuid1, uuid2}},
+		{"C of F", C(F("Age").Eq(1).Or(F("Age").Eq(4))).And(F("Dogs.Color").In([]string{"purple", "blue"})), []string{uuid1, uuid4}},
+		{"IsNull", F("DeletedAt").IsNull(), []string{uuid1, uuid2, uuid3, uuid4, uuid5}},
+		{"IsNull of a relation", F("Dogs.Color").IsNull(), []string{}},
+		{"IsNotNull of a relation", F("Dogs.Color").IsNotNull().And(F("Age").Gte(4)), []string{uuid4, uuid5}},
+	}
+	for _, test := range tests {
+		tms := make([]TestModel, 0)
+		if err := Q(db, test.b).Find(&tms).Error(); !assert.Nil(t, err, test.name) {
+			continue
+		}
+		ids := make([]string, len(tms))
+		for i, tm := range tms {
+			ids[i] = tm.ID.String()
+		}
+		assert.ElementsMatch(t, test.want, ids, test.name)
+	}
+}
@@ -0,0 +1,94 @@
+package qry
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	placeholderListRegexp = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)*\s*\)`)
+	placeholderRowsRegexp = regexp.MustCompile(`\(\?\)(\s*,\s*\(\?\))+`)
+)
+
+// Fingerprint is sql normalised so that the statements which are the same but for their values
+// are the same, as pg_stat_statements does. Each literal and placeholder is ?, each IN list
+// (or rows of VALUES) is of one, and there are no comments and no more than a space between words.
+//
+//	SELECT * FROM "dog" WHERE "name" = 'Doggie1' AND "id" IN ($1, $2) LIMIT 10
+//	SELECT * FROM "dog" WHERE "name" = ? AND "id" IN (?) LIMIT ?
+func Fingerprint(sql string) string {
+	var sb strings.Builder
+	runes := []rune(sql)
+	space := false
+	write := func(s string) {
+		if space && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		space = false
+		sb.WriteString(s)
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-': // to the end of the line
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			space = true
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i < len(runes) && !(runes[i-1] == '*' && runes[i] == '/'); i++ {
+			}
+			i++
+			space = true
+		case r == '\'':
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' { // escaped quote
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+			write("?")
+		case r == '"' || r == '`':
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				j++
+			}
+			if j < len(runes) {
+				j++
+			}
+			write(string(runes[i:j]))
+			i = j
+		case r == '?' || (r == '$' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			for i++; i < len(runes) && unicode.IsDigit(runes[i]); i++ {
+			}
+			write("?")
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			write("?")
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			write(string(runes[i:j]))
+			i = j
+		default:
+			write(string(r))
+			i++
+		}
+	}
+
+	fingerprint := placeholderListRegexp.ReplaceAllString(sb.String(), "(?)")
+	return placeholderRowsRegexp.ReplaceAllString(fingerprint, "(?)")
+}
@@ -0,0 +1,27 @@
+package qry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint_ShouldBeTheSameButForValues(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{`SELECT * FROM "dog" WHERE "name" = 'Doggie1' AND "id" IN ($1, $2) LIMIT 10`,
+			`SELECT * FROM "dog" WHERE "name" = ? AND "id" IN (?) LIMIT ?`},
+		{"SELECT *  FROM `dog`\n\tWHERE `name` = 'it''s' AND `id` IN (?,?,?) -- the dogs\n",
+			"SELECT * FROM `dog` WHERE `name` = ? AND `id` IN (?)"},
+		{`/* file=a.go:1 */ UPDATE "dog" SET "age" = 3.5, "name2" = ? WHERE id = ?`,
+			`UPDATE "dog" SET "age" = ?, "name2" = ? WHERE id = ?`},
+		{`INSERT INTO "dog" ("name","color") VALUES (?,?),(?, ?) RETURNING "id"`,
+			`INSERT INTO "dog" ("name","color") VALUES (?) RETURNING "id"`},
+		{`SELECT "col1" FROM "t2" WHERE a IN (1, 2)`, `SELECT "col1" FROM "t2" WHERE a IN (?)`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, Fingerprint(test.sql), test.sql)
+	}
+}
@@ -28,6 +28,10 @@ type cargo struct {
 }
 
 func DeleteModelFixManyToManyAndPegAndPegAssoc(db *gorm.DB, modelObj mdl.IModel) error {
+	return deleteModelFixManyToManyAndPegAndPegAssoc(newGormV1(db), modelObj)
+}
+
+func deleteModelFixManyToManyAndPegAndPegAssoc(db executor, modelObj mdl.IModel) error {
 	if err := removeManyToManyAssociationTableElem(db, modelObj); err != nil {
 		return err
 	}
@@ -45,7 +49,7 @@ func DeleteModelFixManyToManyAndPegAndPegAssoc(db *gorm.DB, modelObj mdl.IModel)
 
 	// Now actually delete
 	for tblName := range car.toProcess {
-		if err := db.Unscoped().Delete(car.toProcess[tblName].modelObj, car.toProcess[tblName].ids).Error; err != nil {
+		if _, err := db.Unscoped().Delete(car.toProcess[tblName].modelObj, car.toProcess[tblName].ids); err != nil {
 			return err
 		}
 	}
@@ -100,7 +104,7 @@ func DeleteModelFixManyToManyAndPegAndPegAssoc(db *gorm.DB, modelObj mdl.IModel)
 
 // TODO: if there is a "pegassoc-manytomany" inside a pegged struct
 // and we're deleting the pegged struct, the many-to-many relationship needs to be removed
-func markForDelete(db *gorm.DB, v reflect.Value, car cargo) error {
+func markForDelete(db executor, v reflect.Value, car cargo) error {
 	for i := 0; i < v.NumField(); i++ {
 		t := pegPegassocOrPegManyToMany(v.Type().Field(i).Tag)
 		if t == "peg" {
@@ -188,7 +192,7 @@ func markForDelete(db *gorm.DB, v reflect.Value, car cargo) error {
 	return nil
 }
 
-func removeManyToManyAssociationTableElem(db *gorm.DB, modelObj mdl.IModel) error {
+func removeManyToManyAssociationTableElem(db executor, modelObj mdl.IModel) error {
 	// many to many, here we remove the entry in the actual immediate table
 	// because that's actually the link table. Thought we don't delete the
 	// Model table itself
@@ -216,16 +220,16 @@ func removeManyToManyAssociationTableElem(db *gorm.DB, modelObj mdl.IModel) erro
 				uuidStmts = uuidStmts[:len(uuidStmts)-1]
 
 				allIds := make([]interface{}, 0, 10)
-				allIds = append(allIds, modelObj.GetID().String())
+				allIds = append(allIds, modelObj.GetID())
 				for j := 0; j < fieldVal.Len(); j++ {
 					idToDel := fieldVal.Index(j).FieldByName("ID").Interface().(*datatype.UUID)
-					allIds = append(allIds, idToDel.String())
+					allIds = append(allIds, idToDel)
 				}
 
-				stmt := fmt.Sprintf("DELETE FROM \"%s\" WHERE \"%s\" = ? AND \"%s\" IN (%s)",
-					linkTableName, selfTableName+"_id", fieldTableName+"_id", uuidStmts)
-				err := db.Exec(stmt, allIds...).Error
-				if err != nil {
+				d := db.Dialect()
+				stmt := fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s IN (%s)",
+					d.Quote(linkTableName), d.Quote(selfTableName+"_id"), d.Quote(fieldTableName+"_id"), uuidStmts)
+				if _, err := db.Exec(stmt, allIds...); err != nil {
 					return err
 				}
 			}
@@ -243,7 +247,7 @@ func pegPegassocOrPegManyToMany(tag reflect.StructTag) string {
 	return ""
 }
 
-func checkIDsNotFound(db *gorm.DB, nestedIModels []mdl.IModel) error {
+func checkIDsNotFound(db executor, nestedIModels []mdl.IModel) error {
 	if len(nestedIModels) > 0 {
 		ids := make([]*datatype.UUID, 0)
 		for _, nestedIModel := range nestedIModels {
@@ -259,13 +263,13 @@ func checkIDsNotFound(db *gorm.DB, nestedIModels []mdl.IModel) error {
 		}
 
 		tableName := mdl.GetTableNameFromIModel(nestedIModels[0])
-		var count int64
-		err := db.Table(tableName).Where("id IN (?)", ids).Count(&count).Error
+		existingIDs := make([]*datatype.UUID, 0)
+		err := db.Table(tableName).Where("id IN (?)", ids).Pluck("id", &existingIDs)
 		if err != nil && !gorm.IsRecordNotFoundError(err) {
 			return err // some real error
 		}
-		if count != 0 {
-			return fmt.Errorf("id of embedded pegged object already exists")
+		if len(existingIDs) != 0 {
+			return &DuplicatePeggedIDError{Table: tableName, IDs: existingIDs}
 		}
 	}
 
@@ -273,6 +277,10 @@ func checkIDsNotFound(db *gorm.DB, nestedIModels []mdl.IModel) error {
 }
 
 func RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db *gorm.DB, modelObj mdl.IModel) error {
+	return removeIDForNonPegOrPeggedFieldsBeforeCreate(newGormV1(db), modelObj)
+}
+
+func removeIDForNonPegOrPeggedFieldsBeforeCreate(db executor, modelObj mdl.IModel) error {
 	v := reflect.Indirect(reflect.ValueOf(modelObj))
 
 	for i := 0; i < v.NumField(); i++ {
@@ -301,7 +309,7 @@ func RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db *gorm.DB, modelObj mdl.IMode
 				for j := 0; j < len(ms); j++ {
 					nestedIModel := ms[j]
 					// Traverse into it
-					if err := RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db, nestedIModel); err != nil {
+					if err := removeIDForNonPegOrPeggedFieldsBeforeCreate(db, nestedIModel); err != nil {
 						return err
 					}
 				}
@@ -317,7 +325,7 @@ func RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db *gorm.DB, modelObj mdl.IMode
 						}
 					}
 					// Traverse into it
-					if err := RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db, nestedIModel); err != nil {
+					if err := removeIDForNonPegOrPeggedFieldsBeforeCreate(db, nestedIModel); err != nil {
 						return err
 					}
 				}
@@ -333,7 +341,7 @@ func RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db *gorm.DB, modelObj mdl.IMode
 					}
 
 					// Traverse into it
-					if err := RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db, nestedIModel); err != nil {
+					if err := removeIDForNonPegOrPeggedFieldsBeforeCreate(db, nestedIModel); err != nil {
 						return err
 					}
 				}
@@ -346,6 +354,10 @@ func RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db *gorm.DB, modelObj mdl.IMode
 
 // CreatePeggedAssocFields :-
 func CreatePeggedAssocFields(db *gorm.DB, modelObj mdl.IModel) (err error) {
+	return createPeggedAssocFields(newGormV1(db), modelObj)
+}
+
+func createPeggedAssocFields(db executor, modelObj mdl.IModel) (err error) {
 	v := reflect.Indirect(reflect.ValueOf(modelObj))
 	for i := 0; i < v.NumField(); i++ {
 		tag := v.Type().Field(i).Tag.Get("betterrest")
@@ -364,7 +376,7 @@ func CreatePeggedAssocFields(db *gorm.DB, modelObj mdl.IModel) (err error) {
 						correspondingColumnName := tableName + "_id"
 						// Where clause is not needed when the embedded is a struct, but if it's a pointer to struct then it's needed
 						if err := db.Model(nestedModel).Where("id = ?", nestedModel.(mdl.IModel).GetID()).
-							Update(correspondingColumnName, modelObj.GetID()).Error; err != nil {
+							Update(correspondingColumnName, modelObj.GetID()); err != nil {
 							return err
 						}
 					}
@@ -381,7 +393,7 @@ func CreatePeggedAssocFields(db *gorm.DB, modelObj mdl.IModel) (err error) {
 					tableName := mdl.GetTableNameFromIModel(modelObj)
 					correspondingColumnName := tableName + "_id"
 					// Where clause is not needed when the embedded is a struct, but if it's a pointer to struct then it's needed
-					if err := db.Model(nestedModel).Where("id = ?", nestedModel.(mdl.IModel).GetID()).Update(correspondingColumnName, modelObj.GetID()).Error; err != nil {
+					if err := db.Model(nestedModel).Where("id = ?", nestedModel.(mdl.IModel).GetID()).Update(correspondingColumnName, modelObj.GetID()); err != nil {
 						return err
 					}
 				}
@@ -393,7 +405,7 @@ func CreatePeggedAssocFields(db *gorm.DB, modelObj mdl.IModel) (err error) {
 					correspondingColumnName := tableName + "_id"
 					// Where clause is not needed when the embedded is a struct, but if it's a pointer to struct then it's needed
 					if err := db.Model(nestedModel).Where("id = ?", nestedModel.(mdl.IModel).GetID()).
-						Update(correspondingColumnName, modelObj.GetID()).Error; err != nil {
+						Update(correspondingColumnName, modelObj.GetID()); err != nil {
 						return err
 					}
 				}
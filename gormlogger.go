@@ -3,8 +3,6 @@ package qry
 import (
 	"database/sql/driver"
 	"fmt"
-	"log"
-	"os"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -34,11 +32,9 @@ func isPrintable(s string) bool {
 var LogFormatter = func(values ...interface{}) (messages []interface{}) {
 	if len(values) > 1 {
 		var (
-			sql             string
-			formattedValues []string
-			level           = values[0]
-			currentTime     = "\n\033[33m[" + NowFunc().Format("2006-01-02 15:04:05") + "]\033[0m"
-			source          = fmt.Sprintf("\033[35m(%v)\033[0m", values[1])
+			level       = values[0]
+			currentTime = "\n\033[33m[" + NowFunc().Format("2006-01-02 15:04:05") + "]\033[0m"
+			source      = fmt.Sprintf("\033[35m(%v)\033[0m", values[1])
 		)
 
 		messages = []interface{}{source, currentTime}
@@ -56,60 +52,7 @@ var LogFormatter = func(values ...interface{}) (messages []interface{}) {
 			// duration
 			messages = append(messages, fmt.Sprintf(" \033[36;1m[%.2fms]\033[0m ", float64(values[2].(time.Duration).Nanoseconds()/1e4)/100.0))
 			// sql
-
-			for _, value := range values[4].([]interface{}) {
-				indirectValue := reflect.Indirect(reflect.ValueOf(value))
-				if indirectValue.IsValid() {
-					value = indirectValue.Interface()
-					if t, ok := value.(time.Time); ok {
-						if t.IsZero() {
-							formattedValues = append(formattedValues, fmt.Sprintf("'%v'", "0000-00-00 00:00:00"))
-						} else {
-							formattedValues = append(formattedValues, fmt.Sprintf("'%v'", t.Format("2006-01-02 15:04:05")))
-						}
-					} else if b, ok := value.([]byte); ok {
-						if str := string(b); isPrintable(str) {
-							formattedValues = append(formattedValues, fmt.Sprintf("'%v'", str))
-						} else {
-							formattedValues = append(formattedValues, "'<binary>'")
-						}
-					} else if r, ok := value.(driver.Valuer); ok {
-						if value, err := r.Value(); err == nil && value != nil {
-							formattedValues = append(formattedValues, fmt.Sprintf("'%v'", value))
-						} else {
-							formattedValues = append(formattedValues, "NULL")
-						}
-					} else {
-						switch value.(type) {
-						case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
-							formattedValues = append(formattedValues, fmt.Sprintf("%v", value))
-						default:
-							formattedValues = append(formattedValues, fmt.Sprintf("'%v'", value))
-						}
-					}
-				} else {
-					formattedValues = append(formattedValues, "NULL")
-				}
-			}
-
-			// differentiate between $n placeholders or else treat like ?
-			if numericPlaceHolderRegexp.MatchString(values[3].(string)) {
-				sql = values[3].(string)
-				for index, value := range formattedValues {
-					placeholder := fmt.Sprintf(`\$%d([^\d]|$)`, index+1)
-					sql = regexp.MustCompile(placeholder).ReplaceAllString(sql, value+"$1")
-				}
-			} else {
-				formattedValuesLength := len(formattedValues)
-				for index, value := range sqlRegexp.Split(values[3].(string), -1) {
-					sql += value
-					if index < formattedValuesLength {
-						sql += formattedValues[index]
-					}
-				}
-			}
-
-			messages = append(messages, sql)
+			messages = append(messages, inlineVars(values[3].(string), formatVars(values[4].([]interface{}))))
 			messages = append(messages, fmt.Sprintf(" \n\033[36;31m[%v]\033[0m ", strconv.FormatInt(values[5].(int64), 10)+" rows affected or returned "))
 		} else {
 			messages = append(messages, "\033[31;1m")
@@ -121,6 +64,69 @@ var LogFormatter = func(values ...interface{}) (messages []interface{}) {
 	return
 }
 
+// formatVars formats each value as it's written in a statement, such as 'same' or NULL
+func formatVars(vars []interface{}) []string {
+	formattedValues := make([]string, 0, len(vars))
+	for _, value := range vars {
+		formattedValues = append(formattedValues, formatVar(value))
+	}
+	return formattedValues
+}
+
+func formatVar(value interface{}) string {
+	indirectValue := reflect.Indirect(reflect.ValueOf(value))
+	if !indirectValue.IsValid() {
+		return "NULL"
+	}
+
+	value = indirectValue.Interface()
+	if t, ok := value.(time.Time); ok {
+		if t.IsZero() {
+			return fmt.Sprintf("'%v'", "0000-00-00 00:00:00")
+		}
+		return fmt.Sprintf("'%v'", t.Format("2006-01-02 15:04:05"))
+	} else if b, ok := value.([]byte); ok {
+		if str := string(b); isPrintable(str) {
+			return fmt.Sprintf("'%v'", str)
+		}
+		return "'<binary>'"
+	} else if r, ok := value.(driver.Valuer); ok {
+		if value, err := r.Value(); err == nil && value != nil {
+			return fmt.Sprintf("'%v'", value)
+		}
+		return "NULL"
+	}
+
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return fmt.Sprintf("%v", value)
+	default:
+		return fmt.Sprintf("'%v'", value)
+	}
+}
+
+// inlineVars puts the formatted values in place of the placeholders of sql
+func inlineVars(sql string, formattedValues []string) string {
+	// differentiate between $n placeholders or else treat like ?
+	if numericPlaceHolderRegexp.MatchString(sql) {
+		for index, value := range formattedValues {
+			placeholder := fmt.Sprintf(`\$%d([^\d]|$)`, index+1)
+			sql = regexp.MustCompile(placeholder).ReplaceAllString(sql, value+"$1")
+		}
+		return sql
+	}
+
+	inlined := ""
+	formattedValuesLength := len(formattedValues)
+	for index, value := range sqlRegexp.Split(sql, -1) {
+		inlined += value
+		if index < formattedValuesLength {
+			inlined += formattedValues[index]
+		}
+	}
+	return inlined
+}
+
 type logger interface {
 	Print(v ...interface{})
 }
@@ -130,32 +136,72 @@ type LogWriter interface {
 	Println(v ...interface{})
 }
 
+// NewLogger returns the logger Gorm v1 is given, logging to the handler every query logs to
+// source is the file:line statements are reported to be run from, on the first one.
 func NewLogger(source string) *Logger {
-	return &Logger{
-		source: source,
-		logger: log.New(os.Stdout, "\r\n", log.Flags()&^(log.Ldate|log.Ltime)),
-	}
+	return newLogger(source, CurrentLogHandler())
+}
+
+func newLogger(source string, h LogHandler) *Logger {
+	return &Logger{source: source, handler: h}
 }
 
 // Logger default logger
 type Logger struct {
 	LogWriter
-	source string
-	logger *log.Logger
+	source  string
+	handler LogHandler
 }
 
-// Print format & print log
+// Print logs what Gorm v1 gives it, which is
+// "sql", source, duration, statement, vars, rows or "log" (or "error"), source, messages...
 func (l *Logger) Print(values ...interface{}) {
-	if l.source != "" {
+	if l.source != "" && len(values) > 1 {
 		values[1] = l.source // hack
 	}
-	l.Println(LogFormatter(values...)...)
+	handle(l.handler, gormEntry(values...))
 	l.source = "" // hack, since I can't get the original logger and save it
 }
 
-// Print format & print log
+// Println logs the values as a message
 func (l *Logger) Println(values ...interface{}) {
-	l.logger.Println(values...)
+	handle(l.handler, Entry{Level: LevelInfo, Message: fmt.Sprint(values...), Source: l.source})
+}
+
+func gormEntry(values ...interface{}) Entry {
+	e := Entry{Level: LevelInfo}
+	if len(values) > 1 {
+		e.Source = fmt.Sprint(values[1])
+	}
+
+	if len(values) == 6 && values[0] == "sql" {
+		e.Level = LevelDebug
+		e.Message = "sql"
+		e.Duration, _ = values[2].(time.Duration)
+		e.SQL, _ = values[3].(string)
+		vars, _ := values[4].([]interface{})
+		e.Vars = redactVars(e.SQL, vars)
+		e.Rows, _ = values[5].(int64)
+		return e
+	}
+
+	if values[0] == "error" {
+		e.Level = LevelError
+	}
+	msgs := make([]interface{}, 0)
+	for _, v := range values[2:] {
+		if err, ok := v.(error); ok && e.Err == nil {
+			e.Level = LevelError
+			e.Err = err
+		} else {
+			msgs = append(msgs, v)
+		}
+	}
+	e.Message = fmt.Sprint(msgs...)
+	if e.Message == "" && e.Err != nil {
+		e.Message = "error"
+	}
+	return e
 }
 
 // type nopLogger struct{}
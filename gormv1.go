@@ -0,0 +1,350 @@
+package qry
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/t2wu/qry/dialect"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// contextSetting is the setting of a Gorm v1 db with the context it runs with
+	contextSetting = "qry:context"
+
+	// loggerSetting is the setting of a Gorm v1 db with the logger of the query it runs
+	loggerSetting = "qry:logger"
+)
+
+// gormV1 is the executor on Gorm v1 (github.com/jinzhu/gorm)
+// Gorm v1 has no context, so statements run with one on a connection which passes it on.
+type gormV1 struct {
+	db *gorm.DB
+}
+
+func newGormV1(db *gorm.DB) executor {
+	return gormV1{db: db}
+}
+
+func (g gormV1) Model(value interface{}) executor {
+	return gormV1{db: g.db.Model(value)}
+}
+
+func (g gormV1) Table(name string) executor {
+	return gormV1{db: g.db.Table(name)}
+}
+
+func (g gormV1) Where(query string, args ...interface{}) executor {
+	return gormV1{db: g.db.Where(query, args...)}
+}
+
+func (g gormV1) Joins(query string, args ...interface{}) executor {
+	return gormV1{db: g.db.Joins(query, args...)}
+}
+
+func (g gormV1) Select(query string) executor {
+	return gormV1{db: g.db.Select(query)}
+}
+
+func (g gormV1) Order(value string) executor {
+	return gormV1{db: g.db.Order(value)}
+}
+
+func (g gormV1) Limit(limit interface{}) executor {
+	return gormV1{db: g.db.Limit(limit)}
+}
+
+func (g gormV1) Offset(offset int) executor {
+	return gormV1{db: g.db.Offset(offset)}
+}
+
+func (g gormV1) Unscoped() executor {
+	return gormV1{db: g.db.Unscoped()}
+}
+
+func (g gormV1) Preload() executor {
+	return gormV1{db: g.db.Set("gorm:auto_preload", true)}
+}
+
+func (g gormV1) WithoutAssociations() executor {
+	return gormV1{db: g.db.Set("gorm:save_associations", false)}
+}
+
+func (g gormV1) Take(out interface{}) error {
+	return g.db.Take(out).Error
+}
+
+func (g gormV1) First(out interface{}) error {
+	return g.db.First(out).Error
+}
+
+func (g gormV1) Find(out interface{}) error {
+	return g.db.Find(out).Error
+}
+
+func (g gormV1) Count(no *int) error {
+	return g.db.Count(no).Error
+}
+
+func (g gormV1) Pluck(column string, out interface{}) error {
+	return g.db.Pluck(column, out).Error
+}
+
+func (g gormV1) SubQuery() interface{} {
+	return g.db.QueryExpr()
+}
+
+func (g gormV1) Create(value interface{}) (int64, error) {
+	return g.write(func(db *gorm.DB) *gorm.DB {
+		return db.Create(value)
+	})
+}
+
+func (g gormV1) Save(value interface{}) error {
+	_, err := g.write(func(db *gorm.DB) *gorm.DB {
+		return db.Save(value)
+	})
+	return err
+}
+
+func (g gormV1) Update(column string, value interface{}) error {
+	if value == nil {
+		value = gorm.Expr("NULL")
+	}
+	_, err := g.write(func(db *gorm.DB) *gorm.DB {
+		return db.Update(column, value)
+	})
+	return err
+}
+
+func (g gormV1) Delete(value interface{}, ids []interface{}) (int64, error) {
+	return g.write(func(db *gorm.DB) *gorm.DB {
+		if ids != nil {
+			return db.Delete(value, ids)
+		}
+		return db.Delete(value)
+	})
+}
+
+// write runs f, a write of Gorm which begins a transaction of its own unless within one
+// Gorm v1 begins it on the connection, whose *sql.Tx the statements then run on directly rather
+// than with the context. So with a context, the transaction is begun here, with the statements
+// run on ctxConn.
+func (g gormV1) write(f func(db *gorm.DB) *gorm.DB) (rowsAffected int64, err error) {
+	if _, ok := g.db.CommonDB().(*ctxDBConn); !ok {
+		result := f(g.db)
+		return result.RowsAffected, result.Error
+	}
+
+	err = g.Transaction(func(tx executor) error {
+		result := f(tx.(gormV1).db)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	return rowsAffected, err
+}
+
+func (g gormV1) Exec(sql string, vals ...interface{}) (int64, error) {
+	result := g.db.Exec(sql, vals...)
+	return result.RowsAffected, result.Error
+}
+
+func (g gormV1) RawScan(out interface{}, sql string, vals ...interface{}) (int64, error) {
+	result := g.db.Raw(sql, vals...).Scan(out)
+	return result.RowsAffected, result.Error
+}
+
+func (g gormV1) Rows(sql string, vals ...interface{}) (*sql.Rows, error) {
+	if g.db.Error != nil { // such as the connection not swapped
+		return nil, g.db.Error
+	}
+	return unwrapConn(g.db.CommonDB()).Query(sql, vals...)
+}
+
+func (g gormV1) Transaction(fc func(tx executor) error) (err error) {
+	ctx, ok := g.context()
+	if !ok {
+		return g.db.Transaction(func(tx *gorm.DB) error {
+			return fc(gormV1{db: tx})
+		})
+	}
+
+	if _, ok := unwrapConn(g.db.CommonDB()).(*sql.Tx); ok { // already within one
+		return fc(g)
+	}
+
+	// As Gorm's Transaction, except that it begins with ctx, and stops if it can't begin
+	tx := g.db.BeginTx(ctx, &sql.TxOptions{})
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	panicked := true
+	defer func() {
+		// Make sure to rollback when panic, Block error or Commit error
+		if panicked || err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fc(gormV1{db: tx}.WithContext(ctx)); err == nil {
+		err = tx.Commit().Error
+	}
+
+	panicked = false
+	return err
+}
+
+func (g gormV1) Dialect() dialect.Dialect {
+	return dialect.Of(g.db)
+}
+
+// WithLogger sets l on a clone of the db, as SetLogger of Gorm v1 sets it on the db it's called on,
+// which could be shared
+func (g gormV1) WithLogger(l *Logger) executor {
+	db := g.db.Set(loggerSetting, l)
+	db.SetLogger(l)
+	return gormV1{db: db}
+}
+
+func (g gormV1) WithContext(ctx context.Context) executor {
+	db := g.db.Set(contextSetting, ctx)
+	setSQLCommon(db, newCtxConn(unwrapConn(g.db.CommonDB()), ctx))
+	return gormV1{db: db}
+}
+
+func (g gormV1) WithConn(conn *sql.DB) executor {
+	db := g.db.New()
+	setSQLCommon(db, conn)
+	return gormV1{db: db}
+}
+
+func (g gormV1) context() (context.Context, bool) {
+	if ctx, ok := g.db.Get(contextSetting); ok {
+		return ctx.(context.Context), true
+	}
+	return nil, false
+}
+
+// ctxConn is a connection of Gorm v1 which runs statements with ctx
+// If ctx is done, the error is ctx's. Statements are reported to the terminal run of ctx.
+type ctxConn struct {
+	conn gorm.SQLCommon
+	ctx  context.Context
+}
+
+// sqlCommonContext is gorm.SQLCommon with context (such as *sql.DB and *sql.Tx)
+type sqlCommonContext interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txBeginner is a connection which can begin a transaction (such as *sql.DB)
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+func newCtxConn(conn gorm.SQLCommon, ctx context.Context) gorm.SQLCommon {
+	c := &ctxConn{conn: conn, ctx: ctx}
+	if _, ok := conn.(txBeginner); ok {
+		return &ctxDBConn{ctxConn: c}
+	}
+	return c
+}
+
+func unwrapConn(conn gorm.SQLCommon) gorm.SQLCommon {
+	switch c := conn.(type) {
+	case *ctxConn:
+		return c.conn
+	case *ctxDBConn:
+		return c.conn
+	}
+	return conn
+}
+
+func (c *ctxConn) err(err error) error {
+	if err != nil && c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+	return err
+}
+
+func (c *ctxConn) Exec(query string, args ...interface{}) (result sql.Result, err error) {
+	end := begin(c.ctx, query, args)
+	defer func() {
+		rows := int64(0)
+		if result != nil {
+			rows, _ = result.RowsAffected()
+		}
+		end(rows, err)
+	}()
+	query = commented(c.ctx, query)
+
+	if conn, ok := c.conn.(sqlCommonContext); ok {
+		result, err = conn.ExecContext(c.ctx, query, args...)
+		return result, c.err(err)
+	}
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	result, err = c.conn.Exec(query, args...)
+	return result, c.err(err)
+}
+
+func (c *ctxConn) Prepare(query string) (*sql.Stmt, error) {
+	query = commented(c.ctx, query)
+	if conn, ok := c.conn.(sqlCommonContext); ok {
+		stmt, err := conn.PrepareContext(c.ctx, query)
+		return stmt, c.err(err)
+	}
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	stmt, err := c.conn.Prepare(query)
+	return stmt, c.err(err)
+}
+
+func (c *ctxConn) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
+	end := begin(c.ctx, query, args)
+	defer func() { end(-1, err) }()
+	query = commented(c.ctx, query)
+
+	if conn, ok := c.conn.(sqlCommonContext); ok {
+		rows, err = conn.QueryContext(c.ctx, query, args...)
+		return rows, c.err(err)
+	}
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	rows, err = c.conn.Query(query, args...)
+	return rows, c.err(err)
+}
+
+// QueryRow can't have the error of ctx, which Query makes up for
+func (c *ctxConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	defer begin(c.ctx, query, args)(-1, nil)
+	query = commented(c.ctx, query)
+
+	if conn, ok := c.conn.(sqlCommonContext); ok {
+		return conn.QueryRowContext(c.ctx, query, args...)
+	}
+	return c.conn.QueryRow(query, args...)
+}
+
+// ctxDBConn is ctxConn on a connection which can begin a transaction (not a transaction itself),
+// so Gorm can begin one with ctx
+type ctxDBConn struct {
+	*ctxConn
+}
+
+func (c *ctxDBConn) Begin() (*sql.Tx, error) {
+	return c.BeginTx(c.ctx, nil)
+}
+
+func (c *ctxDBConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := c.conn.(txBeginner).BeginTx(ctx, opts)
+	return tx, c.err(err)
+}
@@ -0,0 +1,518 @@
+package qry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/dialect"
+	"github.com/t2wu/qry/mdl"
+
+	gormv2 "gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// gormV2 is the executor on Gorm v2 (gorm.io/gorm)
+// It keeps what qry expects of Gorm v1:
+// - ids of mdls (nested ones included) are set before created, as mdl.BaseModel's BeforeCreate
+// is a Gorm v1 hook
+// - records with DeletedAt set are not read unless unscoped (Gorm v2 only does it with
+// gorm.DeletedAt)
+// - Preload() loads every association, nested ones included
+// - ErrRecordNotFound is ErrNotFound
+// - what's created, saved or deleted is of the value given, not of an earlier Model()
+// Tables are named by the NamingStrategy of the db, so use schema.NamingStrategy{SingularTable: true}
+// to have the same names as Gorm v1 with SingularTable(true).
+type gormV2 struct {
+	db           *gormv2.DB
+	preload      bool
+	withoutAssoc bool
+}
+
+func newGormV2(db *gormv2.DB) executor {
+	return gormV2{db: db}
+}
+
+// chain returns db on which a chained call doesn't change g.db
+func (g gormV2) chain() *gormv2.DB {
+	return g.db.Session(&gormv2.Session{})
+}
+
+func (g gormV2) with(db *gormv2.DB) gormV2 {
+	g.db = db
+	return g
+}
+
+func (g gormV2) Model(value interface{}) executor {
+	return g.with(g.chain().Model(value))
+}
+
+func (g gormV2) Table(name string) executor {
+	return g.with(g.chain().Table(name))
+}
+
+func (g gormV2) Where(query string, args ...interface{}) executor {
+	return g.with(g.chain().Where(query, args...))
+}
+
+func (g gormV2) Joins(query string, args ...interface{}) executor {
+	return g.with(g.chain().Joins(query, args...))
+}
+
+func (g gormV2) Select(query string) executor {
+	return g.with(g.chain().Select(query))
+}
+
+func (g gormV2) Order(value string) executor {
+	return g.with(g.chain().Order(value))
+}
+
+func (g gormV2) Limit(limit interface{}) executor {
+	switch limit := limit.(type) {
+	case int:
+		return g.with(g.chain().Limit(limit))
+	case int64:
+		return g.with(g.chain().Limit(int(limit)))
+	}
+	db := g.chain()
+	db.AddError(fmt.Errorf("limit of %T is not supported", limit))
+	return g.with(db)
+}
+
+func (g gormV2) Offset(offset int) executor {
+	return g.with(g.chain().Offset(offset))
+}
+
+func (g gormV2) Unscoped() executor {
+	return g.with(g.chain().Unscoped())
+}
+
+func (g gormV2) Preload() executor {
+	g.preload = true
+	return g
+}
+
+func (g gormV2) WithoutAssociations() executor {
+	g.withoutAssoc = true
+	return g.with(g.chain().Omit(clause.Associations))
+}
+
+func (g gormV2) Take(out interface{}) error {
+	return fromGormV2Error(g.query(out).Take(out).Error)
+}
+
+func (g gormV2) First(out interface{}) error {
+	return fromGormV2Error(g.query(out).First(out).Error)
+}
+
+func (g gormV2) Find(out interface{}) error {
+	return fromGormV2Error(g.query(out).Find(out).Error)
+}
+
+func (g gormV2) Count(no *int) error {
+	g.preload = false
+	var count int64
+	if err := g.query(nil).Count(&count).Error; err != nil {
+		return fromGormV2Error(err)
+	}
+	*no = int(count)
+	return nil
+}
+
+func (g gormV2) Pluck(column string, out interface{}) error {
+	g.preload = false
+	return fromGormV2Error(g.query(nil).Pluck(column, out).Error)
+}
+
+func (g gormV2) SubQuery() interface{} {
+	g.preload = false
+	return g.query(nil)
+}
+
+func (g gormV2) Create(value interface{}) (int64, error) {
+	setIDsBeforeCreate(value, !g.withoutAssoc)
+	result := g.chain().Model(value).Create(value)
+	return result.RowsAffected, fromGormV2Error(result.Error)
+}
+
+// Save saves the associations as well (unless WithoutAssociations), except for the ones tagged
+// with association_autoupdate:false
+func (g gormV2) Save(value interface{}) error {
+	setIDsBeforeCreate(value, !g.withoutAssoc)
+	db := g.chain().Model(value)
+	if !g.withoutAssoc {
+		db = db.Session(&gormv2.Session{FullSaveAssociations: true})
+		if s, err := g.parse(value); err != nil {
+			return err
+		} else if omits := noAutoUpdateAssociations(s); len(omits) > 0 {
+			db = db.Omit(omits...)
+		}
+	}
+	return fromGormV2Error(db.Save(value).Error)
+}
+
+func (g gormV2) Update(column string, value interface{}) error {
+	return fromGormV2Error(g.chain().Update(column, value).Error)
+}
+
+func (g gormV2) Delete(value interface{}, ids []interface{}) (int64, error) {
+	db := g.chain().Model(value)
+	if ids != nil {
+		db = db.Where("id IN (?)", ids)
+	}
+	result := db.Delete(value)
+	return result.RowsAffected, fromGormV2Error(result.Error)
+}
+
+func (g gormV2) Exec(sql string, vals ...interface{}) (int64, error) {
+	result := g.chain().Exec(sql, vals...)
+	return result.RowsAffected, fromGormV2Error(result.Error)
+}
+
+func (g gormV2) RawScan(out interface{}, sql string, vals ...interface{}) (int64, error) {
+	result := g.chain().Raw(sql, vals...).Scan(out)
+	return result.RowsAffected, fromGormV2Error(result.Error)
+}
+
+func (g gormV2) Rows(sql string, vals ...interface{}) (*sql.Rows, error) {
+	return unwrapConnPool(g.db.Statement.ConnPool).QueryContext(g.context(), sql, vals...)
+}
+
+func (g gormV2) Transaction(fc func(tx executor) error) error {
+	return g.db.Transaction(func(tx *gormv2.DB) error {
+		return fc(g.with(tx))
+	})
+}
+
+func (g gormV2) Dialect() dialect.Dialect {
+	name := g.db.Dialector.Name()
+	if name == "sqlite" { // Gorm v1 calls it sqlite3
+		name = dialect.SQLite.GetName()
+	}
+	if d := dialect.Get(name); d != nil {
+		return d
+	}
+	return dialect.Current()
+}
+
+// WithLogger has statements logged to l instead of the logger of the db (given with gorm.Config),
+// at the level it has
+func (g gormV2) WithLogger(l *Logger) executor {
+	return g.with(g.db.Session(&gormv2.Session{Logger: newGormV2Logger(l, g.db.Logger)}))
+}
+
+// WithContext also has statements reported to the terminal run of ctx
+func (g gormV2) WithContext(ctx context.Context) executor {
+	db := g.db.WithContext(ctx)
+	db.Statement.ConnPool = newCtxConnPool(unwrapConnPool(db.Statement.ConnPool))
+	return g.with(db)
+}
+
+func (g gormV2) WithConn(conn *sql.DB) executor {
+	db := g.db.Session(&gormv2.Session{NewDB: true, Context: g.context()}) // a statement of its own
+	db.Statement.ConnPool = conn
+	return gormV2{db: db}
+}
+
+func (g gormV2) context() context.Context {
+	if ctx := g.db.Statement.Context; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// query returns db to read out with, with the conditions Gorm v1 would have
+func (g gormV2) query(out interface{}) *gormv2.DB {
+	db := g.chain()
+	model := db.Statement.Model
+	if model == nil {
+		model = out
+	}
+	if model == nil {
+		return db
+	}
+
+	s, err := g.parse(model)
+	if err != nil {
+		db.AddError(err)
+		return db
+	}
+
+	d := g.Dialect()
+	if !db.Statement.Unscoped && db.Statement.Table == "" && isSoftDeleted(s) {
+		db = db.Where(fmt.Sprintf("%s.deleted_at IS NULL", d.Quote(s.Table)))
+	}
+
+	if g.preload {
+		forEachAssociation(s, "", make(map[*schema.Schema]bool), func(path string, s *schema.Schema) {
+			if isSoftDeleted(s) {
+				cond := fmt.Sprintf("%s.deleted_at IS NULL", d.Quote(s.Table))
+				db = db.Preload(path, func(tx *gormv2.DB) *gormv2.DB { return tx.Where(cond) })
+			} else {
+				db = db.Preload(path)
+			}
+		})
+	}
+
+	return db
+}
+
+func (g gormV2) parse(value interface{}) (*schema.Schema, error) {
+	stmt := &gormv2.Statement{DB: g.db}
+	if err := stmt.Parse(value); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// ctxConnPool is a connection of Gorm v2 on which statements are reported to the terminal run
+// of their context
+type ctxConnPool struct {
+	pool gormv2.ConnPool
+}
+
+func newCtxConnPool(pool gormv2.ConnPool) gormv2.ConnPool {
+	c := &ctxConnPool{pool: pool}
+	switch pool.(type) {
+	case gormv2.TxCommitter:
+		return &ctxTxConnPool{c}
+	case gormv2.TxBeginner, gormv2.ConnPoolBeginner:
+		return &ctxDBConnPool{c}
+	}
+	return c
+}
+
+func unwrapConnPool(pool gormv2.ConnPool) gormv2.ConnPool {
+	switch c := pool.(type) {
+	case *ctxConnPool:
+		return c.pool
+	case *ctxDBConnPool:
+		return c.pool
+	case *ctxTxConnPool:
+		return c.pool
+	}
+	return pool
+}
+
+func (c *ctxConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.pool.PrepareContext(ctx, commented(ctx, query))
+}
+
+func (c *ctxConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	end := begin(ctx, query, args)
+	defer func() {
+		rows := int64(0)
+		if result != nil {
+			rows, _ = result.RowsAffected()
+		}
+		end(rows, err)
+	}()
+	return c.pool.ExecContext(ctx, commented(ctx, query), args...)
+}
+
+func (c *ctxConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	end := begin(ctx, query, args)
+	defer func() { end(-1, err) }()
+	return c.pool.QueryContext(ctx, commented(ctx, query), args...)
+}
+
+func (c *ctxConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer begin(ctx, query, args)(-1, nil)
+	return c.pool.QueryRowContext(ctx, commented(ctx, query), args...)
+}
+
+// GetDBConn is so db.DB() of Gorm v2 works
+func (c *ctxConnPool) GetDBConn() (*sql.DB, error) {
+	switch pool := c.pool.(type) {
+	case *sql.DB:
+		return pool, nil
+	case gormv2.GetDBConnector:
+		return pool.GetDBConn()
+	}
+	return nil, gormv2.ErrInvalidDB
+}
+
+// ctxDBConnPool is ctxConnPool on a connection which can begin a transaction (not a transaction
+// itself), and the transaction is a ctxConnPool as well
+type ctxDBConnPool struct {
+	*ctxConnPool
+}
+
+func (c *ctxDBConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gormv2.ConnPool, error) {
+	var tx gormv2.ConnPool
+	var err error
+	switch beginner := c.pool.(type) {
+	case gormv2.TxBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	case gormv2.ConnPoolBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	default:
+		err = gormv2.ErrInvalidTransaction
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newCtxConnPool(tx), nil
+}
+
+// ctxTxConnPool is ctxConnPool on a transaction
+type ctxTxConnPool struct {
+	*ctxConnPool
+}
+
+func (c *ctxTxConnPool) Commit() error {
+	return c.pool.(gormv2.TxCommitter).Commit()
+}
+
+func (c *ctxTxConnPool) Rollback() error {
+	return c.pool.(gormv2.TxCommitter).Rollback()
+}
+
+// gormV2Logger is the logger of Gorm v2 which logs to a Logger, as Gorm v1 does with it
+// Statements are logged if the level is gormlogger.Info (such as with Debug()), errors if it's
+// gormlogger.Error and above. The values of redacted columns are redacted.
+type gormV2Logger struct {
+	l     *Logger
+	level gormlogger.LogLevel
+
+	// Of the statement traced, given to ParamsFilter while being traced (which holds mu)
+	mu   sync.Mutex
+	sql  string
+	vars []interface{}
+}
+
+// newGormV2Logger returns the logger to l, at the level of the logger of the db if it has one
+// (as Gorm's own has), otherwise gormlogger.Warn
+func newGormV2Logger(l *Logger, dbLogger gormlogger.Interface) *gormV2Logger {
+	level := gormlogger.Warn
+	if v := reflect.Indirect(reflect.ValueOf(dbLogger)); v.Kind() == reflect.Struct {
+		if f := v.FieldByName("LogLevel"); f.IsValid() && f.Type() == reflect.TypeOf(level) {
+			level = gormlogger.LogLevel(f.Int())
+		}
+	}
+	return &gormV2Logger{l: l, level: level}
+}
+
+func (g *gormV2Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &gormV2Logger{l: g.l, level: level}
+}
+
+func (g *gormV2Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	g.log(gormlogger.Info, LevelInfo, msg, data...)
+}
+
+func (g *gormV2Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	g.log(gormlogger.Warn, LevelWarn, msg, data...)
+}
+
+func (g *gormV2Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	g.log(gormlogger.Error, LevelError, msg, data...)
+}
+
+func (g *gormV2Logger) log(atLeast gormlogger.LogLevel, level Level, msg string, data ...interface{}) {
+	if g.level >= atLeast {
+		handle(g.l.handler, Entry{Level: level, Message: fmt.Sprintf(msg, data...), Source: g.l.source})
+	}
+}
+
+func (g *gormV2Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	failed := err != nil && !errors.Is(err, gormv2.ErrRecordNotFound)
+	if g.level < gormlogger.Info && !(failed && g.level >= gormlogger.Error) {
+		return
+	}
+
+	g.mu.Lock()
+	_, rows := fc()
+	sql, vars := g.sql, g.vars
+	g.sql, g.vars = "", nil
+	g.mu.Unlock()
+
+	if g.level >= gormlogger.Info {
+		handle(g.l.handler, Entry{Level: LevelDebug, Message: "sql", SQL: sql, Vars: vars,
+			Duration: time.Since(begin), Rows: rows, Source: g.l.source})
+	}
+	if failed {
+		handle(g.l.handler, Entry{Level: LevelError, Message: "error", Source: g.l.source, Err: err})
+	}
+}
+
+// ParamsFilter keeps the statement with its values redacted for Trace, which is what calls it
+// Gorm's statement given to Trace has the values in place, so it has none.
+func (g *gormV2Logger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	g.sql, g.vars = sql, redactVars(sql, params)
+	return sql, nil
+}
+
+func fromGormV2Error(err error) error {
+	if errors.Is(err, gormv2.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// isSoftDeleted is whether records of s are deleted by setting DeletedAt, which Gorm v2 doesn't
+// handle by itself
+func isSoftDeleted(s *schema.Schema) bool {
+	f := s.LookUpField("DeletedAt")
+	return f != nil && f.DBName == "deleted_at" && f.FieldType != reflect.TypeOf(gormv2.DeletedAt{})
+}
+
+// forEachAssociation calls fc with the path to every association within s (such as "Dogs" and
+// "Dogs.DogToys"), not going back into the ones it's already within
+func forEachAssociation(s *schema.Schema, prefix string, within map[*schema.Schema]bool, fc func(path string, s *schema.Schema)) {
+	within[s] = true
+	defer delete(within, s)
+
+	for _, rel := range s.Relationships.Relations {
+		if within[rel.FieldSchema] {
+			continue
+		}
+		path := prefix + rel.Name
+		fc(path, rel.FieldSchema)
+		forEachAssociation(rel.FieldSchema, path+".", within, fc)
+	}
+}
+
+// noAutoUpdateAssociations returns the associations of s tagged with association_autoupdate:false
+func noAutoUpdateAssociations(s *schema.Schema) []string {
+	names := make([]string, 0)
+	for _, rel := range s.Relationships.Relations {
+		if strings.Contains(rel.Field.Tag.Get("gorm"), "association_autoupdate:false") {
+			names = append(names, rel.Name)
+		}
+	}
+	return names
+}
+
+// setIDsBeforeCreate gives value a new id if it has none, and if nested, so are the mdls
+// within it (an embedded struct which is never initialized is not considered there)
+func setIDsBeforeCreate(value interface{}, nested bool) {
+	m, ok := value.(mdl.IModel)
+	if !ok || isNil(m) {
+		return
+	}
+	if m.GetID() == nil {
+		m.SetID(datatype.NewUUID())
+	}
+	if !nested {
+		return
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(m))
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Anonymous || !v.Type().Field(i).IsExported() {
+			continue
+		}
+		for _, nestedModel := range nestedModelsAtField(v.Field(i)) {
+			setIDsBeforeCreate(nestedModel, true)
+		}
+	}
+}
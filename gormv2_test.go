@@ -0,0 +1,324 @@
+package qry
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/dialect"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	gormv2 "gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// openGormV2 opens a temp-file SQLite database with Gorm v2, with the tables of the test mdls
+func openGormV2(t *testing.T) *gormv2.DB {
+	dir, err := os.MkdirTemp("", "qry")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Gorm v2 warns that mdl.BaseModel's BeforeCreate is of Gorm v1
+	gormlogger.Default = gormlogger.Discard
+
+	prev := dialect.Current()
+	dialect.Use(dialect.SQLite)
+	t.Cleanup(func() {
+		dialect.Use(prev)
+		os.RemoveAll(dir)
+	})
+
+	dbv2, err := gormv2.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gormv2.Config{
+		NamingStrategy:                           schema.NamingStrategy{SingularTable: true},
+		DisableForeignKeyConstraintWhenMigrating: true,
+		Logger:                                   gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := dbv2.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := dbv2.AutoMigrate(&TestModel{}, &Dog{}, &DogToy{}, &Cat{}, &VersionedModel{}, &VersionedChild{}); err != nil {
+		t.Fatal(err)
+	}
+	return dbv2
+}
+
+func createGormV2TestModel(t *testing.T, dbv2 *gormv2.DB) *TestModel {
+	tm := &TestModel{
+		Name: "first",
+		Age:  3,
+		Dogs: []Dog{
+			{Name: "Buddy", Color: "black", DogToys: []DogToy{{ToyName: "bone"}}},
+			{Name: "Max", Color: "white"},
+		},
+	}
+	if err := Q(dbv2).Create(tm).Error(); err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func TestGormV2_Create_ShouldSetIDsOfNestedMdls(t *testing.T) {
+	dbv2 := openGormV2(t)
+	tm := createGormV2TestModel(t, dbv2)
+
+	if !assert.NotNil(t, tm.ID) || !assert.Len(t, tm.Dogs, 2) {
+		return
+	}
+	assert.NotNil(t, tm.Dogs[0].ID)
+	assert.Equal(t, tm.ID.String(), tm.Dogs[0].TestModelID.String())
+	if assert.Len(t, tm.Dogs[0].DogToys, 1) {
+		assert.NotNil(t, tm.Dogs[0].DogToys[0].ID)
+	}
+}
+
+func TestGormV2_FirstByNestedField_ShouldPreloadEverything(t *testing.T) {
+	dbv2 := openGormV2(t)
+	tm := createGormV2TestModel(t, dbv2)
+
+	searched := TestModel{}
+	if err := Q(dbv2, C("Dogs.Name =", "Buddy")).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, tm.ID.String(), searched.ID.String())
+	if assert.Len(t, searched.Dogs, 2) {
+		toys := len(searched.Dogs[0].DogToys) + len(searched.Dogs[1].DogToys)
+		assert.Equal(t, 1, toys)
+	}
+}
+
+func TestGormV2_First_WhenNotFound_ShouldBeErrNotFound(t *testing.T) {
+	dbv2 := openGormV2(t)
+	createGormV2TestModel(t, dbv2)
+
+	err := Q(dbv2, C("Name =", "nobody")).First(&TestModel{}).Error()
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestGormV2_FindAndCount_ShouldOrderLimitAndSkipDeleted(t *testing.T) {
+	dbv2 := openGormV2(t)
+	for _, name := range []string{"a", "b", "c"} {
+		if err := Q(dbv2).Create(&TestModel{Name: name, Age: 1}).Error(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Deleted by setting DeletedAt, as Gorm v1 does
+	if err := dbv2.Exec(`UPDATE "test_model" SET deleted_at = CURRENT_TIMESTAMP WHERE real_name_column = ?`, "c").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tms := make([]TestModel, 0)
+	if err := Q(dbv2, C("Age =", 1)).Order("Name", OrderDesc).Limit(1).Find(&tms).Error(); !assert.Nil(t, err) {
+		return
+	}
+	if assert.Len(t, tms, 1) {
+		assert.Equal(t, "b", tms[0].Name)
+	}
+
+	var no int
+	if assert.Nil(t, Q(dbv2, C("Age =", 1)).Count(&TestModel{}, &no).Error()) {
+		assert.Equal(t, 2, no)
+	}
+}
+
+func TestGormV2_UpdateFields_ShouldUpdateNestedFields(t *testing.T) {
+	dbv2 := openGormV2(t)
+	tm := createGormV2TestModel(t, dbv2)
+
+	q := Q(dbv2, C("Dogs.Name =", "Buddy"))
+	fields := map[string]interface{}{"Age": 4, "Dogs.Color": "gray"}
+	if err := q.UpdateFields(&TestModel{}, fields).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), q.RowsAffected()) // one TestModel and its Dog named "Buddy"
+
+	searched := TestModel{}
+	if err := Q(dbv2, C("ID =", tm.ID)).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 4, searched.Age)
+	for _, dog := range searched.Dogs {
+		if dog.Name == "Buddy" {
+			assert.Equal(t, "gray", dog.Color)
+		} else {
+			assert.Equal(t, "white", dog.Color)
+		}
+	}
+}
+
+func TestGormV2_Save_ShouldBeVersioned(t *testing.T) {
+	dbv2 := openGormV2(t)
+	vm := VersionedModel{Name: "first"}
+	if err := Q(dbv2).Create(&vm).Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := vm
+	vm.Name = "second"
+	if err := Q(dbv2).Save(&vm).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, vm.Version)
+
+	stale.Name = "third"
+	err := Q(dbv2).Save(&stale).Error()
+	assert.True(t, errors.Is(err, ErrStaleObject))
+}
+
+func TestGormV2_Delete_ShouldDeletePeggedMdls(t *testing.T) {
+	dbv2 := openGormV2(t)
+	tm := createGormV2TestModel(t, dbv2)
+
+	q := Q(dbv2)
+	if err := q.Delete(tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), q.RowsAffected())
+
+	for _, tbl := range []string{"test_model", "dog"} {
+		var count int64
+		if assert.Nil(t, dbv2.Table(tbl).Count(&count).Error) {
+			assert.Equal(t, int64(0), count, tbl)
+		}
+	}
+}
+
+func TestGormV2_SaveGraph_ShouldSyncPeggedMdls(t *testing.T) {
+	dbv2 := openGormV2(t)
+	tm := createGormV2TestModel(t, dbv2)
+
+	tm.Dogs = []Dog{tm.Dogs[1], {Name: "Rocky", Color: "brown"}}
+	tm.Dogs[0].Color = "gray"
+	if err := Q(dbv2).SaveGraph(tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	searched := TestModel{}
+	if err := Q(dbv2, C("ID =", tm.ID)).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+	colors := make(map[string]string)
+	for _, dog := range searched.Dogs {
+		colors[dog.Name] = dog.Color
+	}
+	assert.Equal(t, map[string]string{"Max": "gray", "Rocky": "brown"}, colors)
+}
+
+func TestGormV2_ToSQL_ShouldRenderWithoutRunning(t *testing.T) {
+	dbv2 := openGormV2(t)
+
+	stmt, vars, err := Q(dbv2, C("Name =", "same")).ToSQL(&TestModel{}, QueryTypeFind)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(stmt, "SELECT"), stmt)
+	assert.Contains(t, stmt, `"test_model".deleted_at IS NULL`)
+	assert.Equal(t, []interface{}{"same"}, vars)
+}
+
+func TestGormV2_BuildQueryAndGetDB_ShouldBeGormV1Only(t *testing.T) {
+	dbv2 := openGormV2(t)
+
+	_, err := Q(dbv2).BuildQuery(&TestModel{})
+	assert.IsType(t, &BuilderError{}, err)
+	assert.Nil(t, Q(dbv2).GetDB())
+}
+
+func TestQ_WhenNotGorm_ShouldBeBuilderError(t *testing.T) {
+	err := Q("not a db").First(&TestModel{}).Error()
+	assert.IsType(t, &BuilderError{}, err)
+}
+
+func TestGormV2_Create_PegAssoc_ShouldAssociate(t *testing.T) {
+	dbv2 := openGormV2(t)
+	cat := Cat{BaseModel: mdl.BaseModel{ID: datatype.NewUUID()}, Name: "Kitty"}
+	if err := Q(dbv2).Create(&cat).Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := TestModel{Name: "first", Cats: []Cat{cat}}
+	if err := Q(dbv2).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	searched := TestModel{}
+	if err := Q(dbv2, C("ID =", tm.ID)).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+	if assert.Len(t, searched.Cats, 1) {
+		assert.Equal(t, "Kitty", searched.Cats[0].Name)
+	}
+}
+
+func TestGormV2_WithLogHandler_ShouldLogRedactedStatements(t *testing.T) {
+	useRedactedColumns(t)
+	RedactColumns("test_model", "real_name_column")
+	dbv2 := openGormV2(t)
+	if err := Q(dbv2).Create(&TestModel{Name: "first", Age: 1}).Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err := Q(dbv2.Debug(), C("Name =", "first").And("Age =", 1)).WithLogHandler(NewJSONHandler(&buf, LevelDebug)).
+		First(&TestModel{}).Error()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var selected map[string]interface{} // after the preloads
+	for _, line := range jsonLines(t, &buf) {
+		if strings.Contains(line["sql"].(string), "FROM `test_model`") {
+			selected = line
+		}
+	}
+	if assert.NotNil(t, selected) {
+		assert.Equal(t, "sql", selected["msg"])
+		assert.Equal(t, []interface{}{"'***'", "1"}, selected["vars"])
+		assert.Contains(t, selected["source"], "gormv2_test.go")
+	}
+	assert.NotContains(t, buf.String(), "first")
+}
+
+func TestGormV2_UseLogHandler_ShouldLogErrorsAtErrorLevel(t *testing.T) {
+	prev := CurrentLogHandler()
+	defer UseLogHandler(prev)
+	var buf bytes.Buffer
+	UseLogHandler(NewJSONHandler(&buf, LevelDebug))
+
+	dbv2 := openGormV2(t) // silent
+	tm := TestModel{Name: "first"}
+	if err := Q(dbv2).Create(&tm).Error(); err != nil {
+		t.Fatal(err)
+	}
+	duplicate := TestModel{BaseModel: mdl.BaseModel{ID: tm.ID}}
+	assert.Error(t, Q(dbv2).Create(&duplicate).Error())
+	assert.Len(t, jsonLines(t, &buf), 1, "only the one of qry")
+
+	// Also the one of Gorm, as on Gorm v1
+	buf.Reset()
+	errorLevel := dbv2.Session(&gormv2.Session{Logger: dbv2.Logger.LogMode(gormlogger.Error)})
+	assert.Error(t, Q(errorLevel).Create(&duplicate).Error())
+	lines := jsonLines(t, &buf)
+	if assert.Len(t, lines, 2) {
+		for _, line := range lines {
+			assert.Equal(t, "ERROR", line["level"])
+			assert.Contains(t, line["error"], "UNIQUE")
+			assert.Contains(t, line["source"], "gormv2_test.go")
+			assert.NotContains(t, line, "sql")
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package qry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StatementInfo is a statement run by a terminal, which hooks are given before and after it's run
+type StatementInfo struct {
+	// Context is the context of the query (see WithContext), which BeforeStatement may replace,
+	// such as with one of a span, for AfterStatement
+	Context context.Context
+
+	Op     string // the terminal, such as Find or Create
+	Table  string // the table of the model of the terminal
+	Source string // file:line which the query is run from
+
+	SQL  string        // the statement with its placeholders
+	Vars []interface{} // the values of the placeholders, redacted as in the log (see RedactColumns)
+
+	// What's set once it's run, before AfterStatement
+	Start    time.Time
+	Duration time.Duration
+	Rows     int64 // rows affected, -1 if it's a read (its rows are not known as they're read later)
+	Err      error
+}
+
+// Hook is called before and after every statement run by a terminal, such as for metrics or
+// tracing. It's given the same StatementInfo both times.
+type Hook interface {
+	BeforeStatement(s *StatementInfo)
+	AfterStatement(s *StatementInfo)
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []Hook
+)
+
+// UseHooks sets the hooks of the statements of every query, called in order before they're run
+// and in reverse order after, none to remove them
+//
+//	qry.UseHooks(qry.NewPrometheusCollector(), qry.NewSpanRecorder(1000))
+func UseHooks(h ...Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = h
+}
+
+// CurrentHooks returns the hooks of the statements of every query
+func CurrentHooks() []Hook {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return hooks
+}
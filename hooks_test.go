@@ -0,0 +1,204 @@
+package qry
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingHook struct {
+	mu     sync.Mutex
+	before []StatementInfo
+	after  []StatementInfo
+}
+
+func (h *recordingHook) BeforeStatement(s *StatementInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.before = append(h.before, *s)
+}
+
+func (h *recordingHook) AfterStatement(s *StatementInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.after = append(h.after, *s)
+}
+
+func useHooks(t *testing.T, h ...Hook) {
+	prev := CurrentHooks()
+	UseHooks(h...)
+	t.Cleanup(func() { UseHooks(prev...) })
+}
+
+func TestHook_ShouldBeCalledBeforeAndAfterEveryStatement(t *testing.T) {
+	h := &recordingHook{}
+	useHooks(t, h)
+
+	searched := TestModel{}
+	if err := Q(db, C("Name =", "second")).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	// the statement of TestModel, then those preloading its dogs and cats
+	if !assert.NotEmpty(t, h.after) || !assert.Len(t, h.before, len(h.after)) {
+		return
+	}
+	for _, s := range h.after {
+		assert.Equal(t, "First", s.Op)
+	}
+	before, after := h.before[0], h.after[0]
+	assert.Equal(t, "First", before.Op)
+	assert.Equal(t, "test_model", before.Table)
+	assert.Contains(t, before.Source, "hooks_test.go")
+	assert.Contains(t, before.SQL, "SELECT")
+	assert.Equal(t, []interface{}{"second"}, before.Vars)
+	assert.True(t, before.Start.IsZero())
+
+	assert.Equal(t, before.SQL, after.SQL)
+	assert.False(t, after.Start.IsZero())
+	assert.NotZero(t, after.Duration)
+	assert.Equal(t, int64(-1), after.Rows)
+	assert.Nil(t, after.Err)
+}
+
+func TestHook_OnWrites_ShouldHaveRowsAndErrors(t *testing.T) {
+	h := &recordingHook{}
+	useHooks(t, h)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm := TestModel{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(uuid1)}, Name: "duplicate"}
+	assert.Error(t, DB(tx).Create(&tm).Error())
+
+	tm = TestModel{Name: "new"}
+	assert.Nil(t, DB(tx).Create(&tm).Error())
+
+	if assert.Len(t, h.after, 2) {
+		assert.Equal(t, "Create", h.after[0].Op)
+		assert.Error(t, h.after[0].Err)
+		assert.Contains(t, h.after[1].SQL, "INSERT")
+		assert.Equal(t, int64(1), h.after[1].Rows)
+		assert.Nil(t, h.after[1].Err)
+	}
+}
+
+func TestHook_OnWritesOutsideTransaction_ShouldBeCalled(t *testing.T) {
+	h := &recordingHook{}
+	useHooks(t, h)
+
+	// Gorm v1 runs each of them in a transaction of its own
+	tm := TestModel{Name: "outside"}
+	if !assert.Nil(t, DB(db).Create(&tm).Error()) {
+		return
+	}
+	tm.Age = 7
+	assert.Nil(t, DB(db).Save(&tm).Error())
+	assert.Nil(t, DB(db).Delete(&tm).Error())
+
+	sqls := make(map[string]StatementInfo)
+	for _, after := range h.after {
+		for _, verb := range []string{"INSERT", "UPDATE", "DELETE"} {
+			if strings.HasPrefix(after.SQL, verb) {
+				if _, ok := sqls[verb]; !ok {
+					sqls[verb] = after
+				}
+			}
+		}
+	}
+	if assert.Contains(t, sqls, "INSERT") {
+		assert.Equal(t, "Create", sqls["INSERT"].Op)
+		assert.Equal(t, int64(1), sqls["INSERT"].Rows)
+	}
+	if assert.Contains(t, sqls, "UPDATE") {
+		assert.Equal(t, "Save", sqls["UPDATE"].Op)
+	}
+	if assert.Contains(t, sqls, "DELETE") {
+		assert.Equal(t, "Delete", sqls["DELETE"].Op)
+	}
+}
+
+func TestHook_OnGormV2_ShouldBeCalled(t *testing.T) {
+	dbv2 := openGormV2(t)
+	h := &recordingHook{}
+	useHooks(t, h)
+
+	tm := TestModel{Name: "first"}
+	assert.Nil(t, Q(dbv2).Create(&tm).Error())
+	assert.Nil(t, Q(dbv2, C("Name =", "first")).Find(&[]TestModel{}).Error())
+
+	if assert.True(t, len(h.after) >= 2) {
+		assert.Equal(t, "Create", h.after[0].Op)
+		assert.Equal(t, int64(1), h.after[0].Rows)
+		assert.Equal(t, "Find", h.after[1].Op)
+		assert.Equal(t, "test_model", h.after[1].Table)
+	}
+}
+
+func TestPrometheusCollector_ShouldWriteMetrics(t *testing.T) {
+	c := NewPrometheusCollector(0.5, 0.1)
+	c.AfterStatement(&StatementInfo{Op: "Find", Table: "test_model", Duration: 50 * time.Millisecond, Rows: -1})
+	c.AfterStatement(&StatementInfo{Op: "Find", Table: "test_model", Duration: 200 * time.Millisecond, Rows: -1})
+	c.AfterStatement(&StatementInfo{Op: "Create", Table: `a"b`, Duration: time.Second, Rows: 2, Err: ErrNotFound})
+
+	var buf bytes.Buffer
+	n, err := c.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	assert.Equal(t, `# HELP qry_statement_duration_seconds Duration of the statements run by qry.
+# TYPE qry_statement_duration_seconds histogram
+qry_statement_duration_seconds_bucket{op="Create",table="a\"b",le="0.1"} 0
+qry_statement_duration_seconds_bucket{op="Create",table="a\"b",le="0.5"} 0
+qry_statement_duration_seconds_bucket{op="Create",table="a\"b",le="+Inf"} 1
+qry_statement_duration_seconds_sum{op="Create",table="a\"b"} 1
+qry_statement_duration_seconds_count{op="Create",table="a\"b"} 1
+qry_statement_duration_seconds_bucket{op="Find",table="test_model",le="0.1"} 1
+qry_statement_duration_seconds_bucket{op="Find",table="test_model",le="0.5"} 2
+qry_statement_duration_seconds_bucket{op="Find",table="test_model",le="+Inf"} 2
+qry_statement_duration_seconds_sum{op="Find",table="test_model"} 0.25
+qry_statement_duration_seconds_count{op="Find",table="test_model"} 2
+# HELP qry_statement_errors_total Number of the statements run by qry which failed.
+# TYPE qry_statement_errors_total counter
+qry_statement_errors_total{op="Create",table="a\"b"} 1
+qry_statement_errors_total{op="Find",table="test_model"} 0
+# HELP qry_statement_rows_total Number of the rows affected by the statements run by qry.
+# TYPE qry_statement_rows_total counter
+qry_statement_rows_total{op="Create",table="a\"b"} 2
+qry_statement_rows_total{op="Find",table="test_model"} 0
+`, buf.String())
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, buf.String(), rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+}
+
+func TestSpanRecorder_ShouldKeepTheLastSpans(t *testing.T) {
+	r := NewSpanRecorder(2)
+	useHooks(t, r)
+
+	for _, name := range []string{"first", "second", "same"} {
+		assert.Nil(t, Q(db, C("Name =", name)).First(&TestModel{}).Error())
+	}
+
+	spans := r.Spans()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "qry.First", spans[0].Name)
+		assert.Equal(t, "test_model", spans[0].Table)
+		assert.Contains(t, spans[0].Source, "hooks_test.go")
+		assert.False(t, spans[1].Start.Before(spans[0].End))
+		assert.Equal(t, spans[1].End.Sub(spans[1].Start), spans[1].Duration())
+	}
+
+	r.Reset()
+	assert.Empty(t, r.Spans())
+}
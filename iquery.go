@@ -1,6 +1,9 @@
 package qry
 
 import (
+	"context"
+	"time"
+
 	"github.com/jinzhu/gorm"
 	"github.com/t2wu/qry/mdl"
 )
@@ -18,7 +21,11 @@ type IQuery interface {
 	Order(field string, order Order) IQuery
 	Limit(limit int) IQuery
 	Offset(offset int) IQuery
+	Returning(out interface{}) IQuery
 	InnerJoin(modelObj mdl.IModel, foreignObj mdl.IModel, args ...interface{}) IQuery
+	WithContext(ctx context.Context) IQuery
+	Timeout(d time.Duration) IQuery
+	WithLogHandler(h LogHandler) IQuery
 	BuildQuery(modelObj mdl.IModel) (*gorm.DB, error)
 	Take(modelObj mdl.IModel) IQuery
 	First(modelObj mdl.IModel) IQuery
@@ -29,9 +36,14 @@ type IQuery interface {
 	Delete(modelObj mdl.IModel) IQuery
 	DeleteMany(modelObjs []mdl.IModel) IQuery
 	Save(modelObj mdl.IModel) IQuery
+	SaveGraph(modelObj mdl.IModel) IQuery
 	// Update(modelObjs interface{}, attrs ...interface{}) IQuery
 	Update(modelObj mdl.IModel, p *PredicateRelationBuilder) IQuery
+	UpdateFields(modelObj mdl.IModel, fields map[string]interface{}) IQuery
+	UpdateMany(modelObjs []mdl.IModel, fields ...string) IQuery
+	ToSQL(modelObj mdl.IModel, kind QueryType, args ...interface{}) (string, []interface{}, error)
 	GetDB() *gorm.DB
 	Reset() IQuery
+	RowsAffected() int64
 	Error() error
 }
@@ -1,16 +1,15 @@
 package qry
 
-import (
-	"log"
-	"runtime"
-)
-
+// PrintFileAndLine logs err as an error of the caller of the function calling it
 func PrintFileAndLine(err error) {
-	_, filepath, line, ok := runtime.Caller(2)
-	if !ok {
-		log.Println("PrintFileAndLine unable to print file and line number")
+	printFileAndLine(CurrentLogHandler(), err)
+}
+
+func printFileAndLine(h LogHandler, err error) {
+	source := callerSource(3)
+	if source == "" {
+		handle(h, Entry{Level: LevelWarn, Message: "PrintFileAndLine unable to print file and line number"})
 		return
 	}
-	// https://stackoverflow.com/questions/5947742/how-to-change-the-output-color-of-echo-in-linux
-	log.Printf("[BetterQuery] \033[1;36m(%s:%d): %s\033[0m", filepath, line, err.Error())
+	handle(h, Entry{Level: LevelError, Message: "error", Source: source, Err: err})
 }
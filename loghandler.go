@@ -0,0 +1,206 @@
+package qry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Level is the level of an Entry, with the same values as log/slog
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch {
+	case l >= LevelError:
+		return "ERROR"
+	case l >= LevelWarn:
+		return "WARN"
+	case l >= LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// Entry is what's logged, either a statement run (SQL is set) or a message or an error
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+
+	SQL      string        // the statement with its placeholders
+	Vars     []interface{} // the values of the placeholders, *** for those of columns redacted (see RedactColumns)
+	Duration time.Duration
+	Rows     int64 // rows affected or returned, -1 if not known (such as rows yet to be read)
+
+	Source string // file:line which the query is run from
+	Err    error
+
+	Plan string // how the statement is run, if it's explained (see SlowQuery)
+}
+
+// Statement is SQL with the values of Vars in place of the placeholders
+func (e Entry) Statement() string {
+	return inlineVars(e.SQL, formatVars(e.Vars))
+}
+
+// LogHandler is where qry logs to. Use UseLogHandler to set it for every query, or
+// WithLogHandler for one.
+type LogHandler interface {
+	// Enabled is whether entries of level are handled, so they don't have to be made otherwise
+	Enabled(level Level) bool
+
+	Handle(e Entry)
+}
+
+var (
+	logHandlerMu sync.RWMutex
+	logHandler   = NewTextHandler(os.Stdout)
+)
+
+// UseLogHandler sets the handler every query logs to, unless given one with WithLogHandler,
+// nil to log nothing. By default it's NewTextHandler(os.Stdout).
+func UseLogHandler(h LogHandler) {
+	logHandlerMu.Lock()
+	defer logHandlerMu.Unlock()
+	logHandler = h
+}
+
+// CurrentLogHandler returns the handler every query logs to
+func CurrentLogHandler() LogHandler {
+	logHandlerMu.RLock()
+	defer logHandlerMu.RUnlock()
+	return logHandler
+}
+
+func handle(h LogHandler, e Entry) {
+	if h == nil || !h.Enabled(e.Level) {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = NowFunc()
+	}
+	h.Handle(e)
+}
+
+// ------------------
+
+// NewTextHandler returns a handler which writes colorized lines to w, statements being
+// formatted by LogFormatter
+func NewTextHandler(w io.Writer) LogHandler {
+	return &textHandler{logger: log.New(w, "\r\n", log.Flags()&^(log.Ldate|log.Ltime))}
+}
+
+type textHandler struct {
+	logger *log.Logger
+}
+
+func (h *textHandler) Enabled(level Level) bool {
+	return true
+}
+
+func (h *textHandler) Handle(e Entry) {
+	if e.SQL != "" {
+		messages := LogFormatter("sql", e.Source, e.Duration, e.SQL, e.Vars, e.Rows)
+		if e.Message != "sql" {
+			messages = append(messages, fmt.Sprintf("\033[31;1m%s\033[0m", e.Message))
+		}
+		if e.Err != nil {
+			messages = append(messages, fmt.Sprintf("\033[31;1m%s\033[0m", e.Err))
+		}
+		if e.Plan != "" {
+			messages = append(messages, "\n"+e.Plan)
+		}
+		h.logger.Println(messages...)
+		return
+	}
+
+	msg := e.Message
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	// https://stackoverflow.com/questions/5947742/how-to-change-the-output-color-of-echo-in-linux
+	h.logger.Printf("[BetterQuery] \033[1;36m(%s): %s\033[0m", e.Source, msg)
+}
+
+// ------------------
+
+// NewJSONHandler returns a handler which writes entries of level and above to w, one JSON object
+// per line, such as
+// {"time":"...","level":"INFO","msg":"sql","sql":"SELECT ... WHERE id = ?","vars":["'1'"],
+// "duration_ms":0.12,"rows":1,"source":"service.go:12"}
+func NewJSONHandler(w io.Writer, level Level) LogHandler {
+	return &jsonHandler{w: w, level: level}
+}
+
+type jsonHandler struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level Level
+}
+
+type jsonEntry struct {
+	Time     string          `json:"time"`
+	Level    string          `json:"level"`
+	Message  string          `json:"msg"`
+	SQL      string          `json:"sql,omitempty"`
+	Vars     []string        `json:"vars,omitempty"`
+	Duration *float64        `json:"duration_ms,omitempty"`
+	Rows     *int64          `json:"rows,omitempty"`
+	Source   string          `json:"source,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Plan     json.RawMessage `json:"plan,omitempty"`
+}
+
+func (h *jsonHandler) Enabled(level Level) bool {
+	return level >= h.level
+}
+
+func (h *jsonHandler) Handle(e Entry) {
+	je := jsonEntry{
+		Time:    e.Time.Format(time.RFC3339Nano),
+		Level:   e.Level.String(),
+		Message: e.Message,
+		Source:  e.Source,
+	}
+	if e.SQL != "" {
+		duration := float64(e.Duration.Microseconds()) / 1000
+		rows := e.Rows
+		je.SQL, je.Vars, je.Duration, je.Rows = e.SQL, formatVars(e.Vars), &duration, &rows
+	}
+	if e.Err != nil {
+		je.Error = e.Err.Error()
+	}
+	if e.Plan != "" {
+		je.Plan = jsonOf(e.Plan)
+	}
+
+	b, err := json.Marshal(je)
+	if err != nil {
+		b, _ = json.Marshal(jsonEntry{Time: je.Time, Level: LevelError.String(), Message: fmt.Sprintf("cannot log: %s", err)})
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.w.Write(append(b, '\n'))
+}
+
+// jsonOf is s if it's JSON, or s as a JSON string
+func jsonOf(s string) json.RawMessage {
+	if json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	b, _ := json.Marshal(s)
+	return b
+}
@@ -0,0 +1,128 @@
+package qry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func jsonLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	lines := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		m := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestWithLogHandler_JSON_ShouldLogStatementsWithFields(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	var buf bytes.Buffer
+	searched := TestModel{}
+	err := Q(tx, C("Name =", "second")).WithLogHandler(NewJSONHandler(&buf, LevelDebug)).First(&searched).Error()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	lines := jsonLines(t, &buf)
+	if !assert.NotEmpty(t, lines) {
+		return
+	}
+	first := lines[0]
+	assert.Equal(t, "DEBUG", first["level"])
+	assert.Equal(t, "sql", first["msg"])
+	assert.Contains(t, first["sql"], "SELECT")
+	assert.Equal(t, []interface{}{"'second'"}, first["vars"])
+	assert.Equal(t, float64(1), first["rows"])
+	assert.Contains(t, first, "duration_ms")
+	assert.Contains(t, first["source"], "loghandler_test.go")
+	assert.NotContains(t, buf.String(), "\033[")
+}
+
+func TestWithLogHandler_JSON_ShouldLogErrorsAtLevel(t *testing.T) {
+	var buf bytes.Buffer
+	err := DB(db).WithLogHandler(NewJSONHandler(&buf, LevelWarn)).Order("Dogs.Name", OrderAsc).First(&TestModel{}).Error()
+	if !assert.Error(t, err) {
+		return
+	}
+
+	lines := jsonLines(t, &buf)
+	if assert.Len(t, lines, 1) { // no statement as it's below LevelWarn
+		assert.Equal(t, "ERROR", lines[0]["level"])
+		assert.Equal(t, err.Error(), lines[0]["error"])
+		assert.Contains(t, lines[0]["source"], "loghandler_test.go")
+		assert.NotContains(t, lines[0], "sql")
+	}
+}
+
+func TestUseLogHandler_ShouldBeUsedUnlessGivenOne(t *testing.T) {
+	prev := CurrentLogHandler()
+	defer UseLogHandler(prev)
+
+	var global, own bytes.Buffer
+	UseLogHandler(NewJSONHandler(&global, LevelDebug))
+
+	q := DB(db).WithLogHandler(NewJSONHandler(&own, LevelDebug))
+	assert.Nil(t, q.Q(C("Name =", "second")).First(&TestModel{}).Error())
+	assert.Equal(t, 0, global.Len())
+	assert.NotEqual(t, 0, own.Len())
+
+	assert.Nil(t, Q(db, C("Name =", "second")).First(&TestModel{}).Error())
+	assert.NotEqual(t, 0, global.Len())
+
+	// nil logs nothing
+	UseLogHandler(nil)
+	assert.NotNil(t, Q(db, C("NotAField =", 1)).First(&TestModel{}).Error())
+}
+
+func TestWithLogHandler_ShouldNotBeSetOnTheDB(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	var buf bytes.Buffer
+	tm := TestModel{Name: "logged", Age: 1}
+	if err := DB(tx).WithLogHandler(NewJSONHandler(&buf, LevelDebug)).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+	err := Q(tx, C("Name =", "logged")).WithLogHandler(NewJSONHandler(&buf, LevelDebug)).
+		UpdateFields(&TestModel{}, map[string]interface{}{"Age": 2}).Error()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Contains(t, buf.String(), "logged")
+
+	if err := tx.Exec("SELECT 424242").Error; !assert.Nil(t, err) {
+		return
+	}
+	assert.NotContains(t, buf.String(), "424242")
+}
+
+func TestEntry_Statement_ShouldInlineVars(t *testing.T) {
+	e := Entry{SQL: "SELECT * FROM t WHERE a = ? AND b IN (?,?) AND c = ?", Vars: []interface{}{"x", 1, nil, time.Time{}}}
+	assert.Equal(t, "SELECT * FROM t WHERE a = 'x' AND b IN (1,NULL) AND c = '0000-00-00 00:00:00'", e.Statement())
+
+	e = Entry{SQL: "UPDATE t SET a = $1 WHERE id = $2", Vars: []interface{}{true, "id"}}
+	assert.Equal(t, "UPDATE t SET a = true WHERE id = 'id'", e.Statement())
+}
+
+func TestGormEntry_ShouldSeparateFields(t *testing.T) {
+	e := gormEntry("sql", "a.go:1", time.Millisecond, "SELECT 1", []interface{}{}, int64(2))
+	assert.Equal(t, Entry{Level: LevelDebug, Message: "sql", SQL: "SELECT 1", Vars: []interface{}{}, Duration: time.Millisecond, Rows: 2, Source: "a.go:1"}, e)
+
+	err := errors.New("bad")
+	e = gormEntry("error", "a.go:2", err)
+	assert.Equal(t, Entry{Level: LevelError, Message: "error", Source: "a.go:2", Err: err}, e)
+}
@@ -11,10 +11,7 @@ import (
 
 // BaseModel is the base class domain mdl which has standard ID
 type BaseModel struct {
-	// For MySQL
-	// ID        *datatype.UUID `gorm:"type:binary(16);primary_key;" json:"id"`
-
-	// For Postgres
+	// The column type is of the dialect (such as binary(16) for MySQL), see datatype.UUID.GormDataType
 	ID        *datatype.UUID `gorm:"type:uuid;primary_key;" json:"id"`
 	CreatedAt time.Time      `sql:"index" json:"createdAt"`
 	UpdatedAt time.Time      `json:"updatedAt"`
@@ -86,6 +83,14 @@ type IModel interface {
 	// GetDeletedAt() // we don't use this one
 }
 
+// IVersioned is a domain mdl with optimistic locking. The version is kept in field "Version".
+// (Alternatively, tag an int field with `qry:"version"`.)
+type IVersioned interface {
+	IModel
+	GetVersion() int
+	SetVersion(version int)
+}
+
 // ---------------
 
 // IHasTableName we know if there is Gorm's defined custom TableName
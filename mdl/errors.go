@@ -0,0 +1,17 @@
+package mdl
+
+import "fmt"
+
+// InvalidFieldError is when a field (or json key) does not exist in the mdl
+type InvalidFieldError struct {
+	Model string // type name of the mdl
+	Field string
+}
+
+func (e *InvalidFieldError) Error() string {
+	return fmt.Sprintf("field \"%s\" does not exist", e.Field)
+}
+
+func newInvalidFieldError(modelObj IModel, field string) *InvalidFieldError {
+	return &InvalidFieldError{Model: GetModelTypeNameFromIModel(modelObj), Field: field}
+}
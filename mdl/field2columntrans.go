@@ -1,7 +1,6 @@
 package mdl
 
 import (
-	"fmt"
 	"reflect"
 	"strings"
 
@@ -15,7 +14,7 @@ func FieldNameToColumn(modelObj IModel, fieldName string) (string, error) {
 	structField, ok := reflect.TypeOf(modelObj).Elem().FieldByName(first)
 	if !ok {
 		// debug.PrintStack()
-		return "", fmt.Errorf("field \"%s\" does not exist", first)
+		return "", newInvalidFieldError(modelObj, first)
 	}
 
 	columnName := strcase.SnakeCase(first)
@@ -62,7 +61,7 @@ func FieldNameToJSONName(modelObj IModel, fieldName string) (string, error) {
 	structField, ok := reflect.TypeOf(modelObj).Elem().FieldByName(first)
 	if !ok {
 		// debug.PrintStack()
-		return "", fmt.Errorf("field \"%s\" does not exist", first)
+		return "", newInvalidFieldError(modelObj, first)
 	}
 
 	jsonName := strcase.LowerCamelCase(first)
@@ -97,3 +96,22 @@ func FieldNameToJSONName(modelObj IModel, fieldName string) (string, error) {
 
 	return jsonName, nil
 }
+
+// ColumnToFieldName is the reverse of FieldNameToColumn, but for the mdl's own columns only
+func ColumnToFieldName(modelObj IModel, column string) (string, error) {
+	for _, structField := range reflect.VisibleFields(reflect.TypeOf(modelObj).Elem()) {
+		if structField.Anonymous || !structField.IsExported() {
+			continue
+		}
+
+		columnName, err := FieldNameToColumn(modelObj, structField.Name)
+		if err != nil {
+			return "", err
+		}
+		if strings.EqualFold(columnName, column) {
+			return structField.Name, nil
+		}
+	}
+
+	return "", newInvalidFieldError(modelObj, column)
+}
@@ -1,6 +1,7 @@
 package mdl
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -75,3 +76,31 @@ func TestFieldNameToColumnWrongName_Error(t *testing.T) {
 	_, err := FieldNameToColumn(v, "NotHere")
 	assert.Error(t, err)
 }
+
+func TestFieldNameToColumn_NonExistingNestedField_ShouldGiveInvalidFieldError(t *testing.T) {
+	_, err := FieldNameToColumn(&Person{}, "Pet.Bogus")
+	var fieldErr *InvalidFieldError
+	if assert.True(t, errors.As(err, &fieldErr)) {
+		assert.Equal(t, "Pet", fieldErr.Model)
+		assert.Equal(t, "Bogus", fieldErr.Field)
+	}
+}
+
+func TestColumnToFieldName_works(t *testing.T) {
+	field, err := ColumnToFieldName(&Person{}, "first_name")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "FirstName", field)
+	}
+
+	field, err = ColumnToFieldName(&Person{}, "id") // within BaseModel
+	if assert.Nil(t, err) {
+		assert.Equal(t, "ID", field)
+	}
+}
+
+func TestColumnToFieldName_CustomColumn_works(t *testing.T) {
+	field, err := ColumnToFieldName(&Person{}, "My_columnname")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "CustomColumn", field)
+	}
+}
@@ -1,7 +1,6 @@
 package mdl
 
 import (
-	"fmt"
 	"reflect"
 	"strings"
 )
@@ -38,7 +37,7 @@ loop:
 
 	if fieldName == "" {
 		// Not found
-		return "", fmt.Errorf("field \"%s\" does not exist", first)
+		return "", newInvalidFieldError(modelObj, first)
 	}
 
 	// Now, traverse the rest
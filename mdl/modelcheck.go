@@ -54,7 +54,7 @@ func GetModelFieldTypeInModelIfValid(modelObj IModel, field string) (reflect.Typ
 	structField, ok := reflect.TypeOf(modelObj).Elem().FieldByName(first)
 	if !ok {
 		debug.PrintStack()
-		return nil, fmt.Errorf("invalid field")
+		return nil, newInvalidFieldError(modelObj, first)
 	}
 
 	typ := structField.Type
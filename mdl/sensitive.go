@@ -0,0 +1,23 @@
+package mdl
+
+import (
+	"reflect"
+	"strings"
+)
+
+// GetSensitiveFieldNames returns the names of the fields of modelObj tagged with
+// `qry:"sensitive"`, whose values are not to be logged
+func GetSensitiveFieldNames(modelObj IModel) []string {
+	names := make([]string, 0)
+	typ := reflect.TypeOf(modelObj).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		for _, val := range strings.Split(field.Tag.Get("qry"), ",") {
+			if strings.TrimSpace(val) == "sensitive" {
+				names = append(names, field.Name)
+				break
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,19 @@
+package mdl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TaggedSensitive struct {
+	BaseModel
+	Email    string
+	Password string `qry:"sensitive"`
+	Token    string `gorm:"column:api_token" qry:"version, sensitive"`
+}
+
+func TestGetSensitiveFieldNames_ShouldBeTheTaggedFields(t *testing.T) {
+	assert.Equal(t, []string{"Password", "Token"}, GetSensitiveFieldNames(&TaggedSensitive{}))
+	assert.Empty(t, GetSensitiveFieldNames(&Person{}))
+}
@@ -0,0 +1,56 @@
+package mdl
+
+import (
+	"reflect"
+	"strings"
+)
+
+// GetVersionFieldName returns the name of the version field for optimistic locking, if modelObj
+// is IVersioned or has an int field tagged with `qry:"version"`
+func GetVersionFieldName(modelObj IModel) (string, bool) {
+	typ := reflect.TypeOf(modelObj).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Type.Kind() != reflect.Int {
+			continue
+		}
+		for _, val := range strings.Split(field.Tag.Get("qry"), ",") {
+			if strings.TrimSpace(val) == "version" {
+				return field.Name, true
+			}
+		}
+	}
+
+	if _, ok := modelObj.(IVersioned); ok {
+		if field, ok := typ.FieldByName("Version"); ok && field.Type.Kind() == reflect.Int {
+			return "Version", true
+		}
+	}
+
+	return "", false
+}
+
+// GetVersion gets the version of modelObj, if it is versioned
+func GetVersion(modelObj IModel) (int, bool) {
+	if m, ok := modelObj.(IVersioned); ok {
+		return m.GetVersion(), true
+	}
+
+	fieldName, ok := GetVersionFieldName(modelObj)
+	if !ok {
+		return 0, false
+	}
+	return int(reflect.ValueOf(modelObj).Elem().FieldByName(fieldName).Int()), true
+}
+
+// SetVersion sets the version of modelObj, if it is versioned
+func SetVersion(modelObj IModel, version int) {
+	if m, ok := modelObj.(IVersioned); ok {
+		m.SetVersion(version)
+		return
+	}
+
+	if fieldName, ok := GetVersionFieldName(modelObj); ok {
+		reflect.ValueOf(modelObj).Elem().FieldByName(fieldName).SetInt(int64(version))
+	}
+}
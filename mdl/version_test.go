@@ -0,0 +1,51 @@
+package mdl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TaggedVersion struct {
+	BaseModel
+	Rev int `qry:"version"`
+}
+
+type InterfaceVersion struct {
+	BaseModel
+	Version int
+}
+
+func (m *InterfaceVersion) GetVersion() int {
+	return m.Version
+}
+
+func (m *InterfaceVersion) SetVersion(version int) {
+	m.Version = version
+}
+
+func TestGetVersionFieldName_Tagged_ShouldBeTheTaggedField(t *testing.T) {
+	name, ok := GetVersionFieldName(&TaggedVersion{})
+	assert.True(t, ok)
+	assert.Equal(t, "Rev", name)
+}
+
+func TestGetVersionFieldName_IVersioned_ShouldBeVersion(t *testing.T) {
+	name, ok := GetVersionFieldName(&InterfaceVersion{})
+	assert.True(t, ok)
+	assert.Equal(t, "Version", name)
+}
+
+func TestGetVersionFieldName_NotVersioned_ShouldBeFalse(t *testing.T) {
+	_, ok := GetVersionFieldName(&Person{})
+	assert.False(t, ok)
+}
+
+func TestSetVersion_Tagged_ShouldSetTheTaggedField(t *testing.T) {
+	m := TaggedVersion{Rev: 3}
+	SetVersion(&m, 4)
+	version, ok := GetVersion(&m)
+	assert.True(t, ok)
+	assert.Equal(t, 4, version)
+	assert.Equal(t, 4, m.Rev)
+}
@@ -0,0 +1,92 @@
+package qry
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NPlusOne is the detection of N+1 statements, which are of the same shape (the same Fingerprint,
+// but for the values) and run again and again by a terminal, such as a statement of each row.
+// A detection is logged at LevelWarn.
+type NPlusOne struct {
+	// Threshold is the times a statement of the same shape is run by a terminal to be detected,
+	// 0 detects none
+	Threshold int
+
+	// OnDetect is also called with each detection, such as to fail a test (see qrytest.DetectNPlusOne)
+	OnDetect func(d NPlusOneDetection)
+}
+
+// NPlusOneDetection is a statement run again and again by a terminal
+type NPlusOneDetection struct {
+	Op     string // the terminal, such as CreateMany
+	Table  string // the table of the model of the terminal
+	Source string // file:line which the query is run from
+
+	Shape      string // the Fingerprint of the statement
+	Count      int    // the times it's run
+	Statements int    // all the statements run by the terminal
+}
+
+func (d NPlusOneDetection) String() string {
+	return fmt.Sprintf("N+1 query: %s on %s (%s) ran %d of %d statements as: %s",
+		d.Op, d.Table, d.Source, d.Count, d.Statements, d.Shape)
+}
+
+var (
+	nPlusOneMu sync.RWMutex
+	nPlusOne   NPlusOne
+)
+
+// UseNPlusOne sets the detection of N+1 statements of every query, such as in tests or development
+//
+//	qry.UseNPlusOne(qry.NPlusOne{Threshold: 5})
+func UseNPlusOne(n NPlusOne) {
+	nPlusOneMu.Lock()
+	defer nPlusOneMu.Unlock()
+	nPlusOne = n
+}
+
+// CurrentNPlusOne returns the detection of N+1 statements
+func CurrentNPlusOne() NPlusOne {
+	nPlusOneMu.RLock()
+	defer nPlusOneMu.RUnlock()
+	return nPlusOne
+}
+
+// shapes counts the statements of a terminal by their shape
+type shapes struct {
+	order  []string
+	counts map[string]int
+	total  int
+}
+
+func (s *shapes) add(sql string) {
+	if s.counts == nil {
+		s.counts = make(map[string]int)
+	}
+	shape := Fingerprint(sql)
+	if _, ok := s.counts[shape]; !ok {
+		s.order = append(s.order, shape)
+	}
+	s.counts[shape]++
+	s.total++
+}
+
+// detect returns the N+1 statements of the run r
+func (s *shapes) detect(r *terminalRun, threshold int) []NPlusOneDetection {
+	detections := make([]NPlusOneDetection, 0)
+	for _, shape := range s.order {
+		if count := s.counts[shape]; count >= threshold {
+			detections = append(detections, NPlusOneDetection{
+				Op:         r.op,
+				Table:      r.table,
+				Source:     r.source,
+				Shape:      shape,
+				Count:      count,
+				Statements: s.total,
+			})
+		}
+	}
+	return detections
+}
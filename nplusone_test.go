@@ -0,0 +1,81 @@
+package qry
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func useNPlusOne(t *testing.T, n NPlusOne) {
+	prev := CurrentNPlusOne()
+	UseNPlusOne(n)
+	t.Cleanup(func() { UseNPlusOne(prev) })
+}
+
+func TestNPlusOne_WhenRepeatedByATerminal_ShouldBeDetected(t *testing.T) {
+	detections := make([]NPlusOneDetection, 0)
+	useNPlusOne(t, NPlusOne{Threshold: 3, OnDetect: func(d NPlusOneDetection) {
+		detections = append(detections, d)
+	}})
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	var buf bytes.Buffer
+	q := DB(tx).WithLogHandler(NewJSONHandler(&buf, LevelWarn))
+	assert.Nil(t, q.Create(&TestModel{Name: "one"}).Error())
+	assert.Empty(t, detections)
+
+	tms := []mdl.IModel{&TestModel{Name: "a"}, &TestModel{Name: "b"}, &TestModel{Name: "c"}}
+	assert.Nil(t, q.CreateMany(tms).Error())
+
+	if assert.Len(t, detections, 1) {
+		d := detections[0]
+		assert.Equal(t, "CreateMany", d.Op)
+		assert.Equal(t, "test_model", d.Table)
+		assert.Contains(t, d.Source, "nplusone_test.go")
+		assert.Contains(t, d.Shape, `INSERT INTO "test_model"`)
+		assert.Equal(t, 3, d.Count)
+		assert.Equal(t, 3, d.Statements)
+	}
+
+	lines := jsonLines(t, &buf)
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, "WARN", lines[0]["level"])
+		assert.Contains(t, lines[0]["msg"], "N+1 query: CreateMany on test_model")
+	}
+}
+
+func TestNPlusOne_OnWritesOutsideTransaction_ShouldBeDetected(t *testing.T) {
+	detections := make([]NPlusOneDetection, 0)
+	useNPlusOne(t, NPlusOne{Threshold: 3, OnDetect: func(d NPlusOneDetection) {
+		detections = append(detections, d)
+	}})
+
+	// Each is created by Gorm v1 in a transaction of its own
+	tms := []mdl.IModel{&TestModel{Name: "a"}, &TestModel{Name: "b"}, &TestModel{Name: "c"}}
+	if !assert.Nil(t, DB(db).CreateMany(tms).Error()) {
+		return
+	}
+	defer DB(db).DeleteMany(tms)
+
+	if assert.Len(t, detections, 1) {
+		assert.Equal(t, "CreateMany", detections[0].Op)
+		assert.Contains(t, detections[0].Shape, `INSERT INTO "test_model"`)
+		assert.Equal(t, 3, detections[0].Count)
+	}
+}
+
+func TestNPlusOne_WithoutThreshold_ShouldNotBeDetected(t *testing.T) {
+	useNPlusOne(t, NPlusOne{OnDetect: func(d NPlusOneDetection) {
+		t.Error("detected", d)
+	}})
+
+	tx := db.Begin()
+	defer tx.Rollback()
+	tms := []mdl.IModel{&TestModel{Name: "a"}, &TestModel{Name: "b"}, &TestModel{Name: "c"}}
+	assert.Nil(t, DB(tx).CreateMany(tms).Error())
+}
@@ -0,0 +1,232 @@
+package qry
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes (SQLSTATE) of constraint violations
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqCodeNotNullViolation    = "23502"
+	pqCodeForeignKeyViolation = "23503"
+	pqCodeUniqueViolation     = "23505"
+	pqCodeCheckViolation      = "23514"
+)
+
+// ConstraintViolation is what all constraint violation errors have
+// Fields and JSONKeys are the ones of the columns involved, if they can be found in the mdl.
+type ConstraintViolation struct {
+	Model      string // type name of the mdl, or table name if the mdl can't be found
+	Table      string
+	Constraint string
+	Columns    []string
+	Fields     []string
+	JSONKeys   []string
+
+	Err error // the original error from the database
+}
+
+func (e *ConstraintViolation) Unwrap() error {
+	return e.Err
+}
+
+// keys returns the JSONKeys, or the Columns if they can't be found in the mdl
+func (e *ConstraintViolation) keys() string {
+	if len(e.JSONKeys) > 0 {
+		return strings.Join(e.JSONKeys, ", ")
+	}
+	return strings.Join(e.Columns, ", ")
+}
+
+// UniqueViolationError is when the value(s) of the JSONKeys already exist
+type UniqueViolationError struct {
+	ConstraintViolation
+}
+
+func (e *UniqueViolationError) Error() string {
+	if keys := e.keys(); keys != "" {
+		return fmt.Sprintf("\"%s\" with the same %s already exists", e.Model, keys)
+	}
+	return fmt.Sprintf("\"%s\" violates unique constraint \"%s\"", e.Model, e.Constraint)
+}
+
+// ForeignKeyViolationError is when the record referenced does not exist, or when the record
+// deleted is still referenced
+type ForeignKeyViolationError struct {
+	ConstraintViolation
+}
+
+func (e *ForeignKeyViolationError) Error() string {
+	return fmt.Sprintf("\"%s\" violates foreign key constraint \"%s\"", e.Model, e.Constraint)
+}
+
+// NotNullViolationError is when the JSONKeys are null
+type NotNullViolationError struct {
+	ConstraintViolation
+}
+
+func (e *NotNullViolationError) Error() string {
+	return fmt.Sprintf("\"%s\" %s cannot be null", e.Model, e.keys())
+}
+
+// CheckViolationError is when a check constraint fails
+type CheckViolationError struct {
+	ConstraintViolation
+}
+
+func (e *CheckViolationError) Error() string {
+	return fmt.Sprintf("\"%s\" violates check constraint \"%s\"", e.Model, e.Constraint)
+}
+
+// Detail is something like: Key (name, age)=(Christy, 3) already exists.
+var pqDetailKeyRegexp = regexp.MustCompile(`^Key \((.+?)\)=`)
+
+// pgError is what is needed of an error from Postgres, which is *pq.Error of lib/pq, or
+// *pgconn.PgError of pgx (used by Gorm v2's Postgres driver)
+type pgError struct {
+	code       string
+	table      string
+	column     string
+	constraint string
+	detail     string
+}
+
+// sqlStateError is an error with SQLSTATE, such as *pgconn.PgError
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+// asPGError returns the pgError of err, if err is (or wraps) an error from Postgres
+func asPGError(err error) (pgError, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pgError{code: string(pqErr.Code), table: pqErr.Table, column: pqErr.Column,
+			constraint: pqErr.Constraint, detail: pqErr.Detail}, true
+	}
+
+	// Not to depend on pgx, the fields are read by the names *pgconn.PgError has
+	var stateErr sqlStateError
+	if !errors.As(err, &stateErr) {
+		return pgError{}, false
+	}
+	field := func(name string) string {
+		v := reflect.Indirect(reflect.ValueOf(stateErr))
+		if v.Kind() != reflect.Struct {
+			return ""
+		}
+		if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+			return f.String()
+		}
+		return ""
+	}
+	return pgError{code: stateErr.SQLState(), table: field("TableName"), column: field("ColumnName"),
+		constraint: field("ConstraintName"), detail: field("Detail")}, true
+}
+
+// translateDBError turns constraint violations from Postgres into the errors above, with the
+// columns mapped to the fields of modelObj (or of its nested mdl the violation is on)
+// Other errors are returned as they are.
+func translateDBError(modelObj mdl.IModel, err error) error {
+	if err == nil {
+		return nil
+	}
+	pgErr, ok := asPGError(err)
+	if !ok {
+		return err
+	}
+
+	var columns []string
+	switch pgErr.code {
+	case pqCodeUniqueViolation, pqCodeForeignKeyViolation:
+		if matches := pqDetailKeyRegexp.FindStringSubmatch(pgErr.detail); matches != nil {
+			for _, col := range strings.Split(matches[1], ",") {
+				columns = append(columns, strings.Trim(strings.TrimSpace(col), "\""))
+			}
+		}
+	case pqCodeNotNullViolation:
+		if pgErr.column != "" {
+			columns = []string{pgErr.column}
+		}
+	case pqCodeCheckViolation:
+	default:
+		return err
+	}
+
+	cv := ConstraintViolation{
+		Model:      pgErr.table,
+		Table:      pgErr.table,
+		Constraint: pgErr.constraint,
+		Columns:    columns,
+		Fields:     make([]string, 0, len(columns)),
+		JSONKeys:   make([]string, 0, len(columns)),
+		Err:        err,
+	}
+
+	if m := findModelByTableName(modelObj, pgErr.table, make(map[reflect.Type]bool)); m != nil {
+		cv.Model = mdl.GetModelTypeNameFromIModel(m)
+		for _, col := range columns {
+			field, err := mdl.ColumnToFieldName(m, col)
+			if err != nil {
+				continue // could be an expression in an index
+			}
+			cv.Fields = append(cv.Fields, field)
+			if jsonKey, err := mdl.FieldNameToJSONName(m, field); err == nil {
+				cv.JSONKeys = append(cv.JSONKeys, jsonKey)
+			}
+		}
+	}
+
+	switch pgErr.code {
+	case pqCodeUniqueViolation:
+		return &UniqueViolationError{cv}
+	case pqCodeForeignKeyViolation:
+		return &ForeignKeyViolationError{cv}
+	case pqCodeNotNullViolation:
+		return &NotNullViolationError{cv}
+	default: // pqCodeCheckViolation
+		return &CheckViolationError{cv}
+	}
+}
+
+// findModelByTableName finds modelObj or the mdl nested within whose table is tableName
+func findModelByTableName(modelObj mdl.IModel, tableName string, visited map[reflect.Type]bool) mdl.IModel {
+	if modelObj == nil {
+		return nil
+	}
+	if mdl.GetTableNameFromIModel(modelObj) == tableName {
+		return modelObj
+	}
+
+	typ := reflect.TypeOf(modelObj).Elem()
+	if visited[typ] {
+		return nil
+	}
+	visited[typ] = true
+
+	for i := 0; i < typ.NumField(); i++ {
+		fieldTyp := typ.Field(i).Type
+		for fieldTyp.Kind() == reflect.Ptr || fieldTyp.Kind() == reflect.Slice {
+			fieldTyp = fieldTyp.Elem()
+		}
+		if fieldTyp.Kind() != reflect.Struct || typ.Field(i).Anonymous {
+			continue
+		}
+
+		if m, ok := reflect.New(fieldTyp).Interface().(mdl.IModel); ok {
+			if found := findModelByTableName(m, tableName, visited); found != nil {
+				return found
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package qry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateDBError_UniqueViolation_ShouldMapColumnsToFieldsAndJSONKeys(t *testing.T) {
+	pqErr := &pq.Error{
+		Code:       "23505",
+		Table:      "test_model",
+		Constraint: "test_model_real_name_column_key",
+		Detail:     "Key (real_name_column)=(same) already exists.",
+	}
+
+	err := translateDBError(&TestModel{}, pqErr)
+
+	var uniqueErr *UniqueViolationError
+	if assert.True(t, errors.As(err, &uniqueErr)) {
+		assert.Equal(t, "TestModel", uniqueErr.Model)
+		assert.Equal(t, []string{"Name"}, uniqueErr.Fields)
+		assert.Equal(t, []string{"name"}, uniqueErr.JSONKeys)
+	}
+	assert.True(t, errors.Is(err, pqErr), "the original error should be wrapped")
+}
+
+func TestTranslateDBError_NestedModel_ShouldMapToNestedModel(t *testing.T) {
+	pqErr := &pq.Error{
+		Code:   "23502",
+		Table:  "dog",
+		Column: "test_model_id",
+	}
+
+	err := translateDBError(&TestModel{}, pqErr)
+
+	var notNullErr *NotNullViolationError
+	if assert.True(t, errors.As(err, &notNullErr)) {
+		assert.Equal(t, "Dog", notNullErr.Model)
+		assert.Equal(t, []string{"TestModelID"}, notNullErr.Fields)
+	}
+}
+
+func TestTranslateDBError_ForeignKeyAndCheckViolation_ShouldBeTyped(t *testing.T) {
+	err := translateDBError(&TestModel{}, &pq.Error{Code: "23503", Table: "dog",
+		Detail: "Key (test_model_id)=(1e98bfc3-2721-492a-bfd3-09f7dd3c1565) is not present in table \"test_model\"."})
+	var fkErr *ForeignKeyViolationError
+	if assert.True(t, errors.As(err, &fkErr)) {
+		assert.Equal(t, []string{"TestModelID"}, fkErr.Fields)
+	}
+
+	err = translateDBError(&TestModel{}, &pq.Error{Code: "23514", Table: "test_model", Constraint: "age_positive"})
+	var checkErr *CheckViolationError
+	if assert.True(t, errors.As(err, &checkErr)) {
+		assert.Equal(t, "age_positive", checkErr.Constraint)
+	}
+}
+
+func TestTranslateDBError_OtherErrors_ShouldBeLeftAsIs(t *testing.T) {
+	pqErr := &pq.Error{Code: "42601"} // syntax error
+	assert.Equal(t, error(pqErr), translateDBError(&TestModel{}, pqErr))
+	assert.Nil(t, translateDBError(&TestModel{}, nil))
+}
+
+// pgconnError is as *pgconn.PgError of pgx, with SQLState and the fields of the same names
+type pgconnError struct {
+	Code           string
+	Detail         string
+	TableName      string
+	ColumnName     string
+	ConstraintName string
+}
+
+func (e *pgconnError) Error() string {
+	return "ERROR: (SQLSTATE " + e.Code + ")"
+}
+
+func (e *pgconnError) SQLState() string {
+	return e.Code
+}
+
+func TestTranslateDBError_PgconnError_ShouldBeTranslated(t *testing.T) {
+	pgErr := &pgconnError{
+		Code:           "23505",
+		TableName:      "test_model",
+		ConstraintName: "test_model_real_name_column_key",
+		Detail:         "Key (real_name_column)=(same) already exists.",
+	}
+
+	err := translateDBError(&TestModel{}, fmt.Errorf("wrapped: %w", pgErr))
+
+	var uniqueErr *UniqueViolationError
+	if assert.True(t, errors.As(err, &uniqueErr)) {
+		assert.Equal(t, "TestModel", uniqueErr.Model)
+		assert.Equal(t, "test_model_real_name_column_key", uniqueErr.Constraint)
+		assert.Equal(t, []string{"name"}, uniqueErr.JSONKeys)
+	}
+	assert.True(t, errors.Is(err, pgErr), "the original error should be wrapped")
+
+	err = translateDBError(&TestModel{}, &pgconnError{Code: "23502", TableName: "dog", ColumnName: "test_model_id"})
+	var notNullErr *NotNullViolationError
+	if assert.True(t, errors.As(err, &notNullErr)) {
+		assert.Equal(t, []string{"TestModelID"}, notNullErr.Fields)
+	}
+}
+
+func TestUniqueViolationError_WithoutJSONKeys_ShouldGiveColumnsOrConstraint(t *testing.T) {
+	err := translateDBError(&TestModel{}, &pq.Error{Code: "23505", Table: "test_model",
+		Constraint: "name_lower_idx", Detail: "Key (lower(real_name_column::text))=(same) already exists."})
+	assert.Equal(t, `"TestModel" with the same lower(real_name_column::text) already exists`, err.Error())
+
+	err = translateDBError(&TestModel{}, &pq.Error{Code: "23505", Table: "test_model", Constraint: "name_lower_idx"})
+	assert.Equal(t, `"TestModel" violates unique constraint "name_lower_idx"`, err.Error())
+}
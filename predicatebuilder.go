@@ -1,10 +1,5 @@
 package qry
 
-import (
-	"errors"
-	"fmt"
-)
-
 // args is either two arguments: "Name =" "Christy", or another predicate builder C()
 func C(args ...interface{}) *PredicateRelationBuilder {
 	builder := NewPredicateRelationBuilder()
@@ -37,7 +32,7 @@ func (p *PredicateRelationBuilder) C(args ...interface{}) *PredicateRelationBuil
 	}
 
 	if len(p.Rel.PredOrRels) != 0 || len(p.Rel.Logics) != 0 {
-		p.Error = fmt.Errorf("calling C() when predicate or relation not empty")
+		p.Error = newBuilderError("calling C() when predicate or relation not empty")
 		return p
 	}
 
@@ -69,7 +64,7 @@ func (p *PredicateRelationBuilder) addRelation(args ...interface{}) *PredicateRe
 	if len(args) >= 2 {
 		p.addPredicateOrBuilder(args[:len(args)-1]...)
 	} else {
-		p.Error = errors.New("And or Or should have at least two arguments")
+		p.Error = newBuilderError("And or Or should have at least two arguments")
 	}
 
 	logic := args[len(args)-1].(PredicateLogic)
@@ -101,6 +96,6 @@ func (p *PredicateRelationBuilder) addPredicateOrBuilder(args ...interface{}) {
 			p.Rel.PredOrRels = append(p.Rel.PredOrRels, rel)
 		}
 	} else {
-		p.Error = fmt.Errorf("argument to file-level C function incorrect type")
+		p.Error = newBuilderError("argument to file-level C function incorrect type")
 	}
 }
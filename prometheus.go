@@ -0,0 +1,165 @@
+package qry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of the durations of statements,
+// the same as those of the Prometheus client
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusCollector is a hook which collects the metrics of statements by terminal and table,
+// written in the Prometheus text format by WriteTo, or served as the handler of /metrics:
+//
+//	qry_statement_duration_seconds, a histogram of their durations
+//	qry_statement_errors_total, the number of which fail
+//	qry_statement_rows_total, the rows affected
+type PrometheusCollector struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[prometheusLabels]*prometheusSeries
+}
+
+type prometheusLabels struct {
+	op    string
+	table string
+}
+
+type prometheusSeries struct {
+	buckets []uint64 // the count of each bucket, not including those before it
+	count   uint64
+	sum     float64
+	errors  uint64
+	rows    int64
+}
+
+// NewPrometheusCollector returns a collector with the upper bounds of buckets in seconds,
+// DefaultBuckets if none
+func NewPrometheusCollector(buckets ...float64) *PrometheusCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusCollector{buckets: buckets, series: make(map[prometheusLabels]*prometheusSeries)}
+}
+
+func (c *PrometheusCollector) BeforeStatement(s *StatementInfo) {
+}
+
+func (c *PrometheusCollector) AfterStatement(s *StatementInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	labels := prometheusLabels{op: s.Op, table: s.Table}
+	series, ok := c.series[labels]
+	if !ok {
+		series = &prometheusSeries{buckets: make([]uint64, len(c.buckets))}
+		c.series[labels] = series
+	}
+
+	seconds := s.Duration.Seconds()
+	if i := sort.SearchFloat64s(c.buckets, seconds); i < len(c.buckets) {
+		series.buckets[i]++
+	}
+	series.count++
+	series.sum += seconds
+	if s.Err != nil {
+		series.errors++
+	}
+	if s.Rows > 0 {
+		series.rows += s.Rows
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+func (c *PrometheusCollector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	labelsList := make([]prometheusLabels, 0, len(c.series))
+	series := make(map[prometheusLabels]prometheusSeries, len(c.series))
+	for labels, s := range c.series {
+		labelsList = append(labelsList, labels)
+		s := *s
+		s.buckets = append([]uint64{}, s.buckets...)
+		series[labels] = s
+	}
+	c.mu.Unlock()
+
+	sort.Slice(labelsList, func(i, j int) bool {
+		if labelsList[i].op != labelsList[j].op {
+			return labelsList[i].op < labelsList[j].op
+		}
+		return labelsList[i].table < labelsList[j].table
+	})
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	fmt.Fprintln(bw, "# HELP qry_statement_duration_seconds Duration of the statements run by qry.")
+	fmt.Fprintln(bw, "# TYPE qry_statement_duration_seconds histogram")
+	for _, labels := range labelsList {
+		s := series[labels]
+		cumulative := uint64(0)
+		for i, le := range c.buckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(bw, "qry_statement_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(bw, "qry_statement_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.count)
+		fmt.Fprintf(bw, "qry_statement_duration_seconds_sum{%s} %s\n", labels, formatFloat(s.sum))
+		fmt.Fprintf(bw, "qry_statement_duration_seconds_count{%s} %d\n", labels, s.count)
+	}
+
+	fmt.Fprintln(bw, "# HELP qry_statement_errors_total Number of the statements run by qry which failed.")
+	fmt.Fprintln(bw, "# TYPE qry_statement_errors_total counter")
+	for _, labels := range labelsList {
+		fmt.Fprintf(bw, "qry_statement_errors_total{%s} %d\n", labels, series[labels].errors)
+	}
+
+	fmt.Fprintln(bw, "# HELP qry_statement_rows_total Number of the rows affected by the statements run by qry.")
+	fmt.Fprintln(bw, "# TYPE qry_statement_rows_total counter")
+	for _, labels := range labelsList {
+		fmt.Fprintf(bw, "qry_statement_rows_total{%s} %d\n", labels, series[labels].rows)
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP serves the metrics, so c can be the handler of /metrics
+func (c *PrometheusCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+func (l prometheusLabels) String() string {
+	return fmt.Sprintf(`op="%s",table="%s"`, escapeLabelValue(l.op), escapeLabelValue(l.table))
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
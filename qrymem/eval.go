@@ -0,0 +1,371 @@
+package qrymem
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/mdl"
+)
+
+// Evaluation of PredicateRelation on mdls, the way the database would
+// Values are compared as what they'd be in the database (driver.Valuer), so for example
+// a *datatype.UUID is compared with a string.
+
+// matchRelation evaluates rel on m, where every field of rel is on m (the last part of
+// the dot notation). AND goes before OR as in SQL.
+func matchRelation(rel *qry.PredicateRelation, m mdl.IModel) (bool, error) {
+	matched := false
+	curr := true // of the current AND group
+	for i, criteria := range rel.PredOrRels {
+		if i > 0 && rel.Logics[i-1] == qry.PredicateLogicOR {
+			matched = matched || curr
+			curr = true
+		}
+
+		var ok bool
+		var err error
+		switch c := criteria.(type) {
+		case *qry.Predicate:
+			ok, err = matchPredicate(c, m)
+		case *qry.PredicateRelation:
+			ok, err = matchRelation(c, m)
+		default:
+			err = newBuilderError("unknown criteria %T", criteria)
+		}
+		if err != nil {
+			return false, err
+		}
+		curr = curr && ok
+	}
+	return matched || curr, nil
+}
+
+func matchPredicate(p *qry.Predicate, m mdl.IModel) (bool, error) {
+	toks := strings.Split(p.Field, ".")
+	field := toks[len(toks)-1]
+	fieldVal := reflect.Indirect(reflect.ValueOf(m)).FieldByName(field)
+	if !fieldVal.IsValid() {
+		return false, &qry.InvalidFieldError{Model: mdl.GetModelTypeNameFromIModel(m), Field: field}
+	}
+	if _, ok := p.Value.(*qry.Escape); ok {
+		return false, newBuilderError("qrymem does not support escaped values")
+	}
+
+	a := normalize(fieldVal.Interface())
+	switch p.Cond {
+	case qry.PredicateCondIN:
+		vals, ok := sliceValues(p.Value)
+		if !ok {
+			return false, newBuilderError("value of \"%s IN\" is not a slice", p.Field)
+		}
+		for _, val := range vals {
+			if c, ok, err := compareField(p, a, val); err != nil {
+				return false, err
+			} else if ok && c == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case qry.PredicateCondBETWEEN:
+		vals, ok := sliceValues(p.Value)
+		if !ok || len(vals) != 2 {
+			return false, newBuilderError("value of \"%s BETWEEN\" is not a slice of two", p.Field)
+		}
+		lo, ok1, err := compareField(p, a, vals[0])
+		if err != nil {
+			return false, err
+		}
+		hi, ok2, err := compareField(p, a, vals[1])
+		if err != nil {
+			return false, err
+		}
+		return ok1 && ok2 && lo >= 0 && hi <= 0, nil
+	case qry.PredicateCondISNULL:
+		return a == nil, nil
+	case qry.PredicateCondISNOTNULL:
+		return a != nil, nil
+	}
+
+	c, ok, err := compareField(p, a, p.Value)
+	if err != nil || !ok {
+		return false, err
+	}
+	switch p.Cond {
+	case qry.PredicateCondEQ:
+		return c == 0, nil
+	case qry.PredicateCondLT:
+		return c < 0, nil
+	case qry.PredicateCondLTEQ:
+		return c <= 0, nil
+	case qry.PredicateCondGT:
+		return c > 0, nil
+	case qry.PredicateCondGTEQ:
+		return c >= 0, nil
+	}
+	return false, newBuilderError("unknown condition \"%s\"", p.Cond)
+}
+
+// compareField compares the (normalized) field value a with val
+// ok is false when either is NULL, which never matches
+func compareField(p *qry.Predicate, a interface{}, val interface{}) (int, bool, error) {
+	b := normalize(val)
+	if a == nil || b == nil {
+		return 0, false, nil
+	}
+	c, ok := compare(a, b)
+	if !ok {
+		return 0, false, newBuilderError("cannot compare field \"%s\" with %v", p.Field, val)
+	}
+	return c, true, nil
+}
+
+// sliceValues returns the elements of v if it is a slice or array (but not []byte)
+func sliceValues(v interface{}) ([]interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) ||
+		rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	vals := make([]interface{}, rv.Len())
+	for i := range vals {
+		vals[i] = rv.Index(i).Interface()
+	}
+	return vals, true
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// normalize turns v into what it is as a database value: nil, int64, uint64, float64, string,
+// bool or time.Time (or v itself if it is none of those)
+func normalize(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+
+	// A struct whose pointer is a Valuer, such as a datatype which is not a pointer field
+	if rv.Kind() == reflect.Struct && !rv.Type().Implements(valuerType) && reflect.PtrTo(rv.Type()).Implements(valuerType) {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		v, rv = ptr.Interface(), ptr
+	}
+
+	if valuer, ok := v.(driver.Valuer); ok {
+		val, err := valuer.Value()
+		if err != nil || val == nil {
+			return nil
+		}
+		if _, ok := val.(driver.Valuer); ok { // shouldn't happen, but don't loop forever
+			return val
+		}
+		return normalize(val)
+	}
+
+	if t, ok := v.(time.Time); ok {
+		return t
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		return normalize(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes())
+		}
+	}
+	return v
+}
+
+// compare compares two normalized values, ok is false if they can't be compared
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return compareOrdered(x, y), true
+		case uint64:
+			if x < 0 {
+				return -1, true
+			}
+			return compareOrdered(uint64(x), y), true
+		case float64:
+			return compareOrdered(float64(x), y), true
+		}
+	case uint64:
+		switch y := b.(type) {
+		case int64:
+			if y < 0 {
+				return 1, true
+			}
+			return compareOrdered(x, uint64(y)), true
+		case uint64:
+			return compareOrdered(x, y), true
+		case float64:
+			return compareOrdered(float64(x), y), true
+		}
+	case float64:
+		switch y := b.(type) {
+		case int64:
+			return compareOrdered(x, float64(y)), true
+		case uint64:
+			return compareOrdered(x, float64(y)), true
+		case float64:
+			return compareOrdered(x, y), true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case !x:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered[T int64 | uint64 | float64](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareForOrder compares field values for Order(), NULL is the largest as in Postgres
+func compareForOrder(a, b interface{}) int {
+	a, b = normalize(a), normalize(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	c, _ := compare(a, b)
+	return c
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// setField sets field of m to value, converting it as the database would
+func setField(m mdl.IModel, field string, value interface{}) error {
+	fieldVal := reflect.Indirect(reflect.ValueOf(m)).FieldByName(field)
+	if !fieldVal.IsValid() || !fieldVal.CanSet() {
+		return &qry.InvalidFieldError{Model: mdl.GetModelTypeNameFromIModel(m), Field: field}
+	}
+
+	if value == nil {
+		fieldVal.Set(reflect.Zero(fieldVal.Type()))
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	typ := fieldVal.Type()
+	switch {
+	case rv.Type().AssignableTo(typ):
+		fieldVal.Set(rv)
+		return nil
+	case typ.Kind() == reflect.Ptr && rv.Type().AssignableTo(typ.Elem()):
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(rv)
+		fieldVal.Set(ptr)
+		return nil
+	case sameKindClass(rv.Kind(), typ.Kind()) && rv.Type().ConvertibleTo(typ):
+		fieldVal.Set(rv.Convert(typ))
+		return nil
+	}
+
+	// Such as a string into *datatype.UUID
+	if typ.Kind() == reflect.Ptr && typ.Implements(scannerType) {
+		ptr := reflect.New(typ.Elem())
+		if err := ptr.Interface().(sql.Scanner).Scan(driverValue(value)); err != nil {
+			return err
+		}
+		fieldVal.Set(ptr)
+		return nil
+	}
+	if reflect.PtrTo(typ).Implements(scannerType) {
+		ptr := reflect.New(typ)
+		if err := ptr.Interface().(sql.Scanner).Scan(driverValue(value)); err != nil {
+			return err
+		}
+		fieldVal.Set(ptr.Elem())
+		return nil
+	}
+
+	return newBuilderError("cannot set field \"%s\" to %v", field, value)
+}
+
+// driverValue is value as it would be given to a Scanner by the database
+func driverValue(value interface{}) interface{} {
+	switch v := normalize(value).(type) {
+	case string:
+		return []byte(v)
+	default:
+		return v
+	}
+}
+
+func sameKindClass(a, b reflect.Kind) bool {
+	class := func(k reflect.Kind) int {
+		switch k {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return 1
+		case reflect.String:
+			return 2
+		case reflect.Bool:
+			return 3
+		}
+		return 0
+	}
+	return class(a) != 0 && class(a) == class(b)
+}
+
+func newBuilderError(format string, a ...interface{}) *qry.BuilderError {
+	return &qry.BuilderError{Reason: fmt.Sprintf(format, a...)}
+}
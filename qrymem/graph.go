@@ -0,0 +1,471 @@
+package qrymem
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Graph strategy, the same as qry on the database
+// Create: pegged and other nested mdls are created (pegged ones cannot exist already), and
+// pegassoc ones which exist are pointed to the mdl (the rest created).
+// Save: the mdl and its pegged and other nested mdls are saved, pegassoc ones are left alone
+// unless new.
+// SaveGraph: see qry.SaveGraph.
+// Delete: pegged mdls are deleted (and traversed into), pegassoc ones dissociated.
+
+type relation int
+
+const (
+	relationOther relation = iota // nested mdl without betterrest tag
+	relationPeg
+	relationPegAssoc
+)
+
+// nestedField is a field of a mdl which holds nested mdl(s)
+type nestedField struct {
+	index     int
+	name      string
+	typ       reflect.Type // struct type of the nested mdl
+	kind      reflect.Kind // reflect.Slice, reflect.Struct or reflect.Ptr
+	elemIsPtr bool         // slice of pointers
+	rel       relation
+}
+
+var modelType = reflect.TypeOf((*mdl.IModel)(nil)).Elem()
+
+// nestedFieldsOf returns the fields of typ (a struct type) which hold nested mdls
+func nestedFieldsOf(typ reflect.Type) []nestedField {
+	fields := make([]nestedField, 0)
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+		if structField.Anonymous || !structField.IsExported() {
+			continue
+		}
+
+		f := nestedField{index: i, name: structField.Name, kind: structField.Type.Kind(), typ: structField.Type}
+		switch f.kind {
+		case reflect.Slice:
+			f.typ = structField.Type.Elem()
+			if f.typ.Kind() == reflect.Ptr {
+				f.elemIsPtr = true
+				f.typ = f.typ.Elem()
+			}
+		case reflect.Ptr:
+			f.typ = structField.Type.Elem()
+		}
+		if f.typ.Kind() != reflect.Struct || !reflect.PtrTo(f.typ).Implements(modelType) {
+			continue
+		}
+
+		tag := structField.Tag.Get("betterrest")
+		switch {
+		case strings.HasPrefix(tag, "peg-ignore") || strings.HasPrefix(tag, "pegassoc-manytomany"):
+			continue
+		case strings.HasPrefix(tag, "pegassoc"):
+			f.rel = relationPegAssoc
+		case strings.HasPrefix(tag, "peg"):
+			f.rel = relationPeg
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// nestedModels returns the mdls within field f of v
+// An embedded struct which is never initialized is not considered there
+func nestedModels(v reflect.Value, f nestedField) []mdl.IModel {
+	fieldVal := v.Field(f.index)
+	ms := make([]mdl.IModel, 0)
+	switch f.kind {
+	case reflect.Slice:
+		for j := 0; j < fieldVal.Len(); j++ {
+			elem := fieldVal.Index(j)
+			if f.elemIsPtr {
+				if !elem.IsNil() {
+					ms = append(ms, elem.Interface().(mdl.IModel))
+				}
+			} else {
+				ms = append(ms, elem.Addr().Interface().(mdl.IModel))
+			}
+		}
+	case reflect.Ptr:
+		if !fieldVal.IsNil() {
+			ms = append(ms, fieldVal.Interface().(mdl.IModel))
+		}
+	case reflect.Struct:
+		if !fieldVal.IsZero() {
+			ms = append(ms, fieldVal.Addr().Interface().(mdl.IModel))
+		}
+	}
+	return ms
+}
+
+// flatCopy copies modelObj without its nested mdls
+func flatCopy(modelObj mdl.IModel) mdl.IModel {
+	v := reflect.New(reflect.TypeOf(modelObj).Elem())
+	v.Elem().Set(reflect.ValueOf(modelObj).Elem())
+	for _, f := range nestedFieldsOf(v.Elem().Type()) {
+		fieldVal := v.Elem().Field(f.index)
+		fieldVal.Set(reflect.Zero(fieldVal.Type()))
+	}
+	return v.Interface().(mdl.IModel)
+}
+
+// load copies the record with its nested mdls, as preloaded from the database
+func (s *Store) load(rec mdl.IModel) mdl.IModel {
+	modelObj := flatCopy(rec)
+	v := reflect.ValueOf(modelObj).Elem()
+	for _, f := range nestedFieldsOf(v.Type()) {
+		children := make([]mdl.IModel, 0)
+		for _, child := range s.children(modelObj, f.typ) {
+			if !isDeleted(child) {
+				children = append(children, s.load(child))
+			}
+		}
+
+		fieldVal := v.Field(f.index)
+		switch f.kind {
+		case reflect.Slice:
+			sl := reflect.MakeSlice(fieldVal.Type(), 0, len(children))
+			for _, child := range children {
+				if f.elemIsPtr {
+					sl = reflect.Append(sl, reflect.ValueOf(child))
+				} else {
+					sl = reflect.Append(sl, reflect.ValueOf(child).Elem())
+				}
+			}
+			fieldVal.Set(sl)
+		case reflect.Ptr:
+			if len(children) > 0 { // the first one if there are more than one (it's ambiguous in the database)
+				fieldVal.Set(reflect.ValueOf(children[0]))
+			}
+		case reflect.Struct:
+			if len(children) > 0 {
+				fieldVal.Set(reflect.ValueOf(children[0]).Elem())
+			}
+		}
+	}
+	return modelObj
+}
+
+// isDeleted is whether the record is soft-deleted (Gorm leaves them out unless unscoped)
+func isDeleted(modelObj mdl.IModel) bool {
+	if m, ok := modelObj.(interface{ GetDeletedAt() *time.Time }); ok {
+		return m.GetDeletedAt() != nil
+	}
+	return false
+}
+
+// getParentID gets the foreign key (for example TestModelID) within m, if there is such field
+// and it's set
+func getParentID(m mdl.IModel, parentTypeName string) (string, bool) {
+	fieldVal := reflect.Indirect(reflect.ValueOf(m)).FieldByName(parentTypeName + "ID")
+	if !fieldVal.IsValid() || fieldVal.Type() != reflect.TypeOf(&datatype.UUID{}) || fieldVal.IsNil() {
+		return "", false
+	}
+	return fieldVal.Interface().(*datatype.UUID).String(), true
+}
+
+// setParentID sets the foreign key (for example TestModelID) within the nested mdl m
+// to point to parent (or nil if parent is nil), if there is such field
+func setParentID(m mdl.IModel, parentTypeName string, parentID *datatype.UUID) {
+	fieldVal := reflect.Indirect(reflect.ValueOf(m)).FieldByName(parentTypeName + "ID")
+	if fieldVal.IsValid() && fieldVal.CanSet() && fieldVal.Type() == reflect.TypeOf(&datatype.UUID{}) {
+		fieldVal.Set(reflect.ValueOf(parentID))
+	}
+}
+
+// setStoredParentID points the stored record of m to parent, if it exists
+func (s *Store) setStoredParentID(m mdl.IModel, parentTypeName string, parentID *datatype.UUID) bool {
+	rec := s.get(mdl.GetTableNameFromIModel(m), m.GetID().String())
+	if rec == nil {
+		return false
+	}
+
+	rec = flatCopy(rec)
+	setParentID(rec, parentTypeName, parentID)
+	s.put(rec)
+	return true
+}
+
+// checkCreate checks that modelObj (whose ID is given) and its pegged mdls do not exist yet
+func (s *Store) checkCreate(modelObj mdl.IModel) error {
+	if modelObj.GetID() != nil && s.get(mdl.GetTableNameFromIModel(modelObj), modelObj.GetID().String()) != nil {
+		return newPrimaryKeyViolationError(modelObj)
+	}
+	return s.checkPeggedIDsNotFound(modelObj)
+}
+
+func (s *Store) checkPeggedIDsNotFound(modelObj mdl.IModel) error {
+	v := reflect.Indirect(reflect.ValueOf(modelObj))
+	for _, f := range nestedFieldsOf(v.Type()) {
+		if f.rel != relationPeg {
+			continue
+		}
+
+		ms := nestedModels(v, f)
+		existingIDs := make([]*datatype.UUID, 0)
+		for _, m := range ms {
+			if m.GetID() != nil && s.get(mdl.GetTableNameFromType(f.typ), m.GetID().String()) != nil {
+				existingIDs = append(existingIDs, m.GetID())
+			}
+		}
+		if len(existingIDs) != 0 {
+			return &qry.DuplicatePeggedIDError{Table: mdl.GetTableNameFromType(f.typ), IDs: existingIDs}
+		}
+
+		for _, m := range ms {
+			if err := s.checkPeggedIDsNotFound(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// create stores modelObj and its nested mdls, setting the IDs, timestamps and foreign keys
+// on them as Gorm does
+func (s *Store) create(modelObj mdl.IModel) {
+	s.put(withCreatedTimestamps(modelObj))
+	s.saveNested(modelObj, true)
+}
+
+// save stores modelObj, creating it if it doesn't exist, and saves its nested mdls as Gorm does
+func (s *Store) save(modelObj mdl.IModel, withNested bool) {
+	old := (mdl.IModel)(nil)
+	if modelObj.GetID() != nil {
+		old = s.get(mdl.GetTableNameFromIModel(modelObj), modelObj.GetID().String())
+	}
+	if old == nil {
+		if withNested {
+			s.create(modelObj)
+		} else {
+			s.put(withCreatedTimestamps(modelObj))
+		}
+		return
+	}
+
+	*modelObj.GetUpdatedAt() = gorm.NowFunc()
+	rec := flatCopy(modelObj)
+	if rec.GetCreatedAt().IsZero() { // Gorm doesn't update it if it's blank
+		*rec.GetCreatedAt() = *old.GetCreatedAt()
+	}
+	s.put(rec)
+
+	if withNested {
+		s.saveNested(modelObj, false)
+	}
+}
+
+// withCreatedTimestamps sets the ID and timestamps of modelObj which is about to be created,
+// if not yet set
+func withCreatedTimestamps(modelObj mdl.IModel) mdl.IModel {
+	if modelObj.GetID() == nil {
+		modelObj.SetID(datatype.NewUUID())
+	}
+	now := gorm.NowFunc()
+	if modelObj.GetCreatedAt().IsZero() {
+		*modelObj.GetCreatedAt() = now
+	}
+	if modelObj.GetUpdatedAt().IsZero() {
+		*modelObj.GetUpdatedAt() = now
+	}
+	return modelObj
+}
+
+// saveNested saves the nested mdls of modelObj (which is already stored)
+// When creating, existing pegassoc mdls are pointed to modelObj, otherwise they are left alone.
+func (s *Store) saveNested(modelObj mdl.IModel, creating bool) {
+	typeName := mdl.GetModelTypeNameFromIModel(modelObj)
+	v := reflect.Indirect(reflect.ValueOf(modelObj))
+	for _, f := range nestedFieldsOf(v.Type()) {
+		for _, m := range nestedModels(v, f) {
+			if f.rel == relationPegAssoc && m.GetID() != nil {
+				if creating {
+					s.setStoredParentID(m, typeName, modelObj.GetID())
+				}
+				continue
+			}
+
+			setParentID(m, typeName, modelObj.GetID())
+			s.save(m, true)
+		}
+	}
+}
+
+// deleteCascade deletes the record m, its pegged mdls, and dissociates its pegassoc mdls
+func (s *Store) deleteCascade(m mdl.IModel) bool {
+	if !s.remove(m) {
+		return false
+	}
+
+	typeName := mdl.GetModelTypeNameFromIModel(m)
+	for _, f := range nestedFieldsOf(reflect.TypeOf(m).Elem()) {
+		switch f.rel {
+		case relationPeg:
+			for _, child := range s.children(m, f.typ) {
+				s.deleteCascade(child)
+			}
+		case relationPegAssoc:
+			for _, child := range s.children(m, f.typ) {
+				s.setStoredParentID(child, typeName, nil)
+			}
+		}
+	}
+	return true
+}
+
+// saveGraph saves modelObj and syncs its pegged and pegassoc fields with old (loaded from the
+// store), as qry.SaveGraph does
+func (s *Store) saveGraph(modelObj mdl.IModel, old mdl.IModel) error {
+	if err := s.checkVersion(modelObj); err != nil {
+		return err
+	}
+	s.save(modelObj, false)
+
+	typeName := mdl.GetModelTypeNameFromIModel(modelObj)
+	v := reflect.Indirect(reflect.ValueOf(modelObj))
+	oldV := reflect.Indirect(reflect.ValueOf(old))
+	pegged, oldPegged := make([]mdl.IModel, 0), make([]mdl.IModel, 0)
+	peggedAssoc, oldPeggedAssoc := make([]mdl.IModel, 0), make([]mdl.IModel, 0)
+	for _, f := range nestedFieldsOf(v.Type()) {
+		switch f.rel {
+		case relationPeg:
+			pegged = append(pegged, nestedModels(v, f)...)
+			oldPegged = append(oldPegged, nestedModels(oldV, f)...)
+		case relationPegAssoc:
+			peggedAssoc = append(peggedAssoc, nestedModels(v, f)...)
+			oldPeggedAssoc = append(oldPeggedAssoc, nestedModels(oldV, f)...)
+		}
+	}
+
+	// Pegged
+	oldByKey := make(map[string]mdl.IModel)
+	for _, oldModel := range oldPegged {
+		oldByKey[tableAndIDKey(oldModel)] = oldModel
+	}
+	toUpdate := make(map[string]mdl.IModel)
+	toCreate := make([]mdl.IModel, 0)
+	for _, m := range pegged {
+		if m.GetID() != nil {
+			key := tableAndIDKey(m)
+			if _, ok := toUpdate[key]; ok {
+				continue // the same one in another field
+			}
+			if _, ok := oldByKey[key]; ok {
+				toUpdate[key] = m
+				continue
+			}
+		}
+		toCreate = append(toCreate, m)
+	}
+
+	for key, oldModel := range oldByKey {
+		if _, ok := toUpdate[key]; !ok {
+			s.deleteCascade(oldModel)
+		}
+	}
+	for key, m := range toUpdate {
+		if err := s.saveGraph(m, oldByKey[key]); err != nil {
+			return err
+		}
+	}
+	for _, m := range toCreate {
+		setParentID(m, typeName, modelObj.GetID())
+		if err := s.checkCreate(m); err != nil {
+			return err
+		}
+		s.create(m)
+	}
+
+	// Pegassoc
+	keys := make(map[string]bool)
+	for _, m := range peggedAssoc {
+		if m.GetID() != nil {
+			keys[tableAndIDKey(m)] = true
+		}
+	}
+	oldKeys := make(map[string]bool)
+	for _, oldModel := range oldPeggedAssoc {
+		key := tableAndIDKey(oldModel)
+		oldKeys[key] = true
+		if !keys[key] {
+			s.setStoredParentID(oldModel, typeName, nil)
+		}
+	}
+	for _, m := range peggedAssoc {
+		if m.GetID() != nil && !oldKeys[tableAndIDKey(m)] {
+			s.setStoredParentID(m, typeName, modelObj.GetID())
+		}
+	}
+
+	return nil
+}
+
+// checkVersion checks that the record of modelObj is at the version modelObj has, if versioned,
+// and increments the version of modelObj
+func (s *Store) checkVersion(modelObj mdl.IModel) error {
+	version, versioned := mdl.GetVersion(modelObj)
+	if !versioned || modelObj.GetID() == nil {
+		return nil
+	}
+
+	tblName := mdl.GetTableNameFromIModel(modelObj)
+	rec := s.get(tblName, modelObj.GetID().String())
+	if rec == nil {
+		return &qry.StaleObjectError{Table: tblName, ID: modelObj.GetID(), Version: version}
+	}
+	if recVersion, _ := mdl.GetVersion(rec); recVersion != version {
+		return &qry.StaleObjectError{Table: tblName, ID: modelObj.GetID(), Version: version}
+	}
+
+	mdl.SetVersion(modelObj, version+1)
+	return nil
+}
+
+// checkVersionsSaved checks and increments the versions of modelObj and of the nested mdls saved
+// along with it, as qry.Save does. If any of them is stale, none is incremented.
+func (s *Store) checkVersionsSaved(modelObj mdl.IModel) error {
+	checked := make([]mdl.IModel, 0)
+	if err := s.checkVersionsSavedOf(modelObj, &checked); err != nil {
+		for _, m := range checked {
+			version, _ := mdl.GetVersion(m)
+			mdl.SetVersion(m, version-1)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Store) checkVersionsSavedOf(modelObj mdl.IModel, checked *[]mdl.IModel) error {
+	if err := s.checkVersion(modelObj); err != nil {
+		return err
+	}
+	if _, versioned := mdl.GetVersion(modelObj); versioned && modelObj.GetID() != nil {
+		*checked = append(*checked, modelObj)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(modelObj))
+	for _, f := range nestedFieldsOf(v.Type()) {
+		for _, m := range nestedModels(v, f) {
+			if f.rel == relationPegAssoc && m.GetID() != nil { // left alone by saveNested
+				continue
+			}
+			if err := s.checkVersionsSavedOf(m, checked); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tableAndIDKey(m mdl.IModel) string {
+	return mdl.GetTableNameFromIModel(m) + ":" + m.GetID().String()
+}
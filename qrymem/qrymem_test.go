@@ -0,0 +1,481 @@
+package qrymem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Person struct {
+	mdl.BaseModel
+
+	Name string `gorm:"column:real_name_column" json:"name"`
+	Age  int    `json:"age"`
+
+	Pets    []Pet    `betterrest:"peg" json:"pets"`
+	Friends []Friend `gorm:"association_autoupdate:false;" betterrest:"pegassoc" json:"friends"`
+}
+
+type Pet struct {
+	mdl.BaseModel
+
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Toys  []Toy  `betterrest:"peg" json:"toys"`
+
+	PersonID *datatype.UUID `gorm:"type:uuid;index;not null;" json:"-"`
+}
+
+type Toy struct {
+	mdl.BaseModel
+
+	Name string `json:"name"`
+
+	PetID *datatype.UUID `gorm:"type:uuid;index;not null;" json:"-"`
+}
+
+type Friend struct {
+	mdl.BaseModel
+
+	Name string `json:"name"`
+
+	PersonID *datatype.UUID `gorm:"type:uuid;index;" json:"-"`
+}
+
+// Address is not nested in Person, so it's joined
+type Address struct {
+	mdl.BaseModel
+
+	City string `json:"city"`
+
+	PersonID *datatype.UUID `gorm:"type:uuid;index;" json:"-"`
+}
+
+type Account struct {
+	mdl.BaseModel
+
+	Name    string `json:"name"`
+	Version int    `json:"version" qry:"version"`
+
+	Ledgers []Ledger `betterrest:"peg" json:"ledgers"`
+}
+
+type Ledger struct {
+	mdl.BaseModel
+
+	Name    string `json:"name"`
+	Version int    `json:"version" qry:"version"`
+
+	AccountID *datatype.UUID `gorm:"type:uuid;index;" json:"-"`
+}
+
+// setup creates persons "a" (3), "b" (4) and "c" (4), each with a pet and a toy
+func setup(t *testing.T) (*Store, []*Person) {
+	store := NewStore()
+	persons := []*Person{
+		{Name: "a", Age: 3, Pets: []Pet{{Name: "a-pet", Color: "red", Toys: []Toy{{Name: "ball"}}}}},
+		{Name: "b", Age: 4, Pets: []Pet{{Name: "b-pet", Color: "green", Toys: []Toy{{Name: "bone"}}}}},
+		{Name: "c", Age: 4, Pets: []Pet{{Name: "c-pet", Color: "green", Toys: []Toy{{Name: "ball"}}}}},
+	}
+	for _, p := range persons {
+		if !assert.Nil(t, DB(store).Create(p).Error()) {
+			t.FailNow()
+		}
+	}
+	return store, persons
+}
+
+func names(persons []Person) []string {
+	ns := make([]string, len(persons))
+	for i, p := range persons {
+		ns[i] = p.Name
+	}
+	return ns
+}
+
+func TestCreate_ShouldBeReadBackWithNested(t *testing.T) {
+	store, persons := setup(t)
+
+	p := Person{}
+	err := Q(store, qry.C("Name =", "a")).First(&p).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, persons[0].ID.String(), p.ID.String())
+		assert.False(t, p.CreatedAt.IsZero())
+		if assert.Len(t, p.Pets, 1) && assert.Len(t, p.Pets[0].Toys, 1) {
+			assert.Equal(t, "a-pet", p.Pets[0].Name)
+			assert.Equal(t, p.ID.String(), p.Pets[0].PersonID.String())
+			assert.Equal(t, "ball", p.Pets[0].Toys[0].Name)
+		}
+	}
+}
+
+func TestFind_AndShouldGoBeforeOr(t *testing.T) {
+	store, _ := setup(t)
+
+	persons := make([]Person, 0)
+	err := Q(store, qry.C("Age =", 3).Or("Age =", 4).And("Name =", "b")).Order("Name", qry.OrderAsc).Find(&persons).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"a", "b"}, names(persons))
+	}
+}
+
+func TestFind_NestedCriteria_ShouldMatchAnyNested(t *testing.T) {
+	store, _ := setup(t)
+
+	persons := make([]Person, 0)
+	err := Q(store, qry.C("Pets.Color =", "green"), qry.C("Pets.Toys.Name =", "ball")).Find(&persons).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"c"}, names(persons))
+	}
+}
+
+func TestFind_InAndBetween_ShouldWork(t *testing.T) {
+	store, _ := setup(t)
+
+	persons := make([]*Person, 0)
+	err := Q(store, qry.C("Name IN", []string{"a", "c"})).Order("Name", qry.OrderAsc).Find(&persons).Error()
+	if assert.Nil(t, err) && assert.Len(t, persons, 2) {
+		assert.Equal(t, "a", persons[0].Name)
+		assert.Equal(t, "c", persons[1].Name)
+	}
+
+	var count int
+	err = Q(store, qry.C("Age BETWEEN", []int{4, 10})).Count(&Person{}, &count).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, 2, count)
+	}
+}
+
+func TestFind_FieldIsNullAndBetween_ShouldWork(t *testing.T) {
+	store, _ := setup(t)
+
+	persons := make([]Person, 0)
+	err := Q(store, qry.F("DeletedAt").IsNull().And(qry.F("Age").Between(4, 10))).Find(&persons).Error()
+	if assert.Nil(t, err) {
+		assert.ElementsMatch(t, []string{"b", "c"}, names(persons))
+	}
+
+	var count int
+	err = Q(store, qry.F("Pets.Color").IsNotNull().And(qry.F("DeletedAt").IsNotNull())).Count(&Person{}, &count).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, 0, count)
+	}
+}
+
+func TestFind_OrderLimitAndOffset_ShouldWork(t *testing.T) {
+	store, _ := setup(t)
+
+	persons := make([]Person, 0)
+	err := DB(store).Order("Name", qry.OrderDesc).Offset(1).Limit(1).Find(&persons).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"b"}, names(persons))
+	}
+
+	// The latest created first by default
+	err = DB(store).Limit(1).Find(&persons).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"c"}, names(persons))
+	}
+}
+
+func TestFirst_WhenNoneMatched_ShouldBeNotFound(t *testing.T) {
+	store, _ := setup(t)
+
+	err := Q(store, qry.C("Name =", "nobody")).First(&Person{}).Error()
+	assert.True(t, errors.Is(err, qry.ErrNotFound))
+}
+
+func TestFind_InvalidField_ShouldReturnInvalidFieldError(t *testing.T) {
+	store, _ := setup(t)
+
+	persons := make([]Person, 0)
+	err := Q(store, qry.C("Pets.Weight =", 3)).Find(&persons).Error()
+	var invalidFieldError *qry.InvalidFieldError
+	if assert.True(t, errors.As(err, &invalidFieldError)) {
+		assert.Equal(t, "Weight", invalidFieldError.Field)
+	}
+}
+
+func TestInnerJoin_ShouldSelectByJoinedMdl(t *testing.T) {
+	store, persons := setup(t)
+	address := Address{City: "Taipei", PersonID: persons[1].ID}
+	assert.Nil(t, DB(store).Create(&address).Error())
+
+	p := Person{}
+	err := DB(store).InnerJoin(&Address{}, &Person{}, qry.C("City =", "Taipei")).First(&p).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "b", p.Name)
+	}
+}
+
+func TestCreate_WhenIDExists_ShouldReturnErrors(t *testing.T) {
+	store, persons := setup(t)
+
+	err := DB(store).Create(&Person{BaseModel: mdl.BaseModel{ID: persons[0].ID}}).Error()
+	var uniqueErr *qry.UniqueViolationError
+	if assert.True(t, errors.As(err, &uniqueErr)) {
+		assert.Equal(t, []string{"id"}, uniqueErr.JSONKeys)
+	}
+
+	p := Person{Name: "d", Pets: []Pet{{BaseModel: mdl.BaseModel{ID: persons[0].Pets[0].ID}}}}
+	err = DB(store).Create(&p).Error()
+	var dupErr *qry.DuplicatePeggedIDError
+	assert.True(t, errors.As(err, &dupErr))
+	assert.Equal(t, 3, store.Len(&Person{}), "nothing should be created")
+}
+
+func TestDelete_ShouldCascadePeggedAndDissociatePegAssoc(t *testing.T) {
+	store, _ := setup(t)
+	friend := Friend{Name: "friend"}
+	assert.Nil(t, DB(store).Create(&friend).Error())
+	p := Person{Name: "d", Pets: []Pet{{Name: "d-pet", Toys: []Toy{{Name: "rope"}}}}, Friends: []Friend{friend}}
+	assert.Nil(t, DB(store).Create(&p).Error())
+
+	deleted := make([]Person, 0)
+	q := Q(store, qry.C("Name =", "d")).Returning(&deleted)
+	if assert.Nil(t, q.Delete(&Person{}).Error()) {
+		assert.Equal(t, int64(1), q.RowsAffected())
+		assert.Equal(t, []string{"d"}, names(deleted))
+	}
+
+	assert.Equal(t, 3, store.Len(&Pet{}))
+	assert.Equal(t, 3, store.Len(&Toy{}))
+	loaded := Friend{}
+	if assert.Nil(t, DB(store).First(&loaded).Error()) {
+		assert.Equal(t, "friend", loaded.Name)
+		assert.Nil(t, loaded.PersonID)
+	}
+}
+
+func TestDelete_WithoutIDOrCriteria_ShouldBeUnsafe(t *testing.T) {
+	store, _ := setup(t)
+
+	err := DB(store).Delete(&Person{}).Error()
+	var unsafeErr *qry.UnsafeDeleteError
+	assert.True(t, errors.As(err, &unsafeErr))
+	assert.Equal(t, 3, store.Len(&Person{}))
+}
+
+func TestUpdate_WithoutIDOrCriteria_ShouldUpdateEveryRecord(t *testing.T) {
+	store, _ := setup(t)
+
+	q := DB(store)
+	if assert.Nil(t, q.Update(&Person{}, qry.C("Age =", 30)).Error()) {
+		assert.Equal(t, int64(3), q.RowsAffected())
+	}
+
+	err := DB(store).UpdateFields(&Person{}, map[string]interface{}{"Age": 40}).Error()
+	var unsafeErr *qry.UnsafeUpdateError
+	assert.True(t, errors.As(err, &unsafeErr))
+
+	var count int
+	if assert.Nil(t, Q(store, qry.C("Age =", 30)).Count(&Person{}, &count).Error()) {
+		assert.Equal(t, 3, count)
+	}
+}
+
+func TestWithContextAndTimeout_WhenDone_ShouldFailWithoutChange(t *testing.T) {
+	store, _ := setup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Q(store, qry.C("Name =", "a")).WithContext(ctx).Delete(&Person{}).Error()
+	assert.True(t, errors.Is(err, context.Canceled))
+	err = DB(store).Timeout(0).Create(&Person{Name: "d"}).Error()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 3, store.Len(&Person{}))
+
+	// The context is for one terminal only
+	q := DB(store).WithContext(ctx)
+	assert.NotNil(t, q.Find(&[]Person{}).Error())
+	assert.Nil(t, q.Timeout(time.Minute).Find(&[]Person{}).Error())
+}
+
+func TestDeleteMany_ShouldOnlyDeleteTheOnesGiven(t *testing.T) {
+	store, persons := setup(t)
+
+	q := DB(store)
+	if assert.Nil(t, q.DeleteMany([]mdl.IModel{persons[0], persons[2]}).Error()) {
+		assert.Equal(t, int64(2), q.RowsAffected())
+	}
+	assert.Equal(t, 1, store.Len(&Person{}))
+	assert.Equal(t, 1, store.Len(&Toy{}))
+}
+
+func TestUpdateFields_NestedAndInc_ShouldWork(t *testing.T) {
+	store, _ := setup(t)
+
+	q := Q(store, qry.C("Age =", 4))
+	err := q.UpdateFields(&Person{}, map[string]interface{}{"Age": qry.Inc("Age", 1), "Pets.Color": "purple"}).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(4), q.RowsAffected())
+	}
+
+	persons := make([]Person, 0)
+	if assert.Nil(t, DB(store).Order("Name", qry.OrderAsc).Find(&persons).Error()) && assert.Len(t, persons, 3) {
+		assert.Equal(t, 3, persons[0].Age)
+		assert.Equal(t, "red", persons[0].Pets[0].Color)
+		assert.Equal(t, 5, persons[1].Age)
+		assert.Equal(t, "purple", persons[1].Pets[0].Color)
+		assert.Equal(t, 5, persons[2].Age)
+	}
+}
+
+func TestUpdateFields_NestedFieldAndNestedCriteria_ShouldOnlyUpdateTheMatchingOnes(t *testing.T) {
+	store := NewStore()
+	person := Person{Name: "a", Pets: []Pet{{Name: "red-pet", Color: "red"}, {Name: "green-pet", Color: "green"}}}
+	if !assert.Nil(t, DB(store).Create(&person).Error()) {
+		return
+	}
+
+	err := Q(store, qry.C("Pets.Color =", "red")).UpdateFields(&Person{}, map[string]interface{}{"Pets.Color": "purple"}).Error()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	pets := make([]Pet, 0)
+	if assert.Nil(t, DB(store).Order("Name", qry.OrderAsc).Find(&pets).Error()) && assert.Len(t, pets, 2) {
+		assert.Equal(t, "green", pets[0].Color)
+		assert.Equal(t, "purple", pets[1].Color)
+	}
+}
+
+func TestUpdate_ShouldWork(t *testing.T) {
+	store, persons := setup(t)
+
+	err := DB(store).Update(persons[0], qry.C("Name =", "aa").And("Age =", 30)).Error()
+	if assert.Nil(t, err) {
+		p := Person{}
+		assert.Nil(t, Q(store, qry.C("Name =", "aa")).First(&p).Error())
+		assert.Equal(t, 30, p.Age)
+	}
+}
+
+func TestUpdateFields_Versioned_ShouldCheckVersion(t *testing.T) {
+	store := NewStore()
+	account := Account{Name: "account"}
+	assert.Nil(t, DB(store).Create(&account).Error())
+
+	stale := account
+	assert.Nil(t, DB(store).UpdateFields(&account, map[string]interface{}{"Name": "new"}).Error())
+	assert.Equal(t, 1, account.Version)
+
+	err := DB(store).UpdateFields(&stale, map[string]interface{}{"Name": "stale"}).Error()
+	assert.True(t, errors.Is(err, qry.ErrStaleObject))
+
+	err = DB(store).Save(&stale).Error()
+	assert.True(t, errors.Is(err, qry.ErrStaleObject))
+
+	loaded := Account{}
+	if assert.Nil(t, DB(store).First(&loaded).Error()) {
+		assert.Equal(t, "new", loaded.Name)
+		assert.Equal(t, 1, loaded.Version)
+	}
+}
+
+func TestSave_VersionedNested_ShouldCheckVersion(t *testing.T) {
+	store := NewStore()
+	account := Account{Name: "account", Ledgers: []Ledger{{Name: "ledger"}}}
+	assert.Nil(t, DB(store).Create(&account).Error())
+
+	stale := account
+	stale.Ledgers = []Ledger{account.Ledgers[0]}
+
+	account.Ledgers[0].Name = "new"
+	assert.Nil(t, DB(store).Save(&account).Error())
+	assert.Equal(t, 1, account.Ledgers[0].Version)
+
+	stale.Version = account.Version // only the ledger is stale
+	stale.Ledgers[0].Name = "stale"
+	err := DB(store).Save(&stale).Error()
+	var staleErr *qry.StaleObjectError
+	if assert.True(t, errors.As(err, &staleErr)) {
+		assert.Equal(t, "ledger", staleErr.Table)
+	}
+	assert.Equal(t, 1, stale.Version)
+
+	loaded := Ledger{}
+	if assert.Nil(t, DB(store).First(&loaded).Error()) {
+		assert.Equal(t, "new", loaded.Name)
+		assert.Equal(t, 1, loaded.Version)
+	}
+}
+
+func TestUpdateMany_ShouldUpdateEachToItsOwnValue(t *testing.T) {
+	store, persons := setup(t)
+
+	persons[0].Age, persons[1].Age = 10, 20
+	persons[0].Name = "not updated"
+	q := DB(store)
+	if assert.Nil(t, q.UpdateMany([]mdl.IModel{persons[0], persons[1]}, "Age").Error()) {
+		assert.Equal(t, int64(2), q.RowsAffected())
+	}
+
+	loaded := make([]Person, 0)
+	if assert.Nil(t, DB(store).Order("Age", qry.OrderAsc).Find(&loaded).Error()) {
+		assert.Equal(t, []string{"c", "a", "b"}, names(loaded))
+	}
+}
+
+func TestUpdateMany_DuplicateIDs_ShouldGiveBuilderError(t *testing.T) {
+	store, persons := setup(t)
+
+	duplicate := *persons[0]
+	persons[0].Age, duplicate.Age = 10, 20
+	err := DB(store).UpdateMany([]mdl.IModel{persons[0], &duplicate}, "Age").Error()
+	assert.IsType(t, &qry.BuilderError{}, err)
+
+	p := Person{}
+	if assert.Nil(t, Q(store, qry.C("Name =", "a")).First(&p).Error()) {
+		assert.Equal(t, 3, p.Age)
+	}
+}
+
+func TestSaveGraph_ShouldSyncPegged(t *testing.T) {
+	store, persons := setup(t)
+
+	p := Person{}
+	assert.Nil(t, Q(store, qry.C("Name =", "a")).First(&p).Error())
+	p.Age = 33
+	p.Pets = []Pet{{Name: "new-pet"}}
+	if !assert.Nil(t, DB(store).SaveGraph(&p).Error()) {
+		return
+	}
+
+	loaded := Person{BaseModel: mdl.BaseModel{ID: persons[0].ID}}
+	if assert.Nil(t, DB(store).First(&loaded).Error()) {
+		assert.Equal(t, 33, loaded.Age)
+		if assert.Len(t, loaded.Pets, 1) {
+			assert.Equal(t, "new-pet", loaded.Pets[0].Name)
+		}
+	}
+	assert.Equal(t, 2, store.Len(&Toy{}), "toy of the pet removed should be removed")
+}
+
+func TestBuildQueryAndToSQL_ShouldReturnErrNoDatabase(t *testing.T) {
+	store := NewStore()
+
+	_, err := DB(store).BuildQuery(&Person{})
+	assert.Equal(t, ErrNoDatabase, err)
+
+	_, _, err = DB(store).ToSQL(&Person{}, qry.QueryTypeFind)
+	assert.Equal(t, ErrNoDatabase, err)
+}
+
+func TestLimit_AlreadySet_ShouldWarnToTheLogHandler(t *testing.T) {
+	store, _ := setup(t)
+
+	var buf bytes.Buffer
+	q := DB(store).WithLogHandler(qry.NewJSONHandler(&buf, qry.LevelWarn))
+	assert.Nil(t, q.Q().Limit(1).Limit(2).Find(&[]Person{}).Error())
+	assert.Contains(t, buf.String(), `"level":"WARN"`)
+	assert.Contains(t, buf.String(), "query limit already set")
+}
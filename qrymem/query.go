@@ -0,0 +1,851 @@
+package qrymem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrNoDatabase is returned by what needs a real database, such as BuildQuery() and ToSQL()
+var ErrNoDatabase = errors.New("qrymem has no database")
+
+// Q is qry.Q() on store instead of a database
+func Q(store *Store, args ...interface{}) qry.IQuery {
+	q := &Query{store: store}
+	return q.Q(args...)
+}
+
+// DB is qry.DB() on store instead of a database
+func DB(store *Store) qry.IQuery {
+	return Q(store)
+}
+
+// Query is qry.Query on a Store
+type Query struct {
+	store *Store
+
+	Err error
+
+	orderField *string
+	order      *qry.Order
+
+	limit  *int
+	offset *int
+
+	rowsAffected int64
+	returning    interface{}
+
+	ctx     context.Context
+	timeout *time.Duration
+
+	logHandler qry.LogHandler // if set, warns to it instead of qry.CurrentLogHandler()
+
+	builders []*qry.PredicateRelationBuilder // on the main mdl (including the nested one)
+	joins    []join                          // other non-nested mdls
+}
+
+// join is an InnerJoin()
+type join struct {
+	modelObj   mdl.IModel
+	foreignObj mdl.IModel
+	builders   []*qry.PredicateRelationBuilder
+}
+
+func (q *Query) Q(args ...interface{}) qry.IQuery {
+	// Returns a new IQuery, so it's re-entrant as qry.Query
+	q2 := &Query{store: q.store, ctx: q.ctx, timeout: q.timeout, logHandler: q.logHandler}
+	for _, arg := range args {
+		b, ok := arg.(*qry.PredicateRelationBuilder)
+		if !ok {
+			q2.Err = newBuilderError("incorrect arguments for Q()")
+			return q2
+		}
+		q2.builders = append(q2.builders, b)
+	}
+	return q2
+}
+
+func (q *Query) Order(field string, order qry.Order) qry.IQuery {
+	if q.order != nil {
+		q.warn("query order already set")
+	}
+
+	if strings.Contains(field, ".") {
+		q.Err = newBuilderError("dot notation in field not supported")
+		return q
+	}
+
+	q.orderField = &field
+	q.order = &order
+	return q
+}
+
+func (q *Query) Limit(limit int) qry.IQuery {
+	if q.limit != nil {
+		q.warn("query limit already set")
+	}
+	q.limit = &limit
+	return q
+}
+
+func (q *Query) Offset(offset int) qry.IQuery {
+	if q.offset != nil {
+		q.warn("query offset already set")
+	}
+	q.offset = &offset
+	return q
+}
+
+func (q *Query) Returning(out interface{}) qry.IQuery {
+	if q.returning != nil {
+		q.warn("query returning already set")
+	}
+	q.returning = out
+	return q
+}
+
+// WithContext fails the next terminal if ctx is done by then, as nothing in the store is waited on
+func (q *Query) WithContext(ctx context.Context) qry.IQuery {
+	q.ctx = ctx
+	return q
+}
+
+// Timeout fails the next terminal if d is not positive, as nothing in the store takes any time
+func (q *Query) Timeout(d time.Duration) qry.IQuery {
+	if q.timeout != nil {
+		q.warn("query timeout already set")
+	}
+	q.timeout = &d
+	return q
+}
+
+// WithLogHandler has the query (and the ones made from it by Q()) warn to h instead of
+// qry.CurrentLogHandler(), as there is no statement to log
+func (q *Query) WithLogHandler(h qry.LogHandler) qry.IQuery {
+	q.logHandler = h
+	return q
+}
+
+// warn logs msg as qry does
+func (q *Query) warn(msg string) {
+	h := q.logHandler
+	if h == nil {
+		h = qry.CurrentLogHandler()
+	}
+	if h != nil && h.Enabled(qry.LevelWarn) {
+		h.Handle(qry.Entry{Time: qry.NowFunc(), Level: qry.LevelWarn, Message: msg})
+	}
+}
+
+// InnerJoin selects the mdls which modelObj points to (with its foreign key), where modelObj
+// meets the criteria. Only foreignObj of the same type as the main mdl is supported.
+func (q *Query) InnerJoin(modelObj mdl.IModel, foreignObj mdl.IModel, args ...interface{}) qry.IQuery {
+	if q.Err != nil {
+		return q
+	}
+
+	j := join{modelObj: modelObj, foreignObj: foreignObj}
+	for _, arg := range args {
+		b, ok := arg.(*qry.PredicateRelationBuilder)
+		if !ok {
+			q.Err = newBuilderError("incorrect arguments for Q()")
+			return q
+		}
+		j.builders = append(j.builders, b)
+	}
+	q.joins = append(q.joins, j)
+	return q
+}
+
+// BuildQuery is not supported, since there is no Gorm db
+func (q *Query) BuildQuery(modelObj mdl.IModel) (*gorm.DB, error) {
+	defer q.resetWithoutResetError()
+	return nil, ErrNoDatabase
+}
+
+func (q *Query) Take(modelObj mdl.IModel) qry.IQuery {
+	return q.first(modelObj)
+}
+
+func (q *Query) First(modelObj mdl.IModel) qry.IQuery {
+	return q.first(modelObj)
+}
+
+func (q *Query) first(modelObj mdl.IModel) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	defer q.resetWithoutResetError()
+	if q.Err != nil {
+		return q
+	}
+
+	q.store.mu.Lock()
+	defer q.store.mu.Unlock()
+
+	// As Gorm, the ID of modelObj is part of the criteria if it's set
+	recs, err := q.selectRecords(modelObj, modelObj.GetID(), false)
+	if err != nil {
+		q.Err = err
+		return q
+	}
+	if recs, q.Err = q.orderOffsetAndLimit(modelObj, recs); q.Err != nil {
+		return q
+	}
+	if len(recs) == 0 {
+		q.Err = qry.ErrNotFound
+		return q
+	}
+
+	reflect.ValueOf(modelObj).Elem().Set(reflect.ValueOf(q.store.load(recs[0])).Elem())
+	return q
+}
+
+// Count counts the mdls selected, Limit and Offset are not considered
+func (q *Query) Count(modelObj mdl.IModel, no *int) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	defer q.resetWithoutResetError()
+	if q.Err != nil {
+		return q
+	}
+
+	q.store.mu.Lock()
+	defer q.store.mu.Unlock()
+
+	recs, err := q.selectRecords(modelObj, nil, false)
+	if err != nil {
+		q.Err = err
+		return q
+	}
+	if q.orderField != nil {
+		if _, q.Err = mdl.FieldNameToColumn(modelObj, *q.orderField); q.Err != nil {
+			return q
+		}
+	}
+
+	*no = len(recs)
+	return q
+}
+
+func (q *Query) Find(modelObjs interface{}) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	defer q.resetWithoutResetError()
+	if q.Err != nil {
+		return q
+	}
+
+	out := reflect.ValueOf(modelObjs)
+	if out.Kind() != reflect.Ptr || out.Elem().Kind() != reflect.Slice {
+		q.Err = newBuilderError("Find() takes a pointer to a slice of mdls")
+		return q
+	}
+	elemTyp := out.Elem().Type().Elem()
+	typ := elemTyp
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	modelObj, ok := reflect.New(typ).Interface().(mdl.IModel)
+	if !ok {
+		q.Err = newBuilderError("Find() takes a pointer to a slice of mdls")
+		return q
+	}
+
+	q.store.mu.Lock()
+	defer q.store.mu.Unlock()
+
+	recs, err := q.selectRecords(modelObj, nil, false)
+	if err != nil {
+		q.Err = err
+		return q
+	}
+	if recs, q.Err = q.orderOffsetAndLimit(modelObj, recs); q.Err != nil {
+		return q
+	}
+
+	sl := reflect.MakeSlice(out.Elem().Type(), 0, len(recs))
+	for _, rec := range recs {
+		if elemTyp.Kind() == reflect.Ptr {
+			sl = reflect.Append(sl, reflect.ValueOf(q.store.load(rec)))
+		} else {
+			sl = reflect.Append(sl, reflect.ValueOf(q.store.load(rec)).Elem())
+		}
+	}
+	out.Elem().Set(sl)
+	return q
+}
+
+func (q *Query) Create(modelObj mdl.IModel) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	returning := q.returning
+	q.Reset()
+	defer q.resetWithoutResetError()
+
+	if q.Err = q.store.transaction(func() error {
+		if err := q.store.checkCreate(modelObj); err != nil {
+			return err
+		}
+		q.store.create(modelObj)
+		return nil
+	}); q.Err != nil {
+		return q
+	}
+	q.rowsAffected = 1
+
+	if returning != nil {
+		q.Err = setReturning(returning, []mdl.IModel{flatCopy(modelObj)})
+	}
+	return q
+}
+
+func (q *Query) CreateMany(modelObjs []mdl.IModel) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	returning := q.returning
+	q.Reset()
+	defer q.resetWithoutResetError()
+
+	created := make([]mdl.IModel, 0, len(modelObjs))
+	for _, modelObj := range modelObjs {
+		if q.Err = q.store.transaction(func() error {
+			if err := q.store.checkCreate(modelObj); err != nil {
+				return err
+			}
+			q.store.create(modelObj)
+			return nil
+		}); q.Err != nil {
+			return q
+		}
+		q.rowsAffected++
+		created = append(created, flatCopy(modelObj))
+	}
+
+	if returning != nil && len(created) > 0 {
+		q.Err = setReturning(returning, created)
+	}
+	return q
+}
+
+// Delete deletes the mdls selected (or modelObj itself if it has an ID), along with their
+// pegged mdls
+func (q *Query) Delete(modelObj mdl.IModel) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	returning := q.returning
+	q.returning = nil
+	q.rowsAffected = 0
+
+	if q.Err != nil {
+		return q
+	}
+
+	if modelObj.GetID() == nil && !q.hasBuilder() {
+		q.Err = &qry.UnsafeDeleteError{Table: mdl.GetTableNameFromIModel(modelObj)}
+		return q
+	}
+
+	deleted := make([]mdl.IModel, 0)
+	q.Err = q.store.transaction(func() error {
+		recs, err := q.selectRecords(modelObj, modelObj.GetID(), true)
+		if err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			if q.store.deleteCascade(rec) {
+				deleted = append(deleted, flatCopy(rec))
+			}
+		}
+		return nil
+	})
+	if q.Err != nil {
+		return q
+	}
+	q.rowsAffected = int64(len(deleted))
+
+	if returning != nil {
+		q.Err = setReturning(returning, deleted)
+	}
+	return q
+}
+
+func (q *Query) DeleteMany(modelObjs []mdl.IModel) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	returning := q.returning
+	q.Reset()
+	defer q.resetWithoutResetError()
+
+	for _, modelObj := range modelObjs {
+		if modelObj.GetID() == nil {
+			q.Err = newBuilderError("modelObj to delete cannot have an ID of nil")
+			return q
+		}
+	}
+
+	deleted := make([]mdl.IModel, 0)
+	q.Err = q.store.transaction(func() error {
+		for _, modelObj := range modelObjs {
+			rec := q.store.get(mdl.GetTableNameFromIModel(modelObj), modelObj.GetID().String())
+			if rec != nil && q.store.deleteCascade(rec) {
+				deleted = append(deleted, flatCopy(rec))
+			}
+		}
+		return nil
+	})
+	q.rowsAffected = int64(len(deleted))
+
+	if returning != nil && q.Err == nil {
+		q.Err = setReturning(returning, deleted)
+	}
+	return q
+}
+
+func (q *Query) Save(modelObj mdl.IModel) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	defer q.resetWithoutResetError()
+	if q.Err != nil {
+		return q
+	}
+
+	q.Err = q.store.transaction(func() error {
+		if err := q.store.checkVersionsSaved(modelObj); err != nil {
+			return err
+		}
+		q.store.save(modelObj, true)
+		return nil
+	})
+	return q
+}
+
+func (q *Query) SaveGraph(modelObj mdl.IModel) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	defer q.resetWithoutResetError()
+	if q.Err != nil {
+		return q
+	}
+
+	if modelObj.GetID() == nil {
+		q.Err = newBuilderError("save graph must have a modelID")
+		return q
+	}
+
+	q.Err = q.store.transaction(func() error {
+		rec := q.store.get(mdl.GetTableNameFromIModel(modelObj), modelObj.GetID().String())
+		if rec == nil || isDeleted(rec) {
+			return qry.ErrNotFound
+		}
+		return q.store.saveGraph(modelObj, q.store.load(rec))
+	})
+	return q
+}
+
+// Update only allow one level of builder
+// p is a list of "=" joined by And, such as C("Age =", 3).And("Name =", "same")
+func (q *Query) Update(modelObj mdl.IModel, p *qry.PredicateRelationBuilder) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	defer q.resetWithoutResetError()
+	q.rowsAffected = 0
+
+	if q.Err != nil {
+		return q
+	}
+
+	rel, err := p.GetPredicateRelation()
+	if err != nil {
+		q.Err = err
+		return q
+	}
+
+	if field2Struct, _ := qry.FindFieldNameToStructAndStructFieldNameIfAny(rel); field2Struct != nil {
+		q.Err = newBuilderError("dot notation in update")
+		return q
+	}
+
+	fields, err := updateFieldsFromPredicateRelation(rel)
+	if err != nil {
+		q.Err = err
+		return q
+	}
+
+	q.rowsAffected, q.Err = q.updateFields(modelObj, fields)
+	return q
+}
+
+func (q *Query) UpdateFields(modelObj mdl.IModel, fields map[string]interface{}) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	defer q.resetWithoutResetError()
+	q.rowsAffected = 0
+
+	if q.Err != nil {
+		return q
+	}
+
+	if modelObj.GetID() == nil && !q.hasBuilder() {
+		q.Err = &qry.UnsafeUpdateError{Table: mdl.GetTableNameFromIModel(modelObj)}
+		return q
+	}
+
+	q.rowsAffected, q.Err = q.updateFields(modelObj, fields)
+	return q
+}
+
+func (q *Query) UpdateMany(modelObjs []mdl.IModel, fields ...string) qry.IQuery {
+	if q.done() {
+		return q
+	}
+	defer q.resetWithoutResetError()
+	q.rowsAffected = 0
+
+	if q.Err != nil || len(modelObjs) == 0 {
+		return q
+	}
+
+	q.Err = q.store.transaction(func() error {
+		var err error
+		q.rowsAffected, err = q.store.updateMany(modelObjs, fields)
+		return err
+	})
+	if q.Err != nil {
+		q.rowsAffected = 0
+	}
+	return q
+}
+
+// ToSQL is not supported, since there is no SQL
+func (q *Query) ToSQL(modelObj mdl.IModel, kind qry.QueryType, args ...interface{}) (string, []interface{}, error) {
+	defer q.resetWithoutResetError()
+	return "", nil, ErrNoDatabase
+}
+
+// GetDB returns nil, since there is no Gorm db
+func (q *Query) GetDB() *gorm.DB {
+	return nil
+}
+
+func (q *Query) Reset() qry.IQuery {
+	q.Err = nil
+	q.rowsAffected = 0
+	q.resetWithoutResetError()
+	return q
+}
+
+func (q *Query) RowsAffected() int64 {
+	return q.rowsAffected
+}
+
+func (q *Query) Error() error {
+	q.resetWithoutResetError()
+	err := q.Err
+	q.Err = nil
+	return err
+}
+
+func (q *Query) resetWithoutResetError() {
+	q.order = nil
+	q.orderField = nil
+	q.limit = nil
+	q.offset = nil
+	q.returning = nil
+	q.ctx = nil
+	q.timeout = nil
+
+	q.builders = nil
+	q.joins = nil
+}
+
+// done is whether the context given by WithContext or Timeout is done, which then fails the
+// terminal with its error
+func (q *Query) done() bool {
+	var err error
+	if q.timeout != nil && *q.timeout <= 0 {
+		err = context.DeadlineExceeded
+	} else if q.ctx != nil {
+		err = q.ctx.Err()
+	}
+	q.ctx, q.timeout = nil, nil // for this terminal only
+	if err == nil {
+		return false
+	}
+	q.Reset()
+	q.Err = err
+	return true
+}
+
+func (q *Query) hasBuilder() bool {
+	return len(q.builders) > 0 || len(q.joins) > 0
+}
+
+// ------------------
+
+// selectRecords returns the records of modelObj's table selected by the builders and joins
+// (and with id, if given), in order of insertion. The store should be locked.
+func (q *Query) selectRecords(modelObj mdl.IModel, id *datatype.UUID, unscoped bool) ([]mdl.IModel, error) {
+	rels, err := relationsOf(modelObj, q.builders)
+	if err != nil {
+		return nil, err
+	}
+
+	joinRels := make([][]*qry.PredicateRelation, len(q.joins))
+	for i, j := range q.joins {
+		if reflect.TypeOf(j.foreignObj) != reflect.TypeOf(modelObj) {
+			return nil, newBuilderError("qrymem only supports InnerJoin() on \"%s\"", mdl.GetModelTypeNameFromIModel(modelObj))
+		}
+		if joinRels[i], err = relationsOf(j.modelObj, j.builders); err != nil {
+			return nil, err
+		}
+	}
+
+	selected := make([]mdl.IModel, 0)
+loop:
+	for _, rec := range q.store.all(reflect.TypeOf(modelObj).Elem()) {
+		if (!unscoped && isDeleted(rec)) || (id != nil && rec.GetID().String() != id.String()) {
+			continue
+		}
+
+		if ok, err := q.store.matchRelations(modelObj, rels, rec); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		for i, j := range q.joins {
+			if ok, err := q.store.matchJoin(j.modelObj, joinRels[i], rec); err != nil {
+				return nil, err
+			} else if !ok {
+				continue loop
+			}
+		}
+
+		selected = append(selected, rec)
+	}
+	return selected, nil
+}
+
+// relationsOf gets the predicate relations of builders on modelObj, checking the fields
+func relationsOf(modelObj mdl.IModel, builders []*qry.PredicateRelationBuilder) ([]*qry.PredicateRelation, error) {
+	rels := make([]*qry.PredicateRelation, 0, len(builders))
+	for _, b := range builders {
+		rel, err := b.GetPredicateRelation()
+		if err != nil {
+			return nil, err
+		}
+		if len(rel.PredOrRels) == 0 {
+			continue
+		}
+		if _, _, err := rel.BuildQueryStringAndValues(modelObj); err != nil {
+			return nil, err
+		}
+		rels = append(rels, rel)
+	}
+	return rels, nil
+}
+
+// matchRelations is whether rec (of modelObj's table) meets every one of rels, which on nested
+// mdls (such as "Dogs.Name =") means any of the nested mdls does, as with an inner join
+func (s *Store) matchRelations(modelObj mdl.IModel, rels []*qry.PredicateRelation, rec mdl.IModel) (bool, error) {
+	for _, rel := range rels {
+		matched := false
+		for _, m := range s.reach(rec, rel.GetDesignatedField(modelObj)) {
+			ok, err := matchRelation(rel, m)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchJoin is whether there is a record of joinObj's table pointing to rec which meets rels
+func (s *Store) matchJoin(joinObj mdl.IModel, rels []*qry.PredicateRelation, rec mdl.IModel) (bool, error) {
+	for _, joined := range s.children(rec, reflect.TypeOf(joinObj).Elem()) {
+		ok, err := s.matchRelations(joinObj, rels, joined)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reach returns the records designated within rec, for example with designator "Dogs.DogToys"
+// the dog toys of the dogs of rec. Empty designator is rec itself.
+func (s *Store) reach(rec mdl.IModel, designator string) []mdl.IModel {
+	curr := []mdl.IModel{rec}
+	if designator == "" {
+		return curr
+	}
+
+	for _, tok := range strings.Split(designator, ".") {
+		curr = s.reachAll(curr, tok)
+	}
+	return curr
+}
+
+// reachAll returns the nested mdls at field tok of every one of recs
+func (s *Store) reachAll(recs []mdl.IModel, tok string) []mdl.IModel {
+	next := make([]mdl.IModel, 0)
+	for _, m := range recs {
+		structField, ok := reflect.TypeOf(m).Elem().FieldByName(tok)
+		if !ok {
+			continue
+		}
+		typ := structField.Type
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		next = append(next, s.children(m, typ)...)
+	}
+	return next
+}
+
+// reachMatching is as reach, but only reaches the nested mdls (and their parents) which meet the
+// ones of rels on their designator, as with the inner joins of qry
+func (s *Store) reachMatching(modelObj mdl.IModel, rels []*qry.PredicateRelation, rec mdl.IModel, designator string) ([]mdl.IModel, error) {
+	curr := []mdl.IModel{rec}
+	if designator == "" {
+		return curr, nil
+	}
+
+	toks := strings.Split(designator, ".")
+	for i, tok := range toks {
+		curr = s.reachAll(curr, tok)
+
+		at := strings.Join(toks[:i+1], ".")
+		for _, rel := range rels {
+			if rel.GetDesignatedField(modelObj) != at {
+				continue
+			}
+			matching := make([]mdl.IModel, 0, len(curr))
+			for _, m := range curr {
+				ok, err := matchRelation(rel, m)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					matching = append(matching, m)
+				}
+			}
+			curr = matching
+		}
+	}
+	return curr, nil
+}
+
+// orderOffsetAndLimit sorts recs by the order given ("CreatedAt" DESC by default), and applies
+// offset and limit
+func (q *Query) orderOffsetAndLimit(modelObj mdl.IModel, recs []mdl.IModel) ([]mdl.IModel, error) {
+	field, desc := "CreatedAt", true
+	if q.orderField != nil && q.order != nil {
+		if _, err := mdl.FieldNameToColumn(modelObj, *q.orderField); err != nil {
+			return nil, err
+		}
+		field, desc = *q.orderField, *q.order == qry.OrderDesc
+	}
+
+	sort.SliceStable(recs, func(i, j int) bool {
+		a := reflect.Indirect(reflect.ValueOf(recs[i])).FieldByName(field).Interface()
+		b := reflect.Indirect(reflect.ValueOf(recs[j])).FieldByName(field).Interface()
+		c := compareForOrder(a, b)
+		if desc {
+			c = -c
+		}
+		return c < 0
+	})
+
+	if q.offset != nil {
+		if *q.offset >= len(recs) {
+			recs = recs[:0]
+		} else if *q.offset > 0 {
+			recs = recs[*q.offset:]
+		}
+	}
+	if q.limit != nil && *q.limit >= 0 && *q.limit < len(recs) {
+		recs = recs[:*q.limit]
+	}
+	return recs, nil
+}
+
+// setReturning sets out (a pointer to a mdl or a slice of mdls) to recs
+func setReturning(out interface{}, recs []mdl.IModel) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return newBuilderError("returning must be a pointer to a mdl or a slice of mdls")
+	}
+
+	v = v.Elem()
+	switch v.Kind() {
+	case reflect.Slice:
+		sl := reflect.MakeSlice(v.Type(), 0, len(recs))
+		for _, rec := range recs {
+			recV := reflect.ValueOf(rec)
+			if v.Type().Elem().Kind() != reflect.Ptr {
+				recV = recV.Elem()
+			}
+			if !recV.Type().AssignableTo(v.Type().Elem()) {
+				return newBuilderError("cannot return %s into %s", recV.Type(), v.Type())
+			}
+			sl = reflect.Append(sl, recV)
+		}
+		v.Set(sl)
+	case reflect.Struct:
+		if len(recs) > 0 {
+			recV := reflect.ValueOf(recs[0]).Elem()
+			if !recV.Type().AssignableTo(v.Type()) {
+				return newBuilderError("cannot return %s into %s", recV.Type(), v.Type())
+			}
+			v.Set(recV)
+		}
+	default:
+		return newBuilderError("returning must be a pointer to a mdl or a slice of mdls")
+	}
+	return nil
+}
+
+// newPrimaryKeyViolationError is the error Postgres would give when creating a record whose ID exists
+func newPrimaryKeyViolationError(modelObj mdl.IModel) error {
+	tblName := mdl.GetTableNameFromIModel(modelObj)
+	return &qry.UniqueViolationError{ConstraintViolation: qry.ConstraintViolation{
+		Model:      mdl.GetModelTypeNameFromIModel(modelObj),
+		Table:      tblName,
+		Constraint: tblName + "_pkey",
+		Columns:    []string{"id"},
+		Fields:     []string{"ID"},
+		JSONKeys:   []string{"id"},
+		Err:        fmt.Errorf("duplicate key value violates unique constraint \"%s_pkey\"", tblName),
+	}}
+}
@@ -0,0 +1,139 @@
+// Package qrymem is an in-memory qry.IQuery, so service code can be unit-tested without Postgres.
+//
+//	store := qrymem.NewStore()
+//	q := qrymem.DB(store) // instead of qry.DB(db)
+//	err := q.Q(qry.C("Name =", "same")).Find(&tms).Error()
+//
+// Each table is a map of records keyed by ID. Nested mdls are kept in their own tables and
+// linked by their foreign keys (such as Dog.TestModelID), as they would be in the database.
+// Those are followed when the mdls are read (as with preloading), queried with dot notation
+// and updated. Deleting a mdl deletes its pegged mdls as well, and dissociates its pegassoc ones.
+// Many to many is not supported.
+package qrymem
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/t2wu/qry/mdl"
+)
+
+// Store holds the tables. Use one per test.
+type Store struct {
+	mu     sync.Mutex
+	tables map[string]map[string]*record // table name -> id -> record
+	seq    int64
+}
+
+// record is a row, which is never modified once stored (so a snapshot is a copy of the maps)
+type record struct {
+	modelObj mdl.IModel // without the nested mdls
+	seq      int64      // order of insertion, so the results are stable
+}
+
+// NewStore returns an empty Store
+func NewStore() *Store {
+	return &Store{tables: make(map[string]map[string]*record)}
+}
+
+// Len is the number of records in the table of modelObj
+func (s *Store) Len(modelObj mdl.IModel) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tables[mdl.GetTableNameFromIModel(modelObj)])
+}
+
+// get returns the record of modelObj's table with id, or nil
+func (s *Store) get(tblName string, id string) mdl.IModel {
+	if rec, ok := s.tables[tblName][id]; ok {
+		return rec.modelObj
+	}
+	return nil
+}
+
+// put stores a copy of modelObj without its nested mdls, replacing the one with the same ID
+func (s *Store) put(modelObj mdl.IModel) {
+	tblName := mdl.GetTableNameFromIModel(modelObj)
+	if _, ok := s.tables[tblName]; !ok {
+		s.tables[tblName] = make(map[string]*record)
+	}
+
+	id := modelObj.GetID().String()
+	rec := &record{modelObj: flatCopy(modelObj)}
+	if old, ok := s.tables[tblName][id]; ok {
+		rec.seq = old.seq
+	} else {
+		s.seq++
+		rec.seq = s.seq
+	}
+	s.tables[tblName][id] = rec
+}
+
+func (s *Store) remove(modelObj mdl.IModel) bool {
+	tblName := mdl.GetTableNameFromIModel(modelObj)
+	id := modelObj.GetID().String()
+	if _, ok := s.tables[tblName][id]; !ok {
+		return false
+	}
+	delete(s.tables[tblName], id)
+	return true
+}
+
+// all returns the records of the table of typ (a struct type), in order of insertion
+func (s *Store) all(typ reflect.Type) []mdl.IModel {
+	recs := make([]*record, 0, len(s.tables[mdl.GetTableNameFromType(typ)]))
+	for _, rec := range s.tables[mdl.GetTableNameFromType(typ)] {
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].seq < recs[j].seq })
+
+	modelObjs := make([]mdl.IModel, len(recs))
+	for i, rec := range recs {
+		modelObjs[i] = rec.modelObj
+	}
+	return modelObjs
+}
+
+// children returns the records of typ whose foreign key points to parent, in order of insertion
+func (s *Store) children(parent mdl.IModel, typ reflect.Type) []mdl.IModel {
+	children := make([]mdl.IModel, 0)
+	if parent.GetID() == nil {
+		return children
+	}
+
+	parentID := parent.GetID().String()
+	parentTypeName := mdl.GetModelTypeNameFromIModel(parent)
+	for _, m := range s.all(typ) {
+		if id, ok := getParentID(m, parentTypeName); ok && id == parentID {
+			children = append(children, m)
+		}
+	}
+	return children
+}
+
+type snapshot map[string]map[string]*record
+
+func (s *Store) snapshot() snapshot {
+	snap := make(snapshot, len(s.tables))
+	for tblName, recs := range s.tables {
+		snap[tblName] = make(map[string]*record, len(recs))
+		for id, rec := range recs {
+			snap[tblName][id] = rec
+		}
+	}
+	return snap
+}
+
+// transaction runs f with the store locked, and undoes everything f did if it errs
+func (s *Store) transaction(f func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := s.snapshot()
+	if err := f(); err != nil {
+		s.tables = snap
+		return err
+	}
+	return nil
+}
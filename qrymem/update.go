@@ -0,0 +1,282 @@
+package qrymem
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+// updateFieldsFromPredicateRelation turns something like C("Age =", 3).And("Name =", "same")
+// into field -> value for update
+func updateFieldsFromPredicateRelation(rel *qry.PredicateRelation) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	for _, logic := range rel.Logics {
+		if logic != qry.PredicateLogicAND {
+			return nil, newBuilderError("only AND is allowed in update")
+		}
+	}
+
+	for _, pr := range rel.PredOrRels {
+		switch c := pr.(type) {
+		case *qry.Predicate:
+			if c.Cond != qry.PredicateCondEQ {
+				return nil, newBuilderError("only \"=\" is allowed in update")
+			}
+			fields[c.Field] = c.Value
+		case *qry.PredicateRelation:
+			inner, err := updateFieldsFromPredicateRelation(c)
+			if err != nil {
+				return nil, err
+			}
+			for field, value := range inner {
+				fields[field] = value
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// updateFields updates fields (which can be nested) on the mdls selected (or modelObj itself
+// if it has an ID, every one of the table if neither), as qry.UpdateFields does. Returns rows
+// affected.
+func (q *Query) updateFields(modelObj mdl.IModel, fields map[string]interface{}) (int64, error) {
+	if len(fields) == 0 {
+		return 0, newBuilderError("update must have at least one field")
+	}
+
+	// Field "Dogs.Color" is updated on designator "Dogs"
+	designatorToFields := make(map[string][]string)
+	for field := range fields {
+		if _, err := mdl.FieldNameToColumn(modelObj, field); err != nil {
+			return 0, err
+		}
+
+		designator, name := "", field
+		if i := strings.LastIndex(field, "."); i != -1 {
+			designator, name = field[:i], field[i+1:]
+		}
+
+		currModelObj := modelObj
+		if designator != "" {
+			var err error
+			if currModelObj, err = mdl.GetInnerModelIfValid(modelObj, designator); err != nil {
+				return 0, err
+			}
+		}
+		typ, err := mdl.GetModelFieldTypeInModelIfValid(currModelObj, name)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := reflect.New(typ).Interface().(mdl.IModel); ok {
+			return 0, newBuilderError("field \"%s\" is not a column", field)
+		}
+
+		designatorToFields[designator] = append(designatorToFields[designator], field)
+	}
+
+	// Versioning is of the top-level mdl only, unless the version is set explicitly
+	// It's checked only when updating the mdl by ID, otherwise no rows updated could be
+	// because of the criteria
+	version, versioned := mdl.GetVersion(modelObj)
+	versionFieldName, _ := mdl.GetVersionFieldName(modelObj)
+	if _, ok := fields[versionFieldName]; ok && versioned {
+		versioned = false
+	}
+	checkVersion := versioned && modelObj.GetID() != nil && !q.hasBuilder()
+
+	// The nested mdls updated have to meet the criteria on them as well
+	rels, err := relationsOf(modelObj, q.builders)
+	if err != nil {
+		return 0, err
+	}
+
+	returning := q.returning
+	var rowsAffected int64
+	err = q.store.transaction(func() error {
+		recs, err := q.selectRecords(modelObj, modelObj.GetID(), false)
+		if err != nil {
+			return err
+		}
+		if checkVersion {
+			if len(recs) == 0 {
+				return &qry.StaleObjectError{Table: mdl.GetTableNameFromIModel(modelObj), ID: modelObj.GetID(), Version: version}
+			}
+			if recVersion, _ := mdl.GetVersion(recs[0]); recVersion != version {
+				return &qry.StaleObjectError{Table: mdl.GetTableNameFromIModel(modelObj), ID: modelObj.GetID(), Version: version}
+			}
+		}
+
+		updated := make([]mdl.IModel, 0, len(recs))
+		for designator, fieldsAt := range designatorToFields {
+			targets := make([]mdl.IModel, 0)
+			for _, rec := range recs {
+				reached, err := q.store.reachMatching(modelObj, rels, rec, designator)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, reached...)
+			}
+
+			for _, target := range targets {
+				m, err := updateRecord(target, fieldsAt, fields)
+				if err != nil {
+					return err
+				}
+				if designator == "" {
+					if versioned {
+						recVersion, _ := mdl.GetVersion(m)
+						mdl.SetVersion(m, recVersion+1)
+					}
+					updated = append(updated, m)
+				}
+				q.store.put(m)
+				rowsAffected++
+			}
+		}
+
+		if returning != nil {
+			return setReturning(returning, updated)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if checkVersion {
+		mdl.SetVersion(modelObj, version+1)
+	}
+	return rowsAffected, nil
+}
+
+// updateRecord returns a copy of rec with fields set to their values in fieldToValue
+// UpdateExpr are evaluated on rec, as the database does with the row before the update
+func updateRecord(rec mdl.IModel, fields []string, fieldToValue map[string]interface{}) (mdl.IModel, error) {
+	sort.Strings(fields)
+
+	m := flatCopy(rec)
+	hasUpdatedAt := false
+	for _, field := range fields {
+		toks := strings.Split(field, ".")
+		name := toks[len(toks)-1]
+		if name == "UpdatedAt" {
+			hasUpdatedAt = true
+		}
+
+		value := fieldToValue[field]
+		if expr, ok := value.(qry.UpdateExpr); ok {
+			evaluator, ok := expr.(qry.UpdateExprEvaluator)
+			if !ok {
+				return nil, newBuilderError("qrymem cannot evaluate %T", expr)
+			}
+			var err error
+			if value, err = evaluator.EvaluateOn(rec); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := setField(m, name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep the same behavior as Gorm's update
+	if !hasUpdatedAt && m.GetUpdatedAt() != nil {
+		*m.GetUpdatedAt() = gorm.NowFunc()
+	}
+	return m, nil
+}
+
+// updateMany updates fields of each mdl to the values within that mdl, as qry.UpdateMany does
+// Returns rows affected.
+func (s *Store) updateMany(modelObjs []mdl.IModel, fields []string) (int64, error) {
+	if len(fields) == 0 {
+		return 0, newBuilderError("update many must have at least one field")
+	}
+
+	typ := reflect.TypeOf(modelObjs[0])
+	for _, field := range fields {
+		if strings.Contains(field, ".") {
+			return 0, newBuilderError("dot notation in update many")
+		}
+
+		ftyp, err := mdl.GetModelFieldTypeInModelIfValid(modelObjs[0], field)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := reflect.New(ftyp).Interface().(mdl.IModel); ok {
+			return 0, newBuilderError("field \"%s\" is not a column", field)
+		}
+	}
+
+	// Versioned, unless the version is set explicitly
+	_, versioned := mdl.GetVersion(modelObjs[0])
+	versionFieldName, _ := mdl.GetVersionFieldName(modelObjs[0])
+	for _, field := range fields {
+		if field == versionFieldName {
+			versioned = false
+		}
+	}
+
+	ids := make(map[string]bool, len(modelObjs))
+	for _, modelObj := range modelObjs {
+		if reflect.TypeOf(modelObj) != typ {
+			return 0, newBuilderError("update many must have mdls of the same type")
+		}
+		if modelObj.GetID() == nil {
+			return 0, newBuilderError("modelObj to update cannot have an ID of nil")
+		}
+		if ids[modelObj.GetID().String()] {
+			return 0, newBuilderError("update many has more than one mdl of ID %s", modelObj.GetID().String())
+		}
+		ids[modelObj.GetID().String()] = true
+	}
+
+	tblName := mdl.GetTableNameFromIModel(modelObjs[0])
+	var rowsAffected int64
+	for _, modelObj := range modelObjs {
+		rec := s.get(tblName, modelObj.GetID().String())
+		if rec == nil || isDeleted(rec) {
+			if versioned {
+				return 0, &qry.StaleObjectError{Table: tblName}
+			}
+			continue
+		}
+
+		fieldToValue := make(map[string]interface{}, len(fields))
+		v := reflect.Indirect(reflect.ValueOf(modelObj))
+		for _, field := range fields {
+			fieldToValue[field] = v.FieldByName(field).Interface()
+		}
+
+		m, err := updateRecord(rec, append([]string{}, fields...), fieldToValue)
+		if err != nil {
+			return 0, err
+		}
+		if versioned {
+			version, _ := mdl.GetVersion(modelObj)
+			if recVersion, _ := mdl.GetVersion(rec); recVersion != version {
+				// Can't tell which one, as with qry
+				return 0, &qry.StaleObjectError{Table: tblName}
+			}
+			mdl.SetVersion(m, version+1)
+		}
+		s.put(m)
+		rowsAffected++
+	}
+
+	if versioned {
+		for _, modelObj := range modelObjs {
+			version, _ := mdl.GetVersion(modelObj)
+			mdl.SetVersion(modelObj, version+1)
+		}
+	}
+
+	return rowsAffected, nil
+}
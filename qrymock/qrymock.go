@@ -0,0 +1,820 @@
+// Package qrymock is a qry.IQuery which records every call and returns what the test expects,
+// so service code can be unit-tested without a database.
+//
+//	mock := qrymock.New()
+//	mock.Expect(qry.QueryTypeFind, &TestModel{}, qry.C("Name =", "same")).Return([]TestModel{tm1, tm2})
+//	mock.Expect(qry.QueryTypeDelete, &TestModel{}).ReturnError(qry.ErrNotFound)
+//
+//	err := service(mock.DB()) // instead of qry.DB(db)
+//
+//	if err := mock.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+//
+// Criteria are compared by their PredicateRelation, so Q(C("Name =", "same")) matches
+// Q(C("Name =", "same")) but not Q(C("Name IN", []string{"same"})). Use Any as a value to match
+// any value.
+package qrymock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrUnexpectedCall is when a call matches none of the expectations
+var ErrUnexpectedCall = errors.New("qrymock: unexpected call")
+
+// ErrNoDatabase is returned by what needs a real database, such as BuildQuery() and ToSQL()
+var ErrNoDatabase = errors.New("qrymock has no database")
+
+// Any matches any value of a predicate, such as C("CreatedAt <", qrymock.Any)
+var Any = anyValue{}
+
+type anyValue struct{}
+
+func (anyValue) String() string {
+	return "<any>"
+}
+
+// Mock holds the expectations and the calls
+type Mock struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []*Call
+	unexpected   []*Call
+}
+
+// New returns a Mock without expectations
+func New() *Mock {
+	return &Mock{}
+}
+
+// Q is qry.Q() on the mock
+func (m *Mock) Q(args ...interface{}) qry.IQuery {
+	q := &Query{mock: m}
+	return q.Q(args...)
+}
+
+// DB is qry.DB() on the mock
+func (m *Mock) DB() qry.IQuery {
+	return m.Q()
+}
+
+// Expect expects the terminal of kind on the type of modelObj (nil for any type), with the
+// criteria of Q() which are exactly builders (none if no builders given)
+func (m *Mock) Expect(kind qry.QueryType, modelObj mdl.IModel, builders ...*qry.PredicateRelationBuilder) *Expectation {
+	e := &Expectation{kind: kind, times: 1}
+	if modelObj != nil {
+		e.modelType = reflect.TypeOf(modelObj)
+	}
+	e.criteria, e.err = relationsOf(builders)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Calls returns the calls made so far, including the unexpected ones
+func (m *Mock) Calls() []*Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Call{}, m.calls...)
+}
+
+// ExpectationsWereMet returns an error if any expectation is not met, or if there was any
+// unexpected call
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msgs := make([]string, 0)
+	for _, e := range m.expectations {
+		if e.err != nil {
+			msgs = append(msgs, fmt.Sprintf("incorrect expectation %s: %s", e, e.err))
+		} else if e.calls < e.times {
+			msgs = append(msgs, fmt.Sprintf("expected %s %d time(s), called %d time(s)", e, e.times, e.calls))
+		}
+	}
+	for _, call := range m.unexpected {
+		msgs = append(msgs, fmt.Sprintf("unexpected %s", call))
+	}
+
+	if len(msgs) != 0 {
+		return fmt.Errorf("qrymock: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// call matches call with the expectations (the first one set which is not yet used up), and
+// applies it
+func (m *Mock) call(call *Call) (*Expectation, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+
+	var e *Expectation
+	for _, candidate := range m.expectations {
+		if candidate.calls < candidate.times && candidate.matches(call) {
+			e = candidate
+			e.calls++
+			break
+		}
+	}
+	if e == nil {
+		m.unexpected = append(m.unexpected, call)
+	}
+	m.mu.Unlock()
+
+	if e == nil {
+		return nil, fmt.Errorf("%w %s", ErrUnexpectedCall, call)
+	}
+
+	if e.run != nil {
+		e.run(call)
+	}
+	if e.result != nil && call.out != nil {
+		if err := assign(call.out, e.result); err != nil {
+			return e, err
+		}
+	}
+	return e, e.returnErr
+}
+
+// ------------------
+
+// Join is an InnerJoin() of a call
+type Join struct {
+	Model    mdl.IModel // the mdl joined
+	Foreign  mdl.IModel
+	Criteria []*qry.PredicateRelation
+}
+
+// Call is a terminal called on the mock
+type Call struct {
+	Kind qry.QueryType
+
+	// Model is what's given to the terminal, a mdl or a slice of them (Find, CreateMany,
+	// DeleteMany and UpdateMany)
+	Model interface{}
+
+	// Args are the rest, such as *PredicateRelationBuilder of Update, the fields of UpdateFields,
+	// or the field names of UpdateMany
+	Args []interface{}
+
+	Criteria   []*qry.PredicateRelation // of Q()
+	Joins      []Join
+	OrderField string // empty if not ordered
+	Order      qry.Order
+	Limit      *int
+	Offset     *int
+
+	Context context.Context // of WithContext, nil if not given
+	Timeout *time.Duration  // nil if not given
+
+	out interface{} // where the result goes
+}
+
+func (c *Call) String() string {
+	var sb strings.Builder
+	sb.WriteString(kindName(c.Kind))
+	if typ := modelTypeOf(c.Model); typ != nil {
+		sb.WriteString(" on " + typ.String())
+	}
+	if len(c.Criteria) != 0 {
+		sb.WriteString(" with " + formatRelations(c.Criteria))
+	}
+	for _, j := range c.Joins {
+		sb.WriteString(fmt.Sprintf(" joining %T", j.Model))
+		if len(j.Criteria) != 0 {
+			sb.WriteString(" with " + formatRelations(j.Criteria))
+		}
+	}
+	if c.OrderField != "" {
+		sb.WriteString(fmt.Sprintf(" order by %s %s", c.OrderField, c.Order))
+	}
+	if c.Offset != nil {
+		sb.WriteString(fmt.Sprintf(" offset %d", *c.Offset))
+	}
+	if c.Limit != nil {
+		sb.WriteString(fmt.Sprintf(" limit %d", *c.Limit))
+	}
+	return sb.String()
+}
+
+// Expectation is a call expected, and what it returns
+type Expectation struct {
+	kind      qry.QueryType
+	modelType reflect.Type
+	criteria  []*qry.PredicateRelation
+	joins     []Join
+	err       error // of the expectation itself
+
+	// Optional
+	args       []interface{}
+	hasArgs    bool
+	orderField *string
+	order      *qry.Order
+	limit      *int
+	offset     *int
+
+	times int
+	calls int
+
+	result       interface{}
+	returnErr    error
+	rowsAffected int64
+	run          func(call *Call)
+}
+
+// WithJoin expects InnerJoin(modelObj, foreignObj, builders...)
+func (e *Expectation) WithJoin(modelObj mdl.IModel, foreignObj mdl.IModel, builders ...*qry.PredicateRelationBuilder) *Expectation {
+	j := Join{Model: modelObj, Foreign: foreignObj}
+	var err error
+	if j.Criteria, err = relationsOf(builders); err != nil && e.err == nil {
+		e.err = err
+	}
+	e.joins = append(e.joins, j)
+	return e
+}
+
+// WithArgs expects the arguments of the terminal after the mdl(s), such as the fields of
+// UpdateFields, compared with reflect.DeepEqual
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	e.hasArgs = true
+	return e
+}
+
+// WithOrder expects Order(field, order)
+func (e *Expectation) WithOrder(field string, order qry.Order) *Expectation {
+	e.orderField = &field
+	e.order = &order
+	return e
+}
+
+// WithLimit expects Limit(limit)
+func (e *Expectation) WithLimit(limit int) *Expectation {
+	e.limit = &limit
+	return e
+}
+
+// WithOffset expects Offset(offset)
+func (e *Expectation) WithOffset(offset int) *Expectation {
+	e.offset = &offset
+	return e
+}
+
+// Times expects the call n times (1 by default)
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Return sets what the call returns: Find sets the slice given to result (a slice of mdls),
+// First and Take set the mdl given to result (a mdl), Count sets the count to result (an int),
+// and the rest set what's given to Returning() to result, if any.
+func (e *Expectation) Return(result interface{}) *Expectation {
+	e.result = result
+	return e
+}
+
+// ReturnError makes the call fail with err
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.returnErr = err
+	return e
+}
+
+// ReturnRowsAffected sets what RowsAffected() returns after the call
+func (e *Expectation) ReturnRowsAffected(n int64) *Expectation {
+	e.rowsAffected = n
+	return e
+}
+
+// Run runs f with the call before returning, f could for example modify call.Model
+func (e *Expectation) Run(f func(call *Call)) *Expectation {
+	e.run = f
+	return e
+}
+
+func (e *Expectation) String() string {
+	var sb strings.Builder
+	sb.WriteString(kindName(e.kind))
+	if e.modelType != nil {
+		sb.WriteString(" on " + e.modelType.String())
+	}
+	if len(e.criteria) != 0 {
+		sb.WriteString(" with " + formatRelations(e.criteria))
+	}
+	for _, j := range e.joins {
+		sb.WriteString(fmt.Sprintf(" joining %T", j.Model))
+		if len(j.Criteria) != 0 {
+			sb.WriteString(" with " + formatRelations(j.Criteria))
+		}
+	}
+	return sb.String()
+}
+
+func (e *Expectation) matches(call *Call) bool {
+	if e.err != nil || e.kind != call.Kind {
+		return false
+	}
+	if e.modelType != nil && e.modelType != modelTypeOf(call.Model) {
+		return false
+	}
+	if !relationsEqual(e.criteria, call.Criteria) {
+		return false
+	}
+
+	if len(e.joins) != len(call.Joins) {
+		return false
+	}
+	for i, j := range e.joins {
+		if reflect.TypeOf(j.Model) != reflect.TypeOf(call.Joins[i].Model) ||
+			reflect.TypeOf(j.Foreign) != reflect.TypeOf(call.Joins[i].Foreign) ||
+			!relationsEqual(j.Criteria, call.Joins[i].Criteria) {
+			return false
+		}
+	}
+
+	if e.hasArgs && !reflect.DeepEqual(e.args, call.Args) {
+		return false
+	}
+	if e.orderField != nil && (*e.orderField != call.OrderField || *e.order != call.Order) {
+		return false
+	}
+	if e.limit != nil && (call.Limit == nil || *e.limit != *call.Limit) {
+		return false
+	}
+	if e.offset != nil && (call.Offset == nil || *e.offset != *call.Offset) {
+		return false
+	}
+	return true
+}
+
+// ------------------
+
+// Query is the qry.IQuery of a Mock
+type Query struct {
+	mock *Mock
+
+	Err error
+
+	orderField string
+	order      qry.Order
+	limit      *int
+	offset     *int
+
+	rowsAffected int64
+	returning    interface{}
+
+	ctx     context.Context
+	timeout *time.Duration
+
+	logHandler qry.LogHandler // if set, warns to it instead of qry.CurrentLogHandler()
+
+	criteria []*qry.PredicateRelation
+	joins    []Join
+}
+
+func (q *Query) Q(args ...interface{}) qry.IQuery {
+	q2 := &Query{mock: q.mock, ctx: q.ctx, timeout: q.timeout, logHandler: q.logHandler}
+	builders := make([]*qry.PredicateRelationBuilder, 0, len(args))
+	for _, arg := range args {
+		b, ok := arg.(*qry.PredicateRelationBuilder)
+		if !ok {
+			q2.Err = &qry.BuilderError{Reason: "incorrect arguments for Q()"}
+			return q2
+		}
+		builders = append(builders, b)
+	}
+	q2.criteria, q2.Err = relationsOf(builders)
+	return q2
+}
+
+func (q *Query) Order(field string, order qry.Order) qry.IQuery {
+	if q.orderField != "" {
+		q.warn("query order already set")
+	}
+	q.orderField = field
+	q.order = order
+	return q
+}
+
+func (q *Query) Limit(limit int) qry.IQuery {
+	if q.limit != nil {
+		q.warn("query limit already set")
+	}
+	q.limit = &limit
+	return q
+}
+
+func (q *Query) Offset(offset int) qry.IQuery {
+	if q.offset != nil {
+		q.warn("query offset already set")
+	}
+	q.offset = &offset
+	return q
+}
+
+func (q *Query) Returning(out interface{}) qry.IQuery {
+	if q.returning != nil {
+		q.warn("query returning already set")
+	}
+	q.returning = out
+	return q
+}
+
+// WithContext is recorded in Call.Context, return context.Canceled with ReturnError to have
+// the terminal canceled
+func (q *Query) WithContext(ctx context.Context) qry.IQuery {
+	q.ctx = ctx
+	return q
+}
+
+// Timeout is recorded in Call.Timeout
+func (q *Query) Timeout(d time.Duration) qry.IQuery {
+	if q.timeout != nil {
+		q.warn("query timeout already set")
+	}
+	q.timeout = &d
+	return q
+}
+
+// WithLogHandler has the query (and the ones made from it by Q()) warn to h instead of
+// qry.CurrentLogHandler(), as there is no statement to log
+func (q *Query) WithLogHandler(h qry.LogHandler) qry.IQuery {
+	q.logHandler = h
+	return q
+}
+
+// warn logs msg as qry does
+func (q *Query) warn(msg string) {
+	h := q.logHandler
+	if h == nil {
+		h = qry.CurrentLogHandler()
+	}
+	if h != nil && h.Enabled(qry.LevelWarn) {
+		h.Handle(qry.Entry{Time: qry.NowFunc(), Level: qry.LevelWarn, Message: msg})
+	}
+}
+
+func (q *Query) InnerJoin(modelObj mdl.IModel, foreignObj mdl.IModel, args ...interface{}) qry.IQuery {
+	if q.Err != nil {
+		return q
+	}
+
+	builders := make([]*qry.PredicateRelationBuilder, 0, len(args))
+	for _, arg := range args {
+		b, ok := arg.(*qry.PredicateRelationBuilder)
+		if !ok {
+			q.Err = &qry.BuilderError{Reason: "incorrect arguments for Q()"}
+			return q
+		}
+		builders = append(builders, b)
+	}
+
+	j := Join{Model: modelObj, Foreign: foreignObj}
+	if j.Criteria, q.Err = relationsOf(builders); q.Err != nil {
+		return q
+	}
+	q.joins = append(q.joins, j)
+	return q
+}
+
+// BuildQuery is not supported, since there is no Gorm db
+func (q *Query) BuildQuery(modelObj mdl.IModel) (*gorm.DB, error) {
+	defer q.resetWithoutResetError()
+	return nil, ErrNoDatabase
+}
+
+func (q *Query) Take(modelObj mdl.IModel) qry.IQuery {
+	return q.terminal(qry.QueryTypeTake, modelObj, modelObj)
+}
+
+func (q *Query) First(modelObj mdl.IModel) qry.IQuery {
+	return q.terminal(qry.QueryTypeFirst, modelObj, modelObj)
+}
+
+func (q *Query) Find(modelObjs interface{}) qry.IQuery {
+	return q.terminal(qry.QueryTypeFind, modelObjs, modelObjs)
+}
+
+func (q *Query) Count(modelObj mdl.IModel, no *int) qry.IQuery {
+	return q.terminal(qry.QueryTypeCount, modelObj, no)
+}
+
+func (q *Query) Create(modelObj mdl.IModel) qry.IQuery {
+	return q.terminal(qry.QueryTypeCreate, modelObj, q.returning)
+}
+
+func (q *Query) CreateMany(modelObjs []mdl.IModel) qry.IQuery {
+	return q.terminal(qry.QueryTypeCreateMany, modelObjs, q.returning)
+}
+
+func (q *Query) Delete(modelObj mdl.IModel) qry.IQuery {
+	return q.terminal(qry.QueryTypeDelete, modelObj, q.returning)
+}
+
+func (q *Query) DeleteMany(modelObjs []mdl.IModel) qry.IQuery {
+	return q.terminal(qry.QueryTypeDeleteMany, modelObjs, q.returning)
+}
+
+func (q *Query) Save(modelObj mdl.IModel) qry.IQuery {
+	return q.terminal(qry.QueryTypeSave, modelObj, q.returning)
+}
+
+func (q *Query) SaveGraph(modelObj mdl.IModel) qry.IQuery {
+	return q.terminal(qry.QueryTypeSaveGraph, modelObj, q.returning)
+}
+
+func (q *Query) Update(modelObj mdl.IModel, p *qry.PredicateRelationBuilder) qry.IQuery {
+	return q.terminal(qry.QueryTypeUpdate, modelObj, q.returning, p)
+}
+
+func (q *Query) UpdateFields(modelObj mdl.IModel, fields map[string]interface{}) qry.IQuery {
+	return q.terminal(qry.QueryTypeUpdateFields, modelObj, q.returning, fields)
+}
+
+func (q *Query) UpdateMany(modelObjs []mdl.IModel, fields ...string) qry.IQuery {
+	return q.terminal(qry.QueryTypeUpdateMany, modelObjs, q.returning, fields)
+}
+
+// ToSQL is not supported, since there is no SQL
+func (q *Query) ToSQL(modelObj mdl.IModel, kind qry.QueryType, args ...interface{}) (string, []interface{}, error) {
+	defer q.resetWithoutResetError()
+	return "", nil, ErrNoDatabase
+}
+
+// GetDB returns nil, since there is no Gorm db
+func (q *Query) GetDB() *gorm.DB {
+	return nil
+}
+
+func (q *Query) Reset() qry.IQuery {
+	q.Err = nil
+	q.rowsAffected = 0
+	q.resetWithoutResetError()
+	return q
+}
+
+func (q *Query) RowsAffected() int64 {
+	return q.rowsAffected
+}
+
+func (q *Query) Error() error {
+	q.resetWithoutResetError()
+	err := q.Err
+	q.Err = nil
+	return err
+}
+
+// terminal records the call and returns what's expected
+func (q *Query) terminal(kind qry.QueryType, model interface{}, out interface{}, args ...interface{}) qry.IQuery {
+	defer q.resetWithoutResetError()
+	q.rowsAffected = 0
+	if q.Err != nil {
+		return q
+	}
+
+	call := &Call{
+		Kind:       kind,
+		Model:      model,
+		Args:       args,
+		Criteria:   q.criteria,
+		Joins:      q.joins,
+		OrderField: q.orderField,
+		Order:      q.order,
+		Limit:      q.limit,
+		Offset:     q.offset,
+		Context:    q.ctx,
+		Timeout:    q.timeout,
+		out:        out,
+	}
+	if args == nil {
+		call.Args = []interface{}{}
+	}
+
+	e, err := q.mock.call(call)
+	q.Err = err
+	if e != nil && err == nil {
+		q.rowsAffected = e.rowsAffected
+	}
+	return q
+}
+
+func (q *Query) resetWithoutResetError() {
+	q.orderField = ""
+	q.order = ""
+	q.limit = nil
+	q.offset = nil
+	q.returning = nil
+	q.ctx = nil
+	q.timeout = nil
+
+	q.criteria = nil
+	q.joins = nil
+}
+
+// ------------------
+
+func relationsOf(builders []*qry.PredicateRelationBuilder) ([]*qry.PredicateRelation, error) {
+	rels := make([]*qry.PredicateRelation, 0, len(builders))
+	for _, b := range builders {
+		rel, err := b.GetPredicateRelation()
+		if err != nil {
+			return nil, err
+		}
+		rels = append(rels, rel)
+	}
+	return rels, nil
+}
+
+// relationsEqual compares the criteria of Q() (or InnerJoin()) regardless of their order,
+// since each one is on a different level
+func relationsEqual(expected, actual []*qry.PredicateRelation) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+
+	used := make([]bool, len(actual))
+	for _, e := range expected {
+		found := false
+		for i, a := range actual {
+			if !used[i] && criteriaEqual(e, a) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func criteriaEqual(expected, actual qry.Criteria) bool {
+	switch e := expected.(type) {
+	case *qry.Predicate:
+		a, ok := actual.(*qry.Predicate)
+		if !ok || e.Field != a.Field || e.Cond != a.Cond {
+			return false
+		}
+		if _, ok := e.Value.(anyValue); ok {
+			return true
+		}
+		return reflect.DeepEqual(e.Value, a.Value)
+	case *qry.PredicateRelation:
+		a, ok := actual.(*qry.PredicateRelation)
+		if !ok || len(e.PredOrRels) != len(a.PredOrRels) || !reflect.DeepEqual(e.Logics, a.Logics) {
+			return false
+		}
+		for i := range e.PredOrRels {
+			if !criteriaEqual(e.PredOrRels[i], a.PredOrRels[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func formatRelations(rels []*qry.PredicateRelation) string {
+	strs := make([]string, len(rels))
+	for i, rel := range rels {
+		strs[i] = "C(" + formatCriteria(rel) + ")"
+	}
+	return strings.Join(strs, ", ")
+}
+
+func formatCriteria(criteria qry.Criteria) string {
+	switch c := criteria.(type) {
+	case *qry.Predicate:
+		return fmt.Sprintf("%s %s %v", c.Field, c.Cond, c.Value)
+	case *qry.PredicateRelation:
+		var sb strings.Builder
+		for i, pr := range c.PredOrRels {
+			if i > 0 {
+				sb.WriteString(fmt.Sprintf(" %s ", c.Logics[i-1]))
+			}
+			if _, ok := pr.(*qry.PredicateRelation); ok {
+				sb.WriteString("(" + formatCriteria(pr) + ")")
+			} else {
+				sb.WriteString(formatCriteria(pr))
+			}
+		}
+		return sb.String()
+	}
+	return fmt.Sprintf("%v", criteria)
+}
+
+// modelTypeOf is the type of mdl (such as *TestModel) given to a terminal, which can be
+// a mdl, a pointer to a slice of mdls, or a slice of mdls
+func modelTypeOf(model interface{}) reflect.Type {
+	if ms, ok := model.([]mdl.IModel); ok {
+		if len(ms) == 0 {
+			return nil
+		}
+		return reflect.TypeOf(ms[0])
+	}
+
+	typ := reflect.TypeOf(model)
+	if typ == nil {
+		return nil
+	}
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		if typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct {
+			return typ
+		}
+		typ = typ.Elem()
+	}
+	return reflect.PtrTo(typ)
+}
+
+// assign sets what dst points to (a mdl, a slice of mdls or an int) to src, which can be
+// a mdl, a pointer to it, or a slice of either
+func assign(dst interface{}, src interface{}) error {
+	dstV := reflect.ValueOf(dst)
+	if dstV.Kind() != reflect.Ptr || dstV.IsNil() {
+		return fmt.Errorf("qrymock: cannot return into %T", dst)
+	}
+	dstV = dstV.Elem()
+
+	if dstV.Kind() == reflect.Slice {
+		srcV := reflect.ValueOf(src)
+		if srcV.Kind() != reflect.Slice {
+			return fmt.Errorf("qrymock: cannot return %T into %T", src, dst)
+		}
+		sl := reflect.MakeSlice(dstV.Type(), 0, srcV.Len())
+		for i := 0; i < srcV.Len(); i++ {
+			elem, ok := convert(srcV.Index(i), dstV.Type().Elem())
+			if !ok {
+				return fmt.Errorf("qrymock: cannot return %T into %T", src, dst)
+			}
+			sl = reflect.Append(sl, elem)
+		}
+		dstV.Set(sl)
+		return nil
+	}
+
+	v, ok := convert(reflect.ValueOf(src), dstV.Type())
+	if !ok {
+		return fmt.Errorf("qrymock: cannot return %T into %T", src, dst)
+	}
+	dstV.Set(v)
+	return nil
+}
+
+// convert converts v into typ, dereferencing or taking the address as needed
+func convert(v reflect.Value, typ reflect.Type) (reflect.Value, bool) {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch {
+	case !v.IsValid():
+		return reflect.Value{}, false
+	case v.Type().AssignableTo(typ):
+		return v, true
+	case v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type().AssignableTo(typ):
+		return v.Elem(), true
+	case typ.Kind() == reflect.Ptr && v.Type().AssignableTo(typ.Elem()):
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(v)
+		return ptr, true
+	case v.Type().ConvertibleTo(typ) && v.Kind() != reflect.String && typ.Kind() != reflect.String:
+		return v.Convert(typ), true
+	}
+	return reflect.Value{}, false
+}
+
+var kindNames = map[qry.QueryType]string{
+	qry.QueryTypeFirst:        "First",
+	qry.QueryTypeFind:         "Find",
+	qry.QueryTypeTake:         "Take",
+	qry.QueryTypeCount:        "Count",
+	qry.QueryTypeCreate:       "Create",
+	qry.QueryTypeCreateMany:   "CreateMany",
+	qry.QueryTypeDelete:       "Delete",
+	qry.QueryTypeDeleteMany:   "DeleteMany",
+	qry.QueryTypeSave:         "Save",
+	qry.QueryTypeSaveGraph:    "SaveGraph",
+	qry.QueryTypeUpdate:       "Update",
+	qry.QueryTypeUpdateFields: "UpdateFields",
+	qry.QueryTypeUpdateMany:   "UpdateMany",
+}
+
+func kindName(kind qry.QueryType) string {
+	if name, ok := kindNames[kind]; ok {
+		return name
+	}
+	return fmt.Sprintf("QueryType(%d)", kind)
+}
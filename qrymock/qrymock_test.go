@@ -0,0 +1,202 @@
+package qrymock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestModel struct {
+	mdl.BaseModel
+
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type Address struct {
+	mdl.BaseModel
+
+	City string `json:"city"`
+
+	TestModelID *datatype.UUID `gorm:"type:uuid;index;" json:"-"`
+}
+
+func TestFind_WithExpectedCriteria_ShouldReturnRows(t *testing.T) {
+	mock := New()
+	rows := []TestModel{{Name: "same", Age: 3}, {Name: "same", Age: 4}}
+	mock.Expect(qry.QueryTypeFind, &TestModel{}, qry.C("Name =", "same")).Return(rows)
+
+	var out []TestModel
+	err := mock.DB().Q(qry.C("Name =", "same")).Find(&out).Error()
+	assert.Nil(t, err)
+	assert.Equal(t, rows, out)
+
+	var ptrs []*TestModel
+	mock.Expect(qry.QueryTypeFind, &TestModel{}, qry.C("Name =", "same")).Return(rows)
+	if assert.Nil(t, mock.DB().Q(qry.C("Name =", "same")).Find(&ptrs).Error()) && assert.Len(t, ptrs, 2) {
+		assert.Equal(t, 4, ptrs[1].Age)
+	}
+
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFind_WithDifferentCriteria_ShouldBeUnexpected(t *testing.T) {
+	mock := New()
+	mock.Expect(qry.QueryTypeFind, &TestModel{}, qry.C("Name =", "same"))
+
+	var out []TestModel
+	err := mock.DB().Q(qry.C("Name IN", []string{"same"})).Find(&out).Error()
+	assert.True(t, errors.Is(err, ErrUnexpectedCall))
+
+	err = mock.ExpectationsWereMet()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "expected Find on *qrymock.TestModel with C(Name = same) 1 time(s), called 0 time(s)")
+		assert.Contains(t, err.Error(), "unexpected Find on *qrymock.TestModel with C(Name IN [same])")
+	}
+}
+
+func TestFind_NestedCriteria_ShouldBeComparedStructurally(t *testing.T) {
+	mock := New()
+	mock.Expect(qry.QueryTypeFind, &TestModel{},
+		qry.C("Name =", "same").And(qry.C("Age >", 3).Or("Age <", 1)),
+		qry.C("Dogs.Color =", "red"),
+	)
+
+	// Q() in a different order is the same, since each one is on a different level
+	var out []TestModel
+	err := mock.DB().Q(
+		qry.C("Dogs.Color =", "red"),
+		qry.C("Name =", "same").And(qry.C("Age >", 3).Or("Age <", 1)),
+	).Find(&out).Error()
+	assert.Nil(t, err)
+
+	// But not a different tree
+	mock.Expect(qry.QueryTypeFind, &TestModel{}, qry.C("Name =", "same").And("Age >", 3).Or("Age <", 1))
+	err = mock.DB().Q(qry.C("Name =", "same").And(qry.C("Age >", 3).Or("Age <", 1))).Find(&out).Error()
+	assert.True(t, errors.Is(err, ErrUnexpectedCall))
+}
+
+func TestFirst_WithAny_ShouldMatchAnyValue(t *testing.T) {
+	mock := New()
+	mock.Expect(qry.QueryTypeFirst, &TestModel{}, qry.C("Name =", Any)).Return(&TestModel{Name: "found"})
+
+	tm := TestModel{}
+	assert.Nil(t, mock.DB().Q(qry.C("Name =", "whatever")).First(&tm).Error())
+	assert.Equal(t, "found", tm.Name)
+}
+
+func TestDelete_WithReturnError_ShouldFail(t *testing.T) {
+	mock := New()
+	mock.Expect(qry.QueryTypeDelete, &TestModel{}).ReturnError(qry.ErrNotFound)
+
+	err := mock.DB().Delete(&TestModel{}).Error()
+	assert.True(t, errors.Is(err, qry.ErrNotFound))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCount_ShouldSetCount(t *testing.T) {
+	mock := New()
+	mock.Expect(qry.QueryTypeCount, &TestModel{}, qry.C("Age >", 3)).Return(2)
+
+	no := 0
+	assert.Nil(t, mock.DB().Q(qry.C("Age >", 3)).Count(&TestModel{}, &no).Error())
+	assert.Equal(t, 2, no)
+}
+
+func TestUpdateFields_WithArgsAndReturning_ShouldMatchAndReturn(t *testing.T) {
+	mock := New()
+	updated := []TestModel{{Name: "new"}}
+	mock.Expect(qry.QueryTypeUpdateFields, &TestModel{}, qry.C("Name =", "old")).
+		WithArgs(map[string]interface{}{"Name": "new"}).
+		Return(updated).
+		ReturnRowsAffected(1)
+
+	var out []TestModel
+	q := mock.DB().Q(qry.C("Name =", "old")).Returning(&out).UpdateFields(&TestModel{}, map[string]interface{}{"Name": "new"})
+	assert.Nil(t, q.Error())
+	assert.Equal(t, updated, out)
+	assert.Equal(t, int64(1), q.RowsAffected())
+
+	err := mock.DB().Q(qry.C("Name =", "old")).UpdateFields(&TestModel{}, map[string]interface{}{"Name": "other"}).Error()
+	assert.True(t, errors.Is(err, ErrUnexpectedCall))
+}
+
+func TestOrderLimitOffsetAndJoin_ShouldBeRecordedAndMatched(t *testing.T) {
+	mock := New()
+	mock.Expect(qry.QueryTypeFind, &TestModel{}).
+		WithJoin(&Address{}, &TestModel{}, qry.C("City =", "Taipei")).
+		WithOrder("Age", qry.OrderDesc).
+		WithLimit(10).
+		Times(2)
+
+	for i := 0; i < 2; i++ {
+		var out []TestModel
+		err := mock.DB().InnerJoin(&Address{}, &TestModel{}, qry.C("City =", "Taipei")).
+			Order("Age", qry.OrderDesc).Offset(i * 10).Limit(10).Find(&out).Error()
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	calls := mock.Calls()
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, qry.QueryTypeFind, calls[1].Kind)
+		assert.Equal(t, "Age", calls[1].OrderField)
+		assert.Equal(t, 10, *calls[1].Offset)
+		assert.Len(t, calls[1].Joins, 1)
+		assert.Equal(t, "Find on *qrymock.TestModel joining *qrymock.Address with C(City = Taipei) order by Age DESC offset 10 limit 10", calls[1].String())
+	}
+}
+
+func TestRun_ShouldBeCalledWithTheCall(t *testing.T) {
+	mock := New()
+	id := datatype.NewUUID()
+	mock.Expect(qry.QueryTypeCreate, &TestModel{}).Run(func(call *Call) {
+		call.Model.(*TestModel).ID = id
+	})
+
+	tm := TestModel{Name: "new"}
+	assert.Nil(t, mock.DB().Create(&tm).Error())
+	assert.Equal(t, id.String(), tm.ID.String())
+}
+
+func TestWithContextAndTimeout_ShouldBeRecorded(t *testing.T) {
+	mock := New()
+	mock.Expect(qry.QueryTypeFirst, &TestModel{}).ReturnError(context.DeadlineExceeded)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := mock.DB().WithContext(ctx).Timeout(time.Second).First(&TestModel{}).Error()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	if calls := mock.Calls(); assert.Len(t, calls, 1) && assert.NotNil(t, calls[0].Timeout) {
+		assert.Equal(t, ctx, calls[0].Context)
+		assert.Equal(t, time.Second, *calls[0].Timeout)
+	}
+}
+
+func TestQ_WithIncorrectCriteria_ShouldFailWithoutCall(t *testing.T) {
+	mock := New()
+
+	var out []TestModel
+	err := mock.DB().Q(qry.C("Name ==", "same")).Find(&out).Error()
+	assert.Error(t, err)
+	assert.Len(t, mock.Calls(), 0)
+}
+
+func TestLimit_AlreadySet_ShouldWarnToTheLogHandler(t *testing.T) {
+	mock := New()
+	mock.Expect(qry.QueryTypeFind, &TestModel{})
+
+	var buf bytes.Buffer
+	q := mock.DB().WithLogHandler(qry.NewJSONHandler(&buf, qry.LevelWarn))
+	assert.Nil(t, q.Q().Limit(1).Limit(2).Find(&[]TestModel{}).Error())
+	assert.Contains(t, buf.String(), `"level":"WARN"`)
+	assert.Contains(t, buf.String(), "query limit already set")
+}
@@ -0,0 +1,149 @@
+// Package qrytest tests the SQL rendered by qry against golden files, without a database.
+//
+//	func TestFindByName(t *testing.T) {
+//		db := qrytest.NewDB("postgres")
+//		qrytest.AssertSQL(t, qry.Q(db, qry.C("Name =", "same")), &Person{}, qry.QueryTypeFind)
+//	}
+//
+// The golden file is testdata/<test name>.golden. Run "go test -update" to create or update it.
+//
+// DetectNPlusOne fails a test whose queries run N+1 statements.
+package qrytest
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+var update = flag.Bool("update", false, "update golden files of qrytest")
+
+// GoldenDir is where the golden files are
+var GoldenDir = "testdata"
+
+// NewDB returns a Gorm db of the dialect (such as "postgres") which is not connected to
+// any database, so it can only be used for rendering SQL (such as with AssertSQL)
+// Tables are singular, as with how qry is normally used.
+func NewDB(dialect string) *gorm.DB {
+	db, err := gorm.Open(dialect, noDB{})
+	if err != nil {
+		panic(err)
+	}
+	db.SingularTable(true)
+	return db
+}
+
+// AssertSQL renders the query of the kind as qry.ToSQL does, and compares it against the golden file
+func AssertSQL(t testing.TB, q qry.IQuery, modelObj mdl.IModel, kind qry.QueryType, args ...interface{}) {
+	t.Helper()
+
+	stmt, vars, err := q.ToSQL(modelObj, kind, args...)
+	if err != nil {
+		t.Fatalf("qrytest: failed to render SQL: %s", err)
+		return
+	}
+
+	AssertGolden(t, Format(stmt, vars))
+}
+
+// AssertGolden compares got against the golden file of the test
+func AssertGolden(t testing.TB, got string) {
+	t.Helper()
+
+	path := GoldenPath(t)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("qrytest: %s", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("qrytest: %s", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("qrytest: %s (run with -update to create it)", err)
+		return
+	}
+
+	if string(want) != got {
+		t.Errorf("qrytest: SQL differs from %s (run with -update if it's expected)\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// GoldenPath is the golden file of the test
+func GoldenPath(t testing.TB) string {
+	return filepath.Join(GoldenDir, unsafeFileChars.ReplaceAllString(t.Name(), "_")+".golden")
+}
+
+// Format formats the statement and its args for the golden file
+// Args that change from run to run (time) are replaced by their type.
+func Format(stmt string, vars []interface{}) string {
+	var sb strings.Builder
+	sb.WriteString("-- sql --\n")
+	sb.WriteString(strings.TrimSpace(stmt))
+	sb.WriteString("\n-- args --\n")
+	for _, v := range vars {
+		switch val := v.(type) {
+		case time.Time:
+			sb.WriteString("time.Time <time>\n")
+		case nil:
+			sb.WriteString("<nil>\n")
+		default:
+			sb.WriteString(fmt.Sprintf("%T %v\n", val, val))
+		}
+	}
+	return sb.String()
+}
+
+// noDB is a connection which never connects, qry.ToSQL replaces it
+type noDB struct{}
+
+func (noDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return nil, errNoDB
+}
+
+func (noDB) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errNoDB
+}
+
+func (noDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errNoDB
+}
+
+func (noDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+var errNoDB = fmt.Errorf("qrytest: not connected to any database")
+
+// DetectNPlusOne fails t if a terminal runs a statement of the same shape threshold times or
+// more until the end of t, such as a statement of each row (see qry.NPlusOne)
+//
+//	func TestCreateDevices(t *testing.T) {
+//		qrytest.DetectNPlusOne(t, 5)
+//		...
+//	}
+func DetectNPlusOne(t testing.TB, threshold int) {
+	t.Helper()
+
+	prev := qry.CurrentNPlusOne()
+	qry.UseNPlusOne(qry.NPlusOne{Threshold: threshold, OnDetect: func(d qry.NPlusOneDetection) {
+		t.Errorf("qrytest: %s", d)
+	}})
+	t.Cleanup(func() { qry.UseNPlusOne(prev) })
+}
@@ -0,0 +1,159 @@
+package qrytest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/t2wu/qry"
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Person struct {
+	mdl.BaseModel
+
+	Name string `gorm:"column:real_name_column" json:"name"`
+	Age  int    `json:"age"`
+
+	Pets []Pet `betterrest:"peg" json:"pets"`
+}
+
+type Pet struct {
+	mdl.BaseModel
+
+	Name  string `json:"name"`
+	Color string `json:"color"`
+
+	PersonID *datatype.UUID `gorm:"type:uuid;index;not null;" json:"-"`
+}
+
+const personID = "bc3eedae-21a5-478f-93d1-a54dc5ad7559"
+
+func TestFind_Criteria(t *testing.T) {
+	db := NewDB("postgres")
+	AssertSQL(t, qry.Q(db, qry.C("Name =", "same").And("Age >=", 18)), &Person{}, qry.QueryTypeFind)
+}
+
+func TestFind_NestedCriteria(t *testing.T) {
+	db := NewDB("postgres")
+	AssertSQL(t, qry.Q(db, qry.C("Pets.Color IN", []string{"red", "green"})), &Person{}, qry.QueryTypeFind)
+}
+
+func TestFind_OrderLimitOffset(t *testing.T) {
+	db := NewDB("postgres")
+	q := qry.Q(db, qry.C("Age <", 3)).Order("Age", qry.OrderAsc).Limit(10).Offset(20)
+	AssertSQL(t, q, &Person{}, qry.QueryTypeFind)
+}
+
+func TestFirst_Criteria(t *testing.T) {
+	db := NewDB("postgres")
+	AssertSQL(t, qry.Q(db, qry.C("Name =", "same")), &Person{}, qry.QueryTypeFirst)
+}
+
+func TestCount_Criteria(t *testing.T) {
+	db := NewDB("postgres")
+	AssertSQL(t, qry.Q(db, qry.C("Age >", 3)), &Person{}, qry.QueryTypeCount)
+}
+
+func TestDelete_Criteria(t *testing.T) {
+	db := NewDB("postgres")
+	AssertSQL(t, qry.Q(db, qry.C("Name =", "same")), &Person{}, qry.QueryTypeDelete)
+}
+
+func TestUpdateFields_Nested(t *testing.T) {
+	db := NewDB("postgres")
+	fields := map[string]interface{}{"Age": qry.Inc("Age", 1), "Pets.Color": "purple"}
+	AssertSQL(t, qry.Q(db, qry.C("Name =", "same")), &Person{}, qry.QueryTypeUpdateFields, fields)
+}
+
+func TestUpdateMany(t *testing.T) {
+	db := NewDB("postgres")
+	p := Person{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(personID)}, Age: 3}
+	AssertSQL(t, qry.Q(db), &p, qry.QueryTypeUpdateMany, "Age")
+}
+
+func TestFind_MySQL_OffsetWithoutLimit(t *testing.T) {
+	db := NewDB("mysql")
+	q := qry.Q(db, qry.C("Pets.Color IN", []string{"red", "green"})).Offset(20)
+	AssertSQL(t, q, &Person{}, qry.QueryTypeFind)
+}
+
+func TestUpdateFields_MySQL_Nested(t *testing.T) {
+	db := NewDB("mysql")
+	fields := map[string]interface{}{"Pets.Color": "purple"}
+	AssertSQL(t, qry.Q(db, qry.C("Name =", "same")), &Person{}, qry.QueryTypeUpdateFields, fields)
+}
+
+func TestUpdateFields_MySQL(t *testing.T) {
+	db := NewDB("mysql")
+	fields := map[string]interface{}{"Age": qry.Inc("Age", 1), "Name": "other"}
+	AssertSQL(t, qry.Q(db, qry.C("Name =", "same")), &Person{}, qry.QueryTypeUpdateFields, fields)
+}
+
+func TestUpdateFields_MySQL_Returning(t *testing.T) {
+	db := NewDB("mysql")
+	q := qry.Q(db, qry.C("Name =", "same")).Returning(&[]Person{})
+	AssertSQL(t, q, &Person{}, qry.QueryTypeUpdateFields, map[string]interface{}{"Age": 3})
+}
+
+func TestUpdateMany_MySQL(t *testing.T) {
+	db := NewDB("mysql")
+	p := Person{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(personID)}, Age: 3}
+	AssertSQL(t, qry.Q(db), &p, qry.QueryTypeUpdateMany, "Age")
+}
+
+func TestDelete_MySQL_Criteria(t *testing.T) {
+	db := NewDB("mysql")
+	AssertSQL(t, qry.Q(db, qry.C("Name =", "same")), &Person{}, qry.QueryTypeDelete)
+}
+
+func TestDelete_MySQL_Returning(t *testing.T) {
+	db := NewDB("mysql")
+	q := qry.Q(db, qry.C("Name =", "same")).Returning(&[]Person{})
+	AssertSQL(t, q, &Person{}, qry.QueryTypeDelete)
+}
+
+func TestFormat_ShouldReplaceTime(t *testing.T) {
+	got := Format("SELECT 1", []interface{}{"same", 3, nil})
+	assert.Equal(t, "-- sql --\nSELECT 1\n-- args --\nstring same\nint 3\n<nil>\n", got)
+}
+
+func TestGoldenPath_ShouldBeUnderTestdata(t *testing.T) {
+	assert.Equal(t, "testdata/TestGoldenPath_ShouldBeUnderTestdata.golden", GoldenPath(t))
+}
+
+// failingTB records what it fails with
+type failingTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (t *failingTB) Helper() {}
+
+func (t *failingTB) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *failingTB) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func TestDetectNPlusOne_ShouldFailUntilCleanup(t *testing.T) {
+	ft := &failingTB{}
+	DetectNPlusOne(ft, 5)
+
+	n := qry.CurrentNPlusOne()
+	assert.Equal(t, 5, n.Threshold)
+	n.OnDetect(qry.NPlusOneDetection{Op: "CreateMany", Table: "person", Count: 5, Statements: 6, Shape: "INSERT"})
+	if assert.Len(t, ft.errors, 1) {
+		assert.Contains(t, ft.errors[0], "qrytest: N+1 query: CreateMany on person")
+	}
+
+	for _, f := range ft.cleanups {
+		f()
+	}
+	assert.Equal(t, 0, qry.CurrentNPlusOne().Threshold)
+}
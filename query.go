@@ -1,38 +1,52 @@
 package qry
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/t2wu/qry/datatype"
 	"github.com/t2wu/qry/mdl"
 
 	"github.com/jinzhu/gorm"
 )
 
 // -----------------------------
+// QueryType is the terminal of IQuery, such as First() or Delete()
 type QueryType int
 
 const (
 	QueryTypeFirst QueryType = iota
 	QueryTypeFind
+	QueryTypeTake
+	QueryTypeCount
+	QueryTypeCreate
+	QueryTypeCreateMany
+	QueryTypeDelete
+	QueryTypeDeleteMany
+	QueryTypeSave
+	QueryTypeSaveGraph
+	QueryTypeUpdate
+	QueryTypeUpdateFields
+	QueryTypeUpdateMany
 )
 
 // It would be Q(db, C(...), C(...)...).First() or Q(db).First() with empty PredicateRelationBuilder
 // Use multiple C() when working on inner fields (one C() per struct field)
-func Q(db *gorm.DB, args ...interface{}) IQuery {
-	q := &Query{db: db, saveLck: &sync.Mutex{}}
+// db is a *gorm.DB of either Gorm v1 (github.com/jinzhu/gorm) or Gorm v2 (gorm.io/gorm).
+func Q(db interface{}, args ...interface{}) IQuery {
+	q := &Query{db: newExecutor(db), saveLck: &sync.Mutex{}}
 	return q.Q(args...)
 }
 
 // Instead of Q() directly, we can use DB().Q()
 // This is so it's easier to stubb out when testing
-func DB(db *gorm.DB) IQuery {
+func DB(db interface{}) IQuery {
 	return Q(db) // no argument. That way mainMB would never be null
 }
 
@@ -42,7 +56,7 @@ func DB(db *gorm.DB) IQuery {
 // Query by field name, and prevent SQL injection by making sure that fields are part of the
 // mdl
 type Query struct {
-	db *gorm.DB // Gorm db object can be a transaction
+	db executor // Gorm db object can be a transaction
 
 	// args  []interface{}
 	Err error
@@ -54,6 +68,15 @@ type Query struct {
 	limit  *int // custom limit
 	offset *int // custom offset
 
+	rowsAffected int64       // rows affected by the last create, update or delete
+	returning    interface{} // if set, rows updated or deleted are scanned into it (RETURNING)
+
+	ctx     context.Context // if set, statements run with it
+	timeout *time.Duration  // if set, statements run with a context which times out after it
+
+	logHandler LogHandler // if set, logs to it instead of CurrentLogHandler()
+	dryRun     bool       // within ToSQL
+
 	// This is the temporary fix, what should probably happen is that each call to Query should
 	// create a new Query intance with the state mantained
 	saveLck *sync.Mutex
@@ -71,14 +94,19 @@ func (q *Query) Q(args ...interface{}) IQuery {
 	// q.Q() be re-entrant and many can call at the same time.
 	// So have to return a new IQuery
 
-	q2 := &Query{db: q.db, saveLck: &sync.Mutex{}}
+	q2 := &Query{db: q.db, ctx: q.ctx, timeout: q.timeout, logHandler: q.logHandler, saveLck: &sync.Mutex{}}
+	if q2.db == nil {
+		q2.Err = newBuilderError("db must be a *gorm.DB of Gorm v1 or v2")
+		q2.printFileAndLine(q2.Err)
+		return q2
+	}
 
 	mb := ModelAndBuilder{}
 	for _, arg := range args {
 		b, ok := arg.(*PredicateRelationBuilder)
 		if !ok {
-			q2.Err = fmt.Errorf("incorrect arguments for Q()")
-			PrintFileAndLine(q2.Err)
+			q2.Err = newBuilderError("incorrect arguments for Q()")
+			q2.printFileAndLine(q2.Err)
 			return q2
 		}
 
@@ -99,12 +127,12 @@ func (q *Query) Q(args ...interface{}) IQuery {
 func (q *Query) Order(field string, order Order) IQuery {
 	// func (q *Query) Order(order string) IQuery {
 	if q.order != nil {
-		log.Println("warning: query order already set")
+		q.warn("query order already set")
 	}
 
 	if strings.Contains(field, ".") {
-		q.Err = fmt.Errorf("dot notation in field not supported")
-		PrintFileAndLine(q.Err)
+		q.Err = newBuilderError("dot notation in field not supported")
+		q.printFileAndLine(q.Err)
 		return q
 	}
 
@@ -115,7 +143,7 @@ func (q *Query) Order(field string, order Order) IQuery {
 
 func (q *Query) Limit(limit int) IQuery {
 	if q.limit != nil {
-		log.Println("warning: query limit already set")
+		q.warn("query limit already set")
 	}
 	q.limit = &limit
 	return q
@@ -123,12 +151,48 @@ func (q *Query) Limit(limit int) IQuery {
 
 func (q *Query) Offset(offset int) IQuery {
 	if q.offset != nil {
-		log.Println("warning: query offset already set")
+		q.warn("query offset already set")
 	}
 	q.offset = &offset
 	return q
 }
 
+// Returning scans the rows updated (by Update or UpdateFields) or deleted (by Delete or DeleteMany)
+// into out, which is a pointer to a mdl or a slice of mdls, using "RETURNING".
+// For Create and CreateMany, the rows are read back after created.
+func (q *Query) Returning(out interface{}) IQuery {
+	if q.returning != nil {
+		q.warn("query returning already set")
+	}
+	q.returning = out
+	return q
+}
+
+// WithContext runs the statements of the next terminal with ctx. Once ctx is done, the statement
+// running stops (so does the rest, such as cascading deletes), the transaction if any is rolled
+// back, and the error is context.Canceled or context.DeadlineExceeded.
+func (q *Query) WithContext(ctx context.Context) IQuery {
+	q.ctx = ctx
+	return q
+}
+
+// Timeout runs the statements of the next terminal with a context which times out after d,
+// derived from the one given by WithContext if any
+func (q *Query) Timeout(d time.Duration) IQuery {
+	if q.timeout != nil {
+		q.warn("query timeout already set")
+	}
+	q.timeout = &d
+	return q
+}
+
+// WithLogHandler has the query (and the ones made from it by Q()) log to h instead of
+// CurrentLogHandler()
+func (q *Query) WithLogHandler(h LogHandler) IQuery {
+	q.logHandler = h
+	return q
+}
+
 // args can be multiple C(), each C() works on one-level of modelObj
 // The args are to select the query of modelObj designated, it could work
 // on nested level inside the modelObj
@@ -144,8 +208,8 @@ func (q *Query) InnerJoin(modelObj mdl.IModel, foreignObj mdl.IModel, args ...in
 	var b *PredicateRelationBuilder
 
 	typeName := mdl.GetModelTypeNameFromIModel(foreignObj)
-	tbl := mdl.GetTableNameFromIModel(foreignObj)
-	esc := &Escape{Value: fmt.Sprintf("\"%s\".id", tbl)}
+	tbl := q.db.Dialect().Quote(mdl.GetTableNameFromIModel(foreignObj))
+	esc := &Escape{Value: fmt.Sprintf("%s.id", tbl)}
 
 	// Prepare for PredicateRelationBuilder which will be use to generate inner join statement
 	// between the modelobj at hand and foreignObj (when joining the immediate table, the forignObj is
@@ -153,8 +217,8 @@ func (q *Query) InnerJoin(modelObj mdl.IModel, foreignObj mdl.IModel, args ...in
 	if len(args) > 0 {
 		b, ok = args[0].(*PredicateRelationBuilder)
 		if !ok {
-			q.Err = fmt.Errorf("incorrect arguments for Q()")
-			PrintFileAndLine(q.Err)
+			q.Err = newBuilderError("incorrect arguments for Q()")
+			q.printFileAndLine(q.Err)
 			return q
 		}
 
@@ -185,8 +249,8 @@ func (q *Query) InnerJoin(modelObj mdl.IModel, foreignObj mdl.IModel, args ...in
 	for i := 0; i < len(args); i++ {
 		b, ok := args[i].(*PredicateRelationBuilder)
 		if !ok {
-			q.Err = fmt.Errorf("incorrect arguments for Q()")
-			PrintFileAndLine(q.Err)
+			q.Err = newBuilderError("incorrect arguments for Q()")
+			q.printFileAndLine(q.Err)
 			return q
 		}
 		binfo := BuilderInfo{
@@ -202,6 +266,7 @@ func (q *Query) InnerJoin(modelObj mdl.IModel, foreignObj mdl.IModel, args ...in
 }
 
 func (q *Query) Take(modelObj mdl.IModel) IQuery {
+	defer q.start("Take", modelObj)()
 	defer resetWithoutResetError(q)
 
 	db := q.db
@@ -224,13 +289,14 @@ func (q *Query) Take(modelObj mdl.IModel) IQuery {
 	}
 
 	db = q.buildQueryOrderOffSetAndLimit(db, modelObj)
-	q.setLogger(db)
-	q.Err = db.Take(modelObj).Error
+	db = q.withLogger(db)
+	q.Err = db.Take(modelObj)
 
 	return q
 }
 
 func (q *Query) First(modelObj mdl.IModel) IQuery {
+	defer q.start("First", modelObj)()
 	defer resetWithoutResetError(q)
 
 	db := q.db
@@ -252,13 +318,14 @@ func (q *Query) First(modelObj mdl.IModel) IQuery {
 	}
 
 	db = q.buildQueryOrderOffSetAndLimit(db, modelObj)
-	q.setLogger(db)
-	q.Err = db.First(modelObj).Error
+	db = q.withLogger(db)
+	q.Err = db.First(modelObj)
 
 	return q
 }
 
 func (q *Query) Count(modelObj mdl.IModel, no *int) IQuery {
+	defer q.start("Count", modelObj)()
 	defer resetWithoutResetError(q)
 
 	db := q.db
@@ -280,15 +347,16 @@ func (q *Query) Count(modelObj mdl.IModel, no *int) IQuery {
 	}
 
 	db = q.buildQueryOrderOffSetAndLimit(db, modelObj)
-	q.Err = db.Count(no).Error
+	q.Err = db.Count(no)
 	if q.Err != nil {
-		PrintFileAndLine(q.Err)
+		q.printFileAndLine(q.Err)
 	}
 
 	return q
 }
 
 func (q *Query) Find(modelObjs interface{}) IQuery {
+	defer q.start("Find", modelObjs)()
 	defer resetWithoutResetError(q)
 
 	db := q.db
@@ -326,34 +394,41 @@ loop:
 	}
 
 	db = q.buildQueryOrderOffSetAndLimit(db, modelObj)
-	q.setLogger(db)
-	q.Err = db.Find(modelObjs).Error
+	db = q.withLogger(db)
+	q.Err = db.Find(modelObjs)
 
 	return q
 }
 
 // This is a passover for building query, we're just building the where clause
+// This is only supported on Gorm v1.
 func (q *Query) BuildQuery(modelObj mdl.IModel) (*gorm.DB, error) {
 	defer resetWithoutResetError(q)
 
-	db := q.db
+	g, ok := q.db.(gormV1)
+	if !ok {
+		return nil, newBuilderError("BuildQuery is only supported on Gorm v1")
+	}
 
 	if q.Err != nil {
-		return db, q.Err
+		return g.db, q.Err
 	}
 
+	var db executor = g
 	if q.mainMB != nil {
 		q.mainMB.modelObj = modelObj
 	} else {
 		db = db.Model(modelObj)
 	}
 
-	return q.buildQueryCore(db, modelObj)
+	db, err := q.buildQueryCore(db, modelObj)
+	return db.(gormV1).db, err
 }
 
-func (q *Query) buildQueryCore(db *gorm.DB, modelObj mdl.IModel) (*gorm.DB, error) {
+func (q *Query) buildQueryCore(db executor, modelObj mdl.IModel) (executor, error) {
 	var err error
-	db = buildPreload(db).Model(modelObj)
+	d := db.Dialect()
+	db = db.Preload().Model(modelObj)
 
 	if q.mainMB != nil {
 
@@ -400,13 +475,13 @@ func (q *Query) buildQueryCore(db *gorm.DB, modelObj mdl.IModel) (*gorm.DB, erro
 				// first level, but since this is the other non-nested table
 				// we use a join, and the foriegn key join is already set up
 				// when we call query.Join
-				s, vals, err := rel.BuildQueryStringAndValues(mb.modelObj)
+				s, vals, err := rel.buildQueryStringAndValues(mb.modelObj, d)
 				if err != nil {
 					return db, err
 				}
 
-				tblName := mdl.GetTableNameFromIModel(mb.modelObj)
-				db = db.Joins(fmt.Sprintf("INNER JOIN \"%s\" ON %s", tblName, s), vals...)
+				tblName := d.Quote(mdl.GetTableNameFromIModel(mb.modelObj))
+				db = db.Joins(fmt.Sprintf("INNER JOIN %s ON %s", tblName, s), vals...)
 			}
 		}
 
@@ -419,7 +494,9 @@ func (q *Query) buildQueryCore(db *gorm.DB, modelObj mdl.IModel) (*gorm.DB, erro
 	return db, nil
 }
 
-func (q *Query) buildQueryCoreInnerJoin(db *gorm.DB, mb *ModelAndBuilder) (*gorm.DB, error) {
+func (q *Query) buildQueryCoreInnerJoin(db executor, mb *ModelAndBuilder) (executor, error) {
+	d := db.Dialect()
+
 	// There may not be any builder for the level of join
 	// for example, when querying for 3rd level field, 2nd level also
 	// needs to join with the first level
@@ -439,7 +516,7 @@ func (q *Query) buildQueryCoreInnerJoin(db *gorm.DB, mb *ModelAndBuilder) (*gorm
 			designatedField := rel.GetDesignatedField(mb.modelObj)
 			if designator == designatedField { // OK, with this level we have search criteria to go along with it
 				found = true
-				s, vals, err := rel.BuildQueryStringAndValues(mb.modelObj)
+				s, vals, err := rel.buildQueryStringAndValues(mb.modelObj, d)
 				if err != nil {
 					return db, err
 				}
@@ -456,8 +533,8 @@ func (q *Query) buildQueryCoreInnerJoin(db *gorm.DB, mb *ModelAndBuilder) (*gorm
 					return db, err
 				}
 
-				db = db.Joins(fmt.Sprintf("INNER JOIN \"%s\" ON \"%s\".%s_id = \"%s\".id AND (%s)", tblName, tblName,
-					outerTableName, outerTableName, s), vals...)
+				db = db.Joins(fmt.Sprintf("INNER JOIN %s ON %s.%s_id = %s.id AND (%s)", d.Quote(tblName), d.Quote(tblName),
+					outerTableName, d.Quote(outerTableName), s), vals...)
 			}
 		}
 		if !found { // no search critiria, just pure join statement
@@ -480,9 +557,9 @@ func (q *Query) buildQueryCoreInnerJoin(db *gorm.DB, mb *ModelAndBuilder) (*gorm
 				return db, err
 			}
 
-			db = db.Joins(fmt.Sprintf("INNER JOIN \"%s\" ON \"%s\".%s_id = \"%s\".id",
-				currTableName, currTableName,
-				upperTableName, upperTableName))
+			db = db.Joins(fmt.Sprintf("INNER JOIN %s ON %s.%s_id = %s.id",
+				d.Quote(currTableName), d.Quote(currTableName),
+				upperTableName, d.Quote(upperTableName)))
 		}
 	}
 
@@ -494,7 +571,7 @@ func (q *Query) buildQueryCoreInnerJoin(db *gorm.DB, mb *ModelAndBuilder) (*gorm
 		}
 
 		if !DesignatorContainsDot(rel) { // where clause
-			s, vals, err := rel.BuildQueryStringAndValues(mb.modelObj)
+			s, vals, err := rel.buildQueryStringAndValues(mb.modelObj, d)
 			if err != nil {
 				return db, err
 			}
@@ -506,18 +583,18 @@ func (q *Query) buildQueryCoreInnerJoin(db *gorm.DB, mb *ModelAndBuilder) (*gorm
 	return db, nil
 }
 
-func (q *Query) buildQueryOrderOffSetAndLimit(db *gorm.DB, modelObj mdl.IModel) *gorm.DB {
+func (q *Query) buildQueryOrderOffSetAndLimit(db executor, modelObj mdl.IModel) executor {
 	order := ""
-	tableName := mdl.GetTableNameFromIModel(modelObj)
+	tableName := db.Dialect().Quote(mdl.GetTableNameFromIModel(modelObj))
 	if q.orderField != nil && q.order != nil {
 		col, err := mdl.FieldNameToColumn(modelObj, *q.orderField)
 		if err != nil {
 			q.Err = err
 		}
 
-		order = fmt.Sprintf("\"%s\".%s %s", tableName, col, *q.order)
+		order = fmt.Sprintf("%s.%s %s", tableName, col, *q.order)
 	} else {
-		order = fmt.Sprintf("\"%s\".created_at DESC", tableName) // descending by default
+		order = fmt.Sprintf("%s.created_at DESC", tableName) // descending by default
 	}
 
 	db = db.Order(order)
@@ -528,38 +605,50 @@ func (q *Query) buildQueryOrderOffSetAndLimit(db *gorm.DB, modelObj mdl.IModel)
 
 	if q.limit != nil {
 		db = db.Limit(*q.limit)
+	} else if noLimit := db.Dialect().NoLimit(); q.offset != nil && noLimit != nil {
+		db = db.Limit(noLimit)
 	}
 	return db
 }
 
 func (q *Query) Create(modelObj mdl.IModel) IQuery {
+	defer q.start("Create", modelObj)()
+	returning := q.returning
 	q.Reset() // This shouldn't matter, unless it's a left-over bug
 	defer resetWithoutResetError(q)
 	db := q.db
 
-	if err := RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db, modelObj); err != nil {
+	if err := removeIDForNonPegOrPeggedFieldsBeforeCreate(db, modelObj); err != nil {
 		q.Err = err
 		return q
 	}
 
-	q.setLogger(db)
-	if err := db.Create(modelObj).Error; err != nil {
-		PrintFileAndLine(err)
-		q.Err = err
+	db = q.withLogger(db)
+	rowsAffected, err := db.Create(modelObj)
+	if err != nil {
+		q.printFileAndLine(err)
+		q.Err = translateDBError(modelObj, err)
 		return q
 	}
+	q.rowsAffected = rowsAffected
 
 	// For pegassociated, the since we expect association_autoupdate:false
 	// need to manually create it
-	if err := CreatePeggedAssocFields(db, modelObj); err != nil {
-		q.Err = err
+	if err := createPeggedAssocFields(db, modelObj); err != nil {
+		q.Err = translateDBError(modelObj, err)
 		return q
 	}
 
+	if returning != nil {
+		q.Err = readBackCreated(db, []mdl.IModel{modelObj}, returning)
+	}
+
 	return q
 }
 
 func (q *Query) CreateMany(modelObjs []mdl.IModel) IQuery {
+	defer q.start("CreateMany", modelObjs)()
+	returning := q.returning
 	q.Reset() // This shouldn't matter, unless it's a left-over bug
 	defer resetWithoutResetError(q)
 	db := q.db
@@ -569,16 +658,18 @@ func (q *Query) CreateMany(modelObjs []mdl.IModel) IQuery {
 
 	// TODO: do a batch create instead
 	for _, modelObj := range modelObjs {
-		if err := RemoveIDForNonPegOrPeggedFieldsBeforeCreate(db, modelObj); err != nil {
+		if err := removeIDForNonPegOrPeggedFieldsBeforeCreate(db, modelObj); err != nil {
 			q.Err = err
 			return q
 		}
 
-		q.Err = db.Create(modelObj).Error
+		rowsAffected, err := db.Create(modelObj)
+		q.Err = translateDBError(modelObj, err)
 		if q.Err != nil {
-			PrintFileAndLine(q.Err)
+			q.printFileAndLine(q.Err)
 			return q
 		}
+		q.rowsAffected += rowsAffected
 
 		// if err := gatherModelToCreate(reflect.ValueOf(modelObj).Elem(), &car); err != nil {
 		// 	q.Err = err
@@ -587,26 +678,34 @@ func (q *Query) CreateMany(modelObjs []mdl.IModel) IQuery {
 
 		// For pegassociated, the since we expect association_autoupdate:false
 		// need to manually create it
-		if err := CreatePeggedAssocFields(db, modelObj); err != nil {
-			q.Err = err
+		if err := createPeggedAssocFields(db, modelObj); err != nil {
+			q.Err = translateDBError(modelObj, err)
 			return q
 		}
 	}
 
+	if returning != nil && len(modelObjs) > 0 {
+		q.Err = readBackCreated(db, modelObjs, returning)
+	}
+
 	return q
 }
 
 // Delete can be with criteria, or can just delete the mdl directly
 func (q *Query) Delete(modelObj mdl.IModel) IQuery {
+	defer q.start("Delete", modelObj)()
 	db := q.db
+	returning := q.returning
+	q.returning = nil
+	q.rowsAffected = 0
 
 	if q.Err != nil {
 		return q
 	}
 
-	if modelObj.GetID() == nil && q.mainMB == nil && len(q.mbs) == 0 {
+	if modelObj.GetID() == nil && !q.hasBuilder() {
 		// You could delete every record in the database with Gormv1
-		q.Err = errors.New("delete must have a modelID or include at least one PredicateRelationBuilder")
+		q.Err = &UnsafeDeleteError{Table: mdl.GetTableNameFromIModel(modelObj)}
 		return q
 	}
 
@@ -625,13 +724,25 @@ func (q *Query) Delete(modelObj mdl.IModel) IQuery {
 		return q
 	}
 
-	q.setLogger(db)
-	if err := db.Delete(modelObj).Error; err != nil {
-		q.Err = err
-		return q
+	db = q.withLogger(db)
+	if returning != nil {
+		tblName := db.Dialect().Quote(mdl.GetTableNameFromIModel(modelObj))
+		if modelObj.GetID() != nil {
+			db = db.Where(fmt.Sprintf("%s.id = ?", tblName), modelObj.GetID())
+		}
+		subQueryOfIDs := db.Select(fmt.Sprintf("%s.id", tblName)).SubQuery()
+		if q.rowsAffected, err = deleteMaybeReturning(q.withLogger(q.db), modelObj, returning, subQueryOfIDs); err != nil {
+			q.Err = translateDBError(modelObj, err)
+			return q
+		}
+	} else {
+		if q.rowsAffected, err = db.Delete(modelObj, nil); err != nil {
+			q.Err = translateDBError(modelObj, err)
+			return q
+		}
 	}
 
-	if err := DeleteModelFixManyToManyAndPegAndPegAssoc(db, modelObj); err != nil {
+	if err := deleteModelFixManyToManyAndPegAndPegAssoc(db, modelObj); err != nil {
 		q.Err = err
 		return q
 	}
@@ -640,29 +751,40 @@ func (q *Query) Delete(modelObj mdl.IModel) IQuery {
 }
 
 func (q *Query) DeleteMany(modelObjs []mdl.IModel) IQuery {
+	defer q.start("DeleteMany", modelObjs)()
+	returning := q.returning
 	q.Reset() // needed only if left-over bug
 	defer resetWithoutResetError(q)
 	db := q.db
 
 	// Collect all the ids, non can be nil
-	ids := make([]*datatype.UUID, len(modelObjs))
+	// Gorm needs them as interface{}, otherwise the condition is dropped and every record is deleted
+	ids := make([]interface{}, len(modelObjs))
 	for i, modelObj := range modelObjs {
 		ids[i] = modelObj.GetID()
 		if modelObj.GetID() == nil {
-			q.Err = errors.New("modelObj to delete cannot have an ID of nil")
+			q.Err = newBuilderError("modelObj to delete cannot have an ID of nil")
 			return q
 		}
 	}
 
 	m := reflect.New(reflect.TypeOf(modelObjs[0]).Elem()).Interface().(mdl.IModel)
 	// Batch delete, not documented for Gorm v1 but actually works
-	q.setLogger(db)
-	if q.Err = db.Unscoped().Delete(m, ids).Error; q.Err != nil {
-		return q
+	db = q.withLogger(db)
+	if returning != nil {
+		if q.rowsAffected, q.Err = deleteMaybeReturning(db, m, returning, ids); q.Err != nil {
+			q.Err = translateDBError(m, q.Err)
+			return q
+		}
+	} else {
+		if q.rowsAffected, q.Err = db.Unscoped().Delete(m, ids); q.Err != nil {
+			q.Err = translateDBError(m, q.Err)
+			return q
+		}
 	}
 
 	for _, modelObj := range modelObjs {
-		if err := DeleteModelFixManyToManyAndPegAndPegAssoc(db, modelObj); err != nil {
+		if err := deleteModelFixManyToManyAndPegAndPegAssoc(db, modelObj); err != nil {
 			q.Err = err
 			return q
 		}
@@ -672,6 +794,7 @@ func (q *Query) DeleteMany(modelObjs []mdl.IModel) IQuery {
 }
 
 func (q *Query) Save(modelObj mdl.IModel) IQuery {
+	defer q.start("Save", modelObj)()
 	q.saveLck.Lock()
 	defer q.saveLck.Unlock()
 
@@ -680,36 +803,62 @@ func (q *Query) Save(modelObj mdl.IModel) IQuery {
 		return q
 	}
 
-	q.Err = q.db.Save(modelObj).Error
+	if anyVersioned(modelsSavedWith(modelObj)) {
+		q.Err = q.db.Transaction(func(tx executor) error {
+			return saveModelCheckVersion(tx, modelObj)
+		})
+	} else {
+		q.Err = q.db.Save(modelObj)
+	}
 	if q.Err != nil {
-		PrintFileAndLine(q.Err)
+		q.printFileAndLine(q.Err)
+		q.Err = translateDBError(modelObj, q.Err)
 	}
 	return q
 }
 
-// Update only allow one level of builder
-func (q *Query) Update(modelObj mdl.IModel, p *PredicateRelationBuilder) IQuery {
-	defer resetWithoutResetError(q)
+// SaveGraph saves modelObj and syncs its pegged and pegassoc fields with what is in the database:
+// pegged elements no longer in modelObj are deleted (cascading like Delete), new ones created and
+// existing ones updated, pegassoc elements no longer in modelObj are dissociated and new ones
+// pointed to modelObj. Everything runs in one transaction.
+func (q *Query) SaveGraph(modelObj mdl.IModel) IQuery {
+	defer q.start("SaveGraph", modelObj)()
+	q.saveLck.Lock()
+	defer q.saveLck.Unlock()
 
+	defer resetWithoutResetError(q)
 	if q.Err != nil {
 		return q
 	}
 
-	if q.mainMB != nil {
-		q.mainMB.modelObj = modelObj
+	if modelObj.GetID() == nil {
+		q.Err = newBuilderError("save graph must have a modelID")
+		return q
 	}
 
 	db := q.db
+	db = q.withLogger(db)
+	q.Err = db.Transaction(func(tx executor) error {
+		return saveModelSyncPegAndPegAssoc(tx, modelObj)
+	})
+	if q.Err != nil {
+		q.printFileAndLine(q.Err)
+		q.Err = translateDBError(modelObj, q.Err)
+	}
+	return q
+}
 
-	// Won't work, builtqueryCore has "ORDER BY Clause"
-	var err error
-	db, err = q.buildQueryCore(db, modelObj)
-	if err != nil {
-		q.Err = err
+// Update only allow one level of builder
+// p is a list of "=" joined by And, such as C("Age =", 3).And("Name =", "same")
+func (q *Query) Update(modelObj mdl.IModel, p *PredicateRelationBuilder) IQuery {
+	defer q.start("Update", modelObj)()
+	defer resetWithoutResetError(q)
+	q.rowsAffected = 0
+
+	if q.Err != nil {
 		return q
 	}
 
-	updateMap := make(map[string]interface{})
 	rel, err := p.GetPredicateRelation()
 	if err != nil {
 		q.Err = err
@@ -718,40 +867,338 @@ func (q *Query) Update(modelObj mdl.IModel, p *PredicateRelationBuilder) IQuery
 
 	field2Struct, _ := FindFieldNameToStructAndStructFieldNameIfAny(rel) // hacky
 	if field2Struct != nil {
-		q.Err = fmt.Errorf("dot notation in update")
-		PrintFileAndLine(q.Err)
+		q.Err = newBuilderError("dot notation in update")
+		q.printFileAndLine(q.Err)
 		return q
 	}
 
-	qstr, values, err := rel.BuildQueryStringAndValues(modelObj)
+	fields, err := updateFieldsFromPredicateRelation(rel)
 	if err != nil {
 		q.Err = err
 		return q
 	}
 
-	toks := strings.Split(qstr, " = ?")
+	q.rowsAffected, q.Err = q.updateFieldsCore(q.withLogger(q.db), modelObj, fields)
+	q.Err = translateDBError(modelObj, q.Err)
 
-	for i, tok := range toks[:len(toks)-1] { // last tok is anempty str
-		s := strings.Split(tok, ".")[1] // strip away the table name
-		updateMap[s] = values[i]
+	return q
+}
+
+// UpdateFields updates the fields (which can be nested, such as "Dogs.Color") to the values given
+// on mdls selected by the query (or modelObj itself if it has an ID).
+// A value can also be an UpdateExpr, such as Inc("Age", 1) or Now().
+// The number of rows updated is reported by RowsAffected().
+func (q *Query) UpdateFields(modelObj mdl.IModel, fields map[string]interface{}) IQuery {
+	defer q.start("UpdateFields", modelObj)()
+	defer resetWithoutResetError(q)
+	q.rowsAffected = 0
+
+	if q.Err != nil {
+		return q
+	}
+
+	if modelObj.GetID() == nil && !q.hasBuilder() {
+		// Otherwise every record in the table would be updated
+		q.Err = &UnsafeUpdateError{Table: mdl.GetTableNameFromIModel(modelObj)}
+		return q
+	}
+
+	q.rowsAffected, q.Err = q.updateFieldsCore(q.withLogger(q.db), modelObj, fields)
+	if q.Err != nil {
+		q.printFileAndLine(q.Err)
+		q.Err = translateDBError(modelObj, q.Err)
+	}
+
+	return q
+}
+
+// UpdateMany updates fields of each mdl to the values within that mdl, in one statement
+// per chunk instead of one Save() per mdl. Nested fields and more than one mdl of the same ID are
+// not supported.
+// The number of rows updated is reported by RowsAffected().
+func (q *Query) UpdateMany(modelObjs []mdl.IModel, fields ...string) IQuery {
+	defer q.start("UpdateMany", modelObjs)()
+	defer resetWithoutResetError(q)
+	q.rowsAffected = 0
+
+	if q.Err != nil || len(modelObjs) == 0 {
+		return q
 	}
 
-	q.setLogger(db)
-	q.Err = db.Update(updateMap).Error
+	db := q.db
+	db = q.withLogger(db)
+	q.Err = db.Transaction(func(tx executor) error {
+		var err error
+		q.rowsAffected, err = updateManyCore(tx, modelObjs, fields)
+		return err
+	})
+	if q.Err != nil {
+		q.rowsAffected = 0
+		q.printFileAndLine(q.Err)
+		q.Err = translateDBError(modelObjs[0], q.Err)
+	}
 
 	return q
 }
 
+// updateFieldsCore updates fields on the mdls selected by the query (or modelObj itself if it has
+// an ID), every one of the table if neither
+func (q *Query) updateFieldsCore(db executor, modelObj mdl.IModel, fields map[string]interface{}) (int64, error) {
+	// Only when updating the mdl by ID, otherwise no rows updated could be because of the criteria
+	checkVersion := modelObj.GetID() != nil && !q.hasBuilder()
+
+	subQueryOfIDs, err := q.buildSubQueryOfIDs(modelObj)
+	if err != nil {
+		return 0, err
+	}
+	nestedCriteria, err := q.nestedCriteria(modelObj)
+	if err != nil {
+		return 0, err
+	}
+
+	var rowsAffected int64
+	err = db.Transaction(func(tx executor) error {
+		var err error
+		rowsAffected, err = updateFieldsCore(tx, modelObj, subQueryOfIDs, fields, nestedCriteria, checkVersion, q.returning)
+		return err
+	})
+	return rowsAffected, err
+}
+
+// nestedCriteria returns the criteria of the query on the mdls nested within modelObj by their
+// designator (such as "Dogs"), which are joined on when selecting modelObj
+func (q *Query) nestedCriteria(modelObj mdl.IModel) (map[string][]*PredicateRelation, error) {
+	nestedCriteria := make(map[string][]*PredicateRelation)
+	if q.mainMB == nil {
+		return nestedCriteria, nil
+	}
+	for _, buildInfo := range q.mainMB.builderInfos {
+		rel, err := buildInfo.builder.GetPredicateRelation()
+		if err != nil {
+			return nil, err
+		}
+		if designator := rel.GetDesignatedField(modelObj); designator != "" {
+			nestedCriteria[designator] = append(nestedCriteria[designator], rel)
+		}
+	}
+	return nestedCriteria, nil
+}
+
+// buildSubQueryOfIDs builds "SELECT id FROM ..." of modelObj selected by the query
+func (q *Query) buildSubQueryOfIDs(modelObj mdl.IModel) (interface{}, error) {
+	db := q.db
+	if q.mainMB != nil {
+		q.mainMB.modelObj = modelObj
+	}
+
+	db, err := q.buildQueryCore(db, modelObj)
+	if err != nil {
+		return nil, err
+	}
+
+	tblName := db.Dialect().Quote(mdl.GetTableNameFromIModel(modelObj))
+	if modelObj.GetID() != nil {
+		db = db.Where(fmt.Sprintf("%s.id = ?", tblName), modelObj.GetID())
+	}
+
+	return db.Select(fmt.Sprintf("%s.id", tblName)).SubQuery(), nil
+}
+
+// ToSQL renders the statements the terminal of kind would run on modelObj, without running them.
+// Terminals which take more than modelObj take the rest in args: Update takes
+// a *PredicateRelationBuilder, UpdateFields takes a map[string]interface{}, and UpdateMany takes
+// the field names. CreateMany, DeleteMany and UpdateMany take more mdls in args as well.
+// When there is more than one statement (such as cascading deletes), they are joined with ";\n"
+// and so are their args. Since nothing is read from the database in a dry run, statements which
+// depend on what's read are not rendered, and SaveGraph is not supported.
+func (q *Query) ToSQL(modelObj mdl.IModel, kind QueryType, args ...interface{}) (string, []interface{}, error) {
+	if q.Err != nil {
+		resetWithoutResetError(q)
+		return "", nil, q.Error()
+	}
+
+	conn, rec, done, err := newDryRunConn()
+	if err != nil {
+		resetWithoutResetError(q)
+		return "", nil, err
+	}
+	defer done()
+
+	q.saveLck.Lock()
+	realDB := q.db
+	q.db, q.dryRun = realDB.WithConn(conn), true
+	q.saveLck.Unlock()
+	defer func() {
+		q.saveLck.Lock()
+		q.db, q.dryRun = realDB, false
+		q.saveLck.Unlock()
+	}()
+
+	// The terminal may change it, such as ID assigned on create
+	modelObj = copyModel(modelObj)
+	modelObjs := []mdl.IModel{modelObj}
+	fields := make([]string, 0)
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case mdl.IModel:
+			modelObjs = append(modelObjs, copyModel(a))
+		case string:
+			fields = append(fields, a)
+		}
+	}
+
+	switch kind {
+	case QueryTypeFirst:
+		q.First(modelObj)
+	case QueryTypeFind:
+		q.Find(reflect.New(reflect.SliceOf(reflect.TypeOf(modelObj).Elem())).Interface())
+	case QueryTypeTake:
+		q.Take(modelObj)
+	case QueryTypeCount:
+		var no int
+		q.Count(modelObj, &no)
+	case QueryTypeCreate:
+		q.Create(modelObj)
+	case QueryTypeCreateMany:
+		q.CreateMany(modelObjs)
+	case QueryTypeDelete:
+		q.Delete(modelObj)
+	case QueryTypeDeleteMany:
+		q.DeleteMany(modelObjs)
+	case QueryTypeSave:
+		q.Save(modelObj)
+	case QueryTypeUpdate:
+		var p *PredicateRelationBuilder
+		if len(args) > 0 {
+			p, _ = args[0].(*PredicateRelationBuilder)
+		}
+		if p == nil {
+			resetWithoutResetError(q)
+			return "", nil, newBuilderError("ToSQL of update must have a PredicateRelationBuilder")
+		}
+		q.Update(modelObj, p)
+	case QueryTypeUpdateFields:
+		var fieldsToUpdate map[string]interface{}
+		if len(args) > 0 {
+			fieldsToUpdate, _ = args[0].(map[string]interface{})
+		}
+		q.UpdateFields(modelObj, fieldsToUpdate)
+	case QueryTypeUpdateMany:
+		q.UpdateMany(modelObjs, fields...)
+	default: // QueryTypeSaveGraph
+		resetWithoutResetError(q)
+		return "", nil, newBuilderError("ToSQL does not support this query type")
+	}
+
+	// Nothing is read from the database, so not found (or no row to count) is expected
+	err = q.Error()
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, sql.ErrNoRows) {
+		return "", nil, err
+	}
+	statements := rec.getStatements()
+	if len(statements) == 0 {
+		if err == nil {
+			err = newBuilderError("nothing to render")
+		}
+		return "", nil, err
+	}
+
+	stmt, vars := joinStatements(statements)
+	return stmt, vars, nil
+}
+
+func copyModel(modelObj mdl.IModel) mdl.IModel {
+	return deepCopy(reflect.ValueOf(modelObj), make(map[copiedPtr]reflect.Value)).Interface().(mdl.IModel)
+}
+
+// copiedPtr is a pointer already copied by deepCopy
+type copiedPtr struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// deepCopy returns a copy of v which shares no pointer, slice or map with it, so what's nested
+// is not changed either. A pointer already copied (in copied) is copied to the same one.
+func deepCopy(v reflect.Value, copied map[copiedPtr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := copiedPtr{typ: v.Type(), ptr: v.Pointer()}
+		if c, ok := copied[key]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		copied[key] = c
+		c.Elem().Set(deepCopy(v.Elem(), copied))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value(), copied))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), copied))
+		return c
+	case reflect.Struct:
+		// Unexported fields (such as of time.Time) are as they are
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i), copied))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// GetDB returns the Gorm v1 db, or nil on Gorm v2
 func (q *Query) GetDB() *gorm.DB {
-	return q.db
+	if g, ok := q.db.(gormV1); ok {
+		return g.db
+	}
+	return nil
 }
 
 func (q *Query) Reset() IQuery {
 	q.Err = nil
+	q.rowsAffected = 0
 	resetWithoutResetError(q)
 	return q
 }
 
+// RowsAffected is the number of rows affected by the last create, update or delete
+func (q *Query) RowsAffected() int64 {
+	return q.rowsAffected
+}
+
 func (q *Query) Error() error {
 	resetWithoutResetError(q)
 	err := q.Err
@@ -759,13 +1206,98 @@ func (q *Query) Error() error {
 	return err
 }
 
-func (q *Query) setLogger(db *gorm.DB) {
-	_, filepath, line, ok := runtime.Caller(2)
-	var source string
-	if ok {
-		source = fmt.Sprintf("%s:%d", filepath, line)
+// start runs q.db with the context given by WithContext and Timeout, and the terminal run which
+// its statements are reported to, until the function returned is called, which then has the
+// error be the context's if it's done. The sensitive fields of modelObjs are redacted from logs.
+// The query is changed under saveLck, since Save and SaveGraph can be called on it concurrently
+// (they lock it after start, and unlock it before the function returned is called).
+func (q *Query) start(op string, modelObjs interface{}) func() {
+	redactSensitiveFields(modelObjs)
+
+	q.saveLck.Lock()
+	defer q.saveLck.Unlock()
+
+	ctx, timeout := q.ctx, q.timeout
+	q.ctx, q.timeout = nil, nil // for this terminal only
+
+	var run *terminalRun
+	if !q.dryRun && observed() {
+		run = newTerminalRun(op, modelObjs, q.getLogHandler())
+	}
+	if q.db == nil || (ctx == nil && timeout == nil && run == nil) {
+		return func() {}
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cancel := func() {}
+	if timeout != nil {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+	}
+	if run != nil {
+		ctx = withTerminalRun(ctx, run)
 	}
-	db.SetLogger(NewLogger(source))
+
+	db := q.db
+	q.db = db.WithContext(ctx)
+	return func() {
+		q.saveLck.Lock()
+		if q.Err != nil && ctx.Err() != nil {
+			q.Err = ctx.Err()
+		}
+		q.db = db
+		q.saveLck.Unlock()
+
+		cancel()
+		if run != nil {
+			run.end(db)
+		}
+	}
+}
+
+// hasBuilder is whether the query has criteria
+func (q *Query) hasBuilder() bool {
+	return len(q.mbs) > 0 || (q.mainMB != nil && len(q.mainMB.builderInfos) > 0)
+}
+
+// withLogger returns db with the logger of the query, which is of the caller of the terminal
+func (q *Query) withLogger(db executor) executor {
+	return db.WithLogger(newLogger(callerSource(2), q.getLogHandler()))
+}
+
+// callerSource is file:line of the caller skip frames up from the function calling it, such as
+// the query of a terminal, "" if it's not known. A TypedQuery is skipped for the query of it.
+func callerSource(skip int) string {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for n > 0 {
+		frame, more := frames.Next()
+		if !more || !strings.HasPrefix(frame.Function, typedQueryFuncPrefix) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+	}
+	return ""
+}
+
+// typedQueryFuncPrefix is the prefix of the names of the methods of TypedQuery
+var typedQueryFuncPrefix = reflect.TypeOf(Query{}).PkgPath() + ".(*TypedQuery["
+
+func (q *Query) getLogHandler() LogHandler {
+	if q.logHandler != nil {
+		return q.logHandler
+	}
+	return CurrentLogHandler()
+}
+
+func (q *Query) warn(msg string) {
+	handle(q.getLogHandler(), Entry{Level: LevelWarn, Message: msg})
+}
+
+// printFileAndLine is PrintFileAndLine to the handler of q
+func (q *Query) printFileAndLine(err error) {
+	printFileAndLine(q.getLogHandler(), err)
 }
 
 // ------------------
@@ -775,10 +1307,6 @@ type TableAndArgs struct {
 	Args    []interface{}
 }
 
-func buildPreload(tx *gorm.DB) *gorm.DB {
-	return tx.Set("gorm:auto_preload", true)
-}
-
 // hacky...
 func FindFieldNameToStructAndStructFieldNameIfAny(rel *PredicateRelation) (*string, *string) {
 	for _, pr := range rel.PredOrRels {
@@ -823,6 +1351,9 @@ func resetWithoutResetError(q *Query) {
 	q.orderField = nil
 	q.limit = nil
 	q.offset = nil
+	q.returning = nil
+	q.ctx = nil
+	q.timeout = nil
 
 	q.mbs = make([]ModelAndBuilder, 0)
 	q.mainMB = nil
@@ -0,0 +1,136 @@
+package qry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithContext_WhenLive_ShouldRunAsUsual(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tm := TestModel{Name: "first", Age: 1}
+	if err := DB(tx).WithContext(ctx).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	searched := TestModel{}
+	err := Q(tx, C("ID =", tm.ID)).WithContext(ctx).Timeout(time.Minute).First(&searched).Error()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "first", searched.Name)
+	}
+}
+
+func TestWithContext_WhenCanceled_ShouldGiveCanceledAndLeaveRecordsIntact(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm := TestModel{
+		BaseModel:   mdl.BaseModel{ID: datatype.NewUUID()},
+		Name:        "first",
+		Age:         1,
+		FavoriteDog: Dog{Name: "Buddy", Color: "black"},
+	}
+	if err := DB(tx).Create(&tm).Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := DB(tx).WithContext(ctx).Delete(&tm).Error()
+	assert.True(t, errors.Is(err, context.Canceled), err)
+
+	// The context is for one terminal only
+	q := Q(tx, C("ID =", tm.ID))
+	assert.NotNil(t, q.WithContext(ctx).Find(&[]TestModel{}).Error())
+	if assert.Nil(t, q.Q(C("ID =", tm.ID)).First(&TestModel{}).Error()) {
+		assert.Nil(t, Q(tx, C("ID =", tm.FavoriteDog.ID)).First(&Dog{}).Error())
+	}
+}
+
+func TestTimeout_WhenExpired_ShouldGiveDeadlineExceeded(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	var no int
+	err := DB(tx).Timeout(-time.Second).Count(&TestModel{}, &no).Error()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestWithContext_Transaction_WhenCanceled_ShouldGiveCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Versioned Save runs in a transaction of its own, which can't begin
+	vm := VersionedModel{BaseModel: mdl.BaseModel{ID: datatype.NewUUID()}, Name: "first"}
+	err := DB(db).WithContext(ctx).Save(&vm).Error()
+	assert.True(t, errors.Is(err, context.Canceled), err)
+
+	err = Q(db, C("ID =", vm.ID)).First(&VersionedModel{}).Error()
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+}
+
+func TestGormV2_WithContext_WhenCanceled_ShouldGiveCanceled(t *testing.T) {
+	dbv2 := openGormV2(t)
+	tm := createGormV2TestModel(t, dbv2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Q(dbv2).WithContext(ctx).SaveGraph(tm).Error()
+	assert.True(t, errors.Is(err, context.Canceled), err)
+
+	err = Q(dbv2).WithContext(ctx).Delete(tm).Error()
+	assert.True(t, errors.Is(err, context.Canceled), err)
+
+	searched := TestModel{}
+	if err := Q(dbv2, C("ID =", tm.ID)).Timeout(time.Minute).First(&searched).Error(); assert.Nil(t, err) {
+		assert.Len(t, searched.Dogs, 2)
+	}
+}
+
+func TestSave_ConcurrentlyOnOneQueryWhileObserved_ShouldSaveEveryOne(t *testing.T) {
+	useHooks(t, &recordingHook{})
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tms := make([]TestModel, 4)
+	for i := range tms {
+		tms[i] = TestModel{Name: "concurrent", Age: i}
+		if err := DB(tx).Create(&tms[i]).Error(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The db of the query is swapped for each terminal while another one could be saving
+	// (the error of the query is of whichever one is last, so the rows are checked instead)
+	q := DB(tx)
+	var wg sync.WaitGroup
+	for i := range tms {
+		wg.Add(1)
+		go func(tm *TestModel) {
+			defer wg.Done()
+			tm.Age += 10
+			q.Save(tm)
+		}(&tms[i])
+	}
+	wg.Wait()
+
+	var count int
+	if assert.Nil(t, Q(tx, C("Name =", "concurrent").And("Age >=", 10)).Count(&TestModel{}, &count).Error()) {
+		assert.Equal(t, len(tms), count)
+	}
+}
@@ -1,6 +1,7 @@
 package qry
 
 import (
+	"errors"
 	"log"
 	"testing"
 
@@ -549,3 +550,40 @@ func TestBatchCreate_PeggAssociateArray_shouldAssociateCorrectly(t *testing.T) {
 		}
 	}
 }
+
+func TestCreate_ShouldGiveRowsAffected_AndReturning(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	created := TestModel{}
+	tm := TestModel{Name: "created", Age: 7}
+	q := Q(tx).Returning(&created)
+	if err := q.Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), q.RowsAffected())
+	assert.Equal(t, tm.ID.String(), created.ID.String())
+	assert.Equal(t, 7, created.Age)
+}
+
+func TestCreate_PeggedArray_WithExistingID_ShouldGiveDuplicatePeggedIDError(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	doguuid := datatype.NewUUID()
+	tm1 := TestModel{Name: "MyTestModel", Age: 1, Dogs: []Dog{{BaseModel: mdl.BaseModel{ID: doguuid}, Name: "Buddy"}}}
+	if err := Q(tx).Create(&tm1).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	tm2 := TestModel{Name: "MyTestModel", Age: 1, Dogs: []Dog{{BaseModel: mdl.BaseModel{ID: doguuid}, Name: "Buddy"}}}
+	err := Q(tx).Create(&tm2).Error()
+
+	var dupErr *DuplicatePeggedIDError
+	if assert.True(t, errors.As(err, &dupErr)) {
+		assert.Equal(t, "dog", dupErr.Table)
+		if assert.Equal(t, 1, len(dupErr.IDs)) {
+			assert.Equal(t, doguuid.String(), dupErr.IDs[0].String())
+		}
+	}
+}
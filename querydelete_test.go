@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/dialect"
 	"github.com/t2wu/qry/mdl"
 
 	"github.com/jinzhu/gorm"
@@ -446,3 +447,170 @@ func TestDelete_criteria_works(t *testing.T) {
 
 	assert.Equal(t, 1, len(tms), "The one in setup() should still be left intact")
 }
+
+func TestDelete_criteria_ShouldGiveRowsAffected(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm1 := TestModel{Name: "MyTestModel", Age: 1}
+	tm2 := TestModel{Name: "MyTestModel", Age: 1}
+	if err := Q(tx).CreateMany([]mdl.IModel{&tm1, &tm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	q := Q(tx, C("Name =", "MyTestModel"))
+	if err := q.Delete(&TestModel{}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), q.RowsAffected())
+}
+
+func TestDelete_criteria_Returning_ShouldGiveDeletedRows(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm1 := TestModel{Name: "MyTestModel", Age: 1}
+	tm2 := TestModel{Name: "MyTestModel", Age: 2}
+	if err := Q(tx).CreateMany([]mdl.IModel{&tm1, &tm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	deleted := make([]TestModel, 0)
+	q := Q(tx, C("Name =", "MyTestModel").And("Age =", 2)).Returning(&deleted)
+	if err := q.Delete(&TestModel{}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), q.RowsAffected())
+	if assert.Equal(t, 1, len(deleted)) {
+		assert.Equal(t, tm2.ID.String(), deleted[0].ID.String())
+		assert.Equal(t, 2, deleted[0].Age)
+	}
+}
+
+func TestBatchDelete_Returning_ShouldGiveDeletedRows(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm1 := TestModel{Name: "MyTestModel", Age: 1}
+	tm2 := TestModel{Name: "MyTestModel", Age: 2}
+	if err := Q(tx).CreateMany([]mdl.IModel{&tm1, &tm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	deleted := make([]TestModel, 0)
+	q := Q(tx).Returning(&deleted)
+	if err := q.DeleteMany([]mdl.IModel{&tm1, &tm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), q.RowsAffected())
+	assert.Equal(t, 2, len(deleted))
+}
+
+// noReturning is the dialect of the test db without RETURNING (as MySQL), so the rows affected
+// are read separately
+type noReturning struct {
+	dialect.Dialect
+}
+
+func (noReturning) Returning() bool {
+	return false
+}
+
+func withoutReturning(t *testing.T) {
+	d := dialect.Of(db)
+	dialect.Register(noReturning{d})
+	t.Cleanup(func() { dialect.Register(d) })
+}
+
+func TestDelete_criteria_ReturningWithoutReturning_ShouldGiveDeletedRows(t *testing.T) {
+	withoutReturning(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm1 := TestModel{Name: "MyTestModel", Age: 1}
+	tm2 := TestModel{Name: "MyTestModel", Age: 2}
+	if err := Q(tx).CreateMany([]mdl.IModel{&tm1, &tm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	deleted := make([]TestModel, 0)
+	q := Q(tx, C("Name =", "MyTestModel").And("Age =", 2)).Returning(&deleted)
+	if err := q.Delete(&TestModel{}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), q.RowsAffected())
+	if assert.Equal(t, 1, len(deleted)) {
+		assert.Equal(t, tm2.ID.String(), deleted[0].ID.String())
+		assert.Equal(t, 2, deleted[0].Age)
+	}
+
+	tms := make([]TestModel, 0)
+	if err := Q(tx, C("Name =", "MyTestModel")).Find(&tms).Error(); assert.Nil(t, err) && assert.Equal(t, 1, len(tms)) {
+		assert.Equal(t, tm1.ID.String(), tms[0].ID.String())
+	}
+}
+
+func TestBatchDelete_ReturningWithoutReturning_ShouldGiveDeletedRows(t *testing.T) {
+	withoutReturning(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm1 := TestModel{Name: "MyTestModel", Age: 1}
+	tm2 := TestModel{Name: "MyTestModel", Age: 2}
+	if err := Q(tx).CreateMany([]mdl.IModel{&tm1, &tm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	deleted := make([]TestModel, 0)
+	q := Q(tx).Returning(&deleted)
+	if err := q.DeleteMany([]mdl.IModel{&tm1, &tm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), q.RowsAffected())
+	assert.Equal(t, 2, len(deleted))
+}
+
+func TestDelete_WithoutIDOrCriteria_ShouldGiveUnsafeDeleteError(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	err := Q(tx).Delete(&TestModel{}).Error()
+	var unsafeErr *UnsafeDeleteError
+	if assert.True(t, errors.As(err, &unsafeErr)) {
+		assert.Equal(t, "test_model", unsafeErr.Table)
+	}
+
+	tms := make([]TestModel, 0)
+	if err := Q(tx).Find(&tms).Error(); assert.Nil(t, err) {
+		assert.NotEqual(t, 0, len(tms), "nothing should have been deleted")
+	}
+}
+
+func TestFirst_NothingFound_ShouldGiveErrNotFound(t *testing.T) {
+	err := Q(db, C("Name =", "does not exist")).First(&TestModel{}).Error()
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestBatchDelete_ShouldOnlyDeleteTheOnesGiven(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm1 := TestModel{Name: "MyTestModel", Age: 1}
+	tm2 := TestModel{Name: "MyTestModel", Age: 2}
+	if err := Q(tx).CreateMany([]mdl.IModel{&tm1, &tm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	q := Q(tx)
+	if err := q.DeleteMany([]mdl.IModel{&tm1}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), q.RowsAffected())
+
+	tms := make([]TestModel, 0)
+	if err := Q(tx, C("Name =", "MyTestModel")).Find(&tms).Error(); assert.Nil(t, err) {
+		if assert.Equal(t, 1, len(tms)) {
+			assert.Equal(t, tm2.ID.String(), tms[0].ID.String())
+		}
+	}
+}
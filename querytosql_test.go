@@ -0,0 +1,104 @@
+package qry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToSQL_Find_ShouldRenderWithoutRunning(t *testing.T) {
+	stmt, vars, err := Q(db, C("Name =", "same")).ToSQL(&TestModel{}, QueryTypeFind)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Contains(t, stmt, "SELECT * FROM \"test_model\"")
+	assert.Contains(t, stmt, "\"test_model\".real_name_column =")
+	assert.Equal(t, []interface{}{"same"}, vars)
+}
+
+func TestToSQL_Count_ShouldRenderCount(t *testing.T) {
+	stmt, _, err := Q(db, C("Age >", 3)).ToSQL(&TestModel{}, QueryTypeCount)
+	if assert.Nil(t, err) {
+		assert.Contains(t, stmt, "SELECT count(*) FROM \"test_model\"")
+	}
+}
+
+func TestToSQL_Delete_ShouldNotDelete(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	stmt, vars, err := Q(tx, C("Name =", "same")).ToSQL(&TestModel{}, QueryTypeDelete)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Contains(t, stmt, "DELETE FROM \"test_model\"")
+	assert.Equal(t, "same", vars[0])
+
+	tms := make([]TestModel, 0)
+	if err := Q(tx, C("Name =", "same")).Find(&tms).Error(); assert.Nil(t, err) {
+		assert.Equal(t, 3, len(tms), "nothing should have been deleted")
+	}
+}
+
+func TestToSQL_UpdateFields_ShouldRenderNestedUpdate(t *testing.T) {
+	fields := map[string]interface{}{"Dogs.Color": "purple"}
+	stmt, vars, err := Q(db, C("Name =", "same")).ToSQL(&TestModel{}, QueryTypeUpdateFields, fields)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Contains(t, stmt, "UPDATE \"dog\" SET color =")
+	assert.Equal(t, "purple", vars[0])
+}
+
+func TestToSQL_Create_ShouldNotChangeModelObj(t *testing.T) {
+	tm := TestModel{Name: "new"}
+	tm.Dogs = []Dog{{Name: "new dog"}}
+	tm.EvilDog = &Dog{Name: "new evil dog"}
+	stmt, _, err := Q(db).ToSQL(&tm, QueryTypeCreate)
+	if assert.Nil(t, err) {
+		assert.Contains(t, stmt, "INSERT INTO \"test_model\"")
+		assert.Contains(t, stmt, "INSERT INTO \"dog\"")
+	}
+	assert.Nil(t, tm.ID)
+	assert.Nil(t, tm.Dogs[0].ID)
+	assert.Nil(t, tm.EvilDog.ID)
+}
+
+// afterCreateFailing is a mdl which fails after it's created
+type afterCreateFailing struct {
+	mdl.BaseModel
+	Name string
+}
+
+var errAfterCreate = errors.New("after create")
+
+func (m *afterCreateFailing) AfterCreate() error {
+	return errAfterCreate
+}
+
+func TestToSQL_WhenFailedAfterRendered_ShouldGiveTheError(t *testing.T) {
+	_, _, err := Q(db).ToSQL(&afterCreateFailing{Name: "new"}, QueryTypeCreate)
+	assert.True(t, errors.Is(err, errAfterCreate), err)
+}
+
+func TestSetSQLCommon_ShouldBeSupportedByGorm(t *testing.T) {
+	assert.Nil(t, errSQLCommonField)
+}
+
+func TestToSQL_DeleteMany_ShouldOnlyDeleteTheIDs(t *testing.T) {
+	tm := TestModel{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(uuid4)}}
+	stmt, _, err := Q(db).ToSQL(&tm, QueryTypeDeleteMany)
+	if assert.Nil(t, err) {
+		assert.Contains(t, stmt, "\"test_model\".\"id\" IN")
+	}
+}
+
+func TestToSQL_InvalidField_ShouldGiveAnError(t *testing.T) {
+	_, _, err := Q(db, C("Bogus =", "same")).ToSQL(&TestModel{}, QueryTypeFind)
+	assert.Error(t, err)
+}
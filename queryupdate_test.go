@@ -1,8 +1,12 @@
 package qry
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -52,6 +56,33 @@ func TestUpdate_Field_Works(t *testing.T) {
 	assert.Equal(t, 120, check.Age)
 }
 
+func TestUpdate_WithoutIDOrCriteria_ShouldUpdateEveryRecord(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	q := DB(tx)
+	if err := q.Update(&TestModel{}, C("Age =", 120)).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(5), q.RowsAffected())
+
+	var count int
+	if assert.Nil(t, Q(tx, C("Age =", 120)).Count(&TestModel{}, &count).Error()) {
+		assert.Equal(t, 5, count)
+	}
+}
+
+func TestUpdateFields_WithoutIDOrCriteria_ShouldGiveUnsafeUpdateError(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	err := DB(tx).UpdateFields(&TestModel{}, map[string]interface{}{"Age": 120}).Error()
+	var unsafeErr *UnsafeUpdateError
+	if assert.True(t, errors.As(err, &unsafeErr)) {
+		assert.Equal(t, "test_model", unsafeErr.Table)
+	}
+}
+
 func TestUpdate_NestedField_ShouldGiveWarning(t *testing.T) {
 	// Name:        "Doggie2",
 	if err := Q(db, C("Name =", "second")).Update(&TestModel{}, C("Dogs.Color =", "purple")).Error(); err != nil {
@@ -62,4 +93,574 @@ func TestUpdate_NestedField_ShouldGiveWarning(t *testing.T) {
 	assert.Fail(t, "should not be here")
 }
 
-// Batch create and delete operations
+func TestSaveGraph_PeggedArray_ShouldCreateUpdateAndDelete(t *testing.T) {
+	uuid := "046bcadb-7127-47b1-9c1e-ff92ccea44b8"
+	doguuid1 := "919b7d4b-35fd-43a9-b707-78a874870f16"
+	doguuid2 := "0bc6e7b5-5d1c-4e33-a8f2-3c0b4f0e5a3d"
+	tm := TestModel{BaseModel: mdl.BaseModel{
+		ID: datatype.NewUUIDFromStringNoErr(uuid)},
+		Name: "MyTestModel",
+		Age:  1,
+		Dogs: []Dog{
+			{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(doguuid1)}, Name: "Buddy", Color: "black"},
+			{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(doguuid2)}, Name: "Max", Color: "white"},
+		},
+	}
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	if err := Q(tx).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	loaded := TestModel{}
+	if err := Q(tx, C("ID =", uuid)).First(&loaded).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	// Keep Buddy with a new color, remove Max, and add Rocky
+	// (FavoriteDog and EvilDog share the same foreign key, so they're loaded with one of the dogs)
+	dogs := make([]Dog, 0)
+	for _, dog := range loaded.Dogs {
+		if dog.ID.String() == doguuid1 {
+			dog.Color = "brown"
+			dogs = append(dogs, dog)
+		}
+	}
+	dogs = append(dogs, Dog{Name: "Rocky", Color: "gray"})
+	loaded.Dogs = dogs
+	loaded.FavoriteDog = dogs[0]
+	loaded.EvilDog = &dogs[0]
+	loaded.Name = "MyTestModelChanged"
+
+	if err := Q(tx).SaveGraph(&loaded).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	searched := TestModel{}
+	if err := Q(tx, C("ID =", uuid)).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, "MyTestModelChanged", searched.Name)
+	if assert.Equal(t, 2, len(searched.Dogs)) {
+		colors := make(map[string]string)
+		for _, dog := range searched.Dogs {
+			colors[dog.Name] = dog.Color
+		}
+		assert.Equal(t, map[string]string{"Buddy": "brown", "Rocky": "gray"}, colors)
+	}
+
+	err := Q(tx, C("ID =", doguuid2)).First(&Dog{}).Error()
+	assert.Error(t, err, "removed pegged dog should be deleted")
+}
+
+func TestSaveGraph_PeggedArray_WhenUpdatedWithoutParentID_ShouldKeepIt(t *testing.T) {
+	uuid := "046bcadb-7127-47b1-9c1e-ff92ccea44b8"
+	doguuid1 := "919b7d4b-35fd-43a9-b707-78a874870f16"
+	tm := TestModel{BaseModel: mdl.BaseModel{
+		ID: datatype.NewUUIDFromStringNoErr(uuid)},
+		Name: "MyTestModel",
+		Age:  1,
+		Dogs: []Dog{
+			{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(doguuid1)}, Name: "Buddy", Color: "black"},
+		},
+	}
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	if err := Q(tx).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	// As if it's from JSON, where the foreign key is left out
+	updated := TestModel{BaseModel: mdl.BaseModel{
+		ID: datatype.NewUUIDFromStringNoErr(uuid)},
+		Name: "MyTestModel",
+		Age:  1,
+		Dogs: []Dog{
+			{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(doguuid1)}, Name: "Buddy", Color: "brown"},
+		},
+	}
+	if err := Q(tx).SaveGraph(&updated).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	dog := Dog{}
+	if err := Q(tx, C("ID =", doguuid1)).First(&dog).Error(); assert.Nil(t, err) {
+		assert.Equal(t, "brown", dog.Color)
+		if assert.NotNil(t, dog.TestModelID) {
+			assert.Equal(t, uuid, dog.TestModelID.String())
+		}
+	}
+}
+
+func TestSaveGraph_PeggedArrayOfPtrs_ShouldCreateUpdateAndDelete(t *testing.T) {
+	kennel := Kennel{Name: "MyKennel", Puppies: []*Puppy{{Name: "Buddy"}, {Name: "Max"}}}
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	if err := Q(tx).Create(&kennel).Error(); !assert.Nil(t, err) {
+		return
+	}
+	maxID := kennel.Puppies[1].ID
+
+	// Rename Buddy, remove Max, and add Rocky (a nil one is skipped)
+	kennel.Puppies[0].Name = "BuddyRenamed"
+	kennel.Puppies = []*Puppy{kennel.Puppies[0], nil, {Name: "Rocky"}}
+	if err := Q(tx).SaveGraph(&kennel).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	searched := Kennel{}
+	if err := Q(tx, C("ID =", kennel.ID)).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+	names := make([]string, 0)
+	for _, puppy := range searched.Puppies {
+		names = append(names, puppy.Name)
+	}
+	assert.ElementsMatch(t, []string{"BuddyRenamed", "Rocky"}, names)
+
+	err := Q(tx, C("ID =", maxID)).First(&Puppy{}).Error()
+	assert.Error(t, err, "removed pegged puppy should be deleted")
+}
+
+func TestSaveGraph_PeggedStructPtr_WhenRemoved_ShouldBeDeleted(t *testing.T) {
+	uuid := "046bcadb-7127-47b1-9c1e-ff92ccea44b8"
+	doguuid1 := "919b7d4b-35fd-43a9-b707-78a874870f16"
+	tm := TestModel{BaseModel: mdl.BaseModel{
+		ID: datatype.NewUUIDFromStringNoErr(uuid)},
+		Name: "MyTestModel",
+		Age:  1,
+		EvilDog: &Dog{
+			BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(doguuid1)},
+			Name:      "Buddy",
+			Color:     "black",
+		},
+	}
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	if err := Q(tx).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	tm.EvilDog = nil
+	if err := Q(tx).SaveGraph(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	err := Q(tx, C("ID =", doguuid1)).First(&Dog{}).Error()
+	assert.Error(t, err, "removed pegged dog should be deleted")
+}
+
+func TestSaveGraph_PegAssocArray_ShouldAssociateAndDissociate(t *testing.T) {
+	catuuid1 := "6a53ab29-72c9-4746-8e12-cb670d289231"
+	catuuid2 := "9f1f6c43-9a0e-4c58-9a47-4a0f3e5e1d11"
+	cat1 := Cat{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(catuuid1)}, Name: "Buddy", Color: "black"}
+	cat2 := Cat{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(catuuid2)}, Name: "Kitty", Color: "white"}
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	if err := Q(tx).Create(&cat1).Create(&cat2).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	uuid := "046bcadb-7127-47b1-9c1e-ff92ccea44b8"
+	tm := TestModel{BaseModel: mdl.BaseModel{
+		ID: datatype.NewUUIDFromStringNoErr(uuid)},
+		Name: "MyTestModel",
+		Age:  1,
+		Cats: []Cat{cat1},
+	}
+
+	if err := Q(tx).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	tm.Cats = []Cat{cat2}
+	if err := Q(tx).SaveGraph(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	searched := TestModel{}
+	if err := Q(tx, C("ID =", uuid)).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	if assert.Equal(t, 1, len(searched.Cats)) {
+		assert.Equal(t, catuuid2, searched.Cats[0].ID.String())
+	}
+
+	loadedCat := Cat{}
+	if err := Q(tx, C("ID =", catuuid1)).First(&loadedCat).Error(); assert.Nil(t, err) {
+		assert.Nil(t, loadedCat.TestModelID, "dissociated cat should be left intact")
+	}
+}
+
+func TestSaveGraph_WithoutID_ShouldGiveAnError(t *testing.T) {
+	err := Q(db).SaveGraph(&TestModel{Name: "NoID"}).Error()
+	assert.Error(t, err)
+}
+
+func TestUpdateFields_Works(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	q := Q(tx, C("Name =", "same"))
+	if err := q.UpdateFields(&TestModel{}, map[string]interface{}{"Age": 120, "Name": "updated"}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(3), q.RowsAffected())
+
+	tms := make([]TestModel, 0)
+	if err := Q(tx, C("Name =", "updated")).Find(&tms).Error(); !assert.Nil(t, err) {
+		return
+	}
+	if assert.Equal(t, 3, len(tms)) {
+		for _, tm := range tms {
+			assert.Equal(t, 120, tm.Age)
+		}
+	}
+}
+
+func TestUpdateFields_Inc_Works(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tm := TestModel{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(uuid4)}}
+	if err := Q(tx).UpdateFields(&tm, map[string]interface{}{"Age": Inc("Age", 10)}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	check := TestModel{}
+	if err := Q(tx, C("ID =", uuid4)).First(&check).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 14, check.Age)
+}
+
+func TestUpdateFields_NestedField_Works(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	q := Q(tx, C("Name =", "first"))
+	if err := q.UpdateFields(&TestModel{}, map[string]interface{}{"Dogs.Color": "purple"}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), q.RowsAffected())
+
+	dog := Dog{}
+	if err := Q(tx, C("Name =", "Doggie0")).First(&dog).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "purple", dog.Color)
+
+	// Dogs of other TestModel are left intact
+	dog = Dog{}
+	if err := Q(tx, C("Name =", "Doggie4")).First(&dog).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "green", dog.Color)
+}
+
+func TestUpdateFields_NestedFieldAndNestedCriteria_ShouldOnlyUpdateTheMatchingOnes(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	// "Doggie1" (red) and "Doggie2" (green) are of the same TestModel
+	q := Q(tx, C("Dogs.Color =", "red"))
+	if err := q.UpdateFields(&TestModel{}, map[string]interface{}{"Dogs.Color": "purple"}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	dog := Dog{}
+	if err := Q(tx, C("Name =", "Doggie1")).First(&dog).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "purple", dog.Color)
+
+	dog = Dog{}
+	if err := Q(tx, C("Name =", "Doggie2")).First(&dog).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "green", dog.Color)
+}
+
+func TestUpdateFields_NonExistingField_ShouldGiveAnError(t *testing.T) {
+	err := Q(db, C("Name =", "second")).UpdateFields(&TestModel{}, map[string]interface{}{"Bogus": 1}).Error()
+	assert.Error(t, err)
+}
+
+func TestUpdateFields_WithoutCriteriaOrID_ShouldGiveAnError(t *testing.T) {
+	err := Q(db).UpdateFields(&TestModel{}, map[string]interface{}{"Age": 1}).Error()
+	assert.Error(t, err)
+}
+
+func TestUpdateMany_EachWithItsOwnValue_Works(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tms := make([]TestModel, 0)
+	if err := Q(tx, C("Name =", "same")).Find(&tms).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	modelObjs := make([]mdl.IModel, len(tms))
+	for i := range tms {
+		tms[i].Age = 100 + i
+		tms[i].Name = "not updated"
+		modelObjs[i] = &tms[i]
+	}
+
+	q := Q(tx)
+	if err := q.UpdateMany(modelObjs, "Age").Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(len(tms)), q.RowsAffected())
+
+	for i := range tms {
+		check := TestModel{}
+		if err := Q(tx, C("ID =", tms[i].ID)).First(&check).Error(); !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 100+i, check.Age)
+		assert.Equal(t, "same", check.Name) // field not listed is left intact
+	}
+}
+
+func TestUpdateMany_NestedField_ShouldGiveAnError(t *testing.T) {
+	tm := TestModel{BaseModel: mdl.BaseModel{ID: datatype.NewUUIDFromStringNoErr(uuid4)}}
+	err := Q(db).UpdateMany([]mdl.IModel{&tm}, "Dogs.Color").Error()
+	assert.Error(t, err)
+
+	err = Q(db).UpdateMany([]mdl.IModel{&tm}, "Dogs").Error()
+	assert.Error(t, err)
+}
+
+func TestUpdateMany_WithoutID_ShouldGiveAnError(t *testing.T) {
+	err := Q(db).UpdateMany([]mdl.IModel{&TestModel{Age: 3}}, "Age").Error()
+	assert.Error(t, err)
+}
+
+func TestUpdateMany_MoreThanOneChunk_Works(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	n := updateManyChunkSize + 10
+	modelObjs := make([]mdl.IModel, n)
+	for i := 0; i < n; i++ {
+		modelObjs[i] = &TestModel{Name: "chunked", Age: 1}
+	}
+	if err := Q(tx).CreateMany(modelObjs).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		modelObjs[i].(*TestModel).Age = i
+	}
+
+	q := Q(tx)
+	if err := q.UpdateMany(modelObjs, "Age").Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(n), q.RowsAffected())
+
+	check := TestModel{}
+	if err := Q(tx, C("ID =", modelObjs[n-1].GetID())).First(&check).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, n-1, check.Age)
+}
+
+func TestSave_Versioned_ShouldIncrementVersion_AndRejectStaleObject(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	vm := VersionedModel{Name: "original"}
+	if err := Q(tx).Create(&vm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	stale := vm
+
+	vm.Name = "first edit"
+	if err := Q(tx).Save(&vm).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, vm.Version)
+
+	stale.Name = "second edit"
+	err := Q(tx).Save(&stale).Error()
+	assert.True(t, errors.Is(err, ErrStaleObject))
+	assert.Equal(t, 0, stale.Version)
+
+	check := VersionedModel{}
+	if err := Q(tx, C("ID =", vm.ID)).First(&check).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "first edit", check.Name)
+	assert.Equal(t, 1, check.Version)
+}
+
+func TestSave_VersionedChild_ShouldIncrementVersion_AndRejectStaleObject(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	vm := VersionedModel{Name: "parent", VersionedChildren: []VersionedChild{{Name: "original"}}}
+	if err := Q(tx).Create(&vm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	stale := vm
+	stale.VersionedChildren = []VersionedChild{vm.VersionedChildren[0]}
+
+	vm.VersionedChildren[0].Name = "first edit"
+	if err := Q(tx).Save(&vm).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, vm.Version)
+	assert.Equal(t, 1, vm.VersionedChildren[0].Version)
+
+	stale.Version = vm.Version // only the child is stale
+	stale.VersionedChildren[0].Name = "second edit"
+	err := Q(tx).Save(&stale).Error()
+	var staleErr *StaleObjectError
+	if assert.True(t, errors.As(err, &staleErr)) {
+		assert.Equal(t, "versioned_child", staleErr.Table)
+	}
+	assert.Equal(t, 1, stale.Version)
+	assert.Equal(t, 0, stale.VersionedChildren[0].Version)
+
+	check := VersionedChild{}
+	if err := Q(tx, C("ID =", vm.VersionedChildren[0].ID)).First(&check).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "first edit", check.Name)
+	assert.Equal(t, 1, check.Version)
+}
+
+func TestUpdateFields_Versioned_ShouldRejectStaleObject(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	vm := VersionedModel{Name: "original"}
+	if err := Q(tx).Create(&vm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	stale := vm
+	if err := Q(tx).UpdateFields(&vm, map[string]interface{}{"Name": "first edit"}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, vm.Version)
+
+	err := Q(tx).UpdateFields(&stale, map[string]interface{}{"Name": "second edit"}).Error()
+	var staleErr *StaleObjectError
+	if assert.True(t, errors.As(err, &staleErr)) {
+		assert.Equal(t, "versioned_model", staleErr.Table) // not quoted
+	}
+
+	check := VersionedModel{}
+	if err := Q(tx, C("ID =", vm.ID)).First(&check).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "first edit", check.Name)
+	assert.Equal(t, 1, check.Version)
+}
+
+func TestUpdateMany_Versioned_ShouldRejectStaleObject(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	vm1, vm2 := VersionedModel{Name: "one"}, VersionedModel{Name: "two"}
+	if err := Q(tx).CreateMany([]mdl.IModel{&vm1, &vm2}).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	stale := vm2
+	vm2.Name = "two edited"
+	if err := Q(tx).UpdateMany([]mdl.IModel{&vm2}, "Name").Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, vm2.Version)
+
+	vm1.Name = "one edited"
+	stale.Name = "two edited again"
+	err := Q(tx).UpdateMany([]mdl.IModel{&vm1, &stale}, "Name").Error()
+	assert.True(t, errors.Is(err, ErrStaleObject))
+
+	check := VersionedModel{}
+	if err := Q(tx, C("ID =", vm2.ID)).First(&check).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "two edited", check.Name)
+	assert.Equal(t, 1, check.Version)
+}
+
+func TestUpdateMany_DuplicateIDs_ShouldGiveBuilderErrorWithoutUpdating(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	vm := VersionedModel{Name: "one"}
+	if err := Q(tx).Create(&vm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	// Not stale, but the same one twice
+	duplicate := vm
+	vm.Name, duplicate.Name = "one edited", "one edited again"
+	err := Q(tx).UpdateMany([]mdl.IModel{&vm, &duplicate}, "Name").Error()
+	assert.IsType(t, &BuilderError{}, err)
+	assert.False(t, errors.Is(err, ErrStaleObject))
+
+	check := VersionedModel{}
+	if err := Q(tx, C("ID =", vm.ID)).First(&check).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "one", check.Name)
+	assert.Equal(t, 0, check.Version)
+}
+
+func TestUpdateFields_Returning_ShouldGiveUpdatedRows(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	updated := make([]TestModel, 0)
+	q := Q(tx, C("Name =", "same")).Returning(&updated)
+	if err := q.UpdateFields(&TestModel{}, map[string]interface{}{"Age": Inc("Age", 1)}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(3), q.RowsAffected())
+	if assert.Equal(t, 3, len(updated)) {
+		for _, tm := range updated {
+			assert.Equal(t, "same", tm.Name)
+			assert.True(t, tm.Age == 4 || tm.Age == 5)
+		}
+	}
+}
+
+func TestUpdateFields_ReturningWithoutReturning_ShouldGiveUpdatedRows(t *testing.T) {
+	withoutReturning(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	updated := make([]TestModel, 0)
+	q := Q(tx, C("Name =", "same")).Returning(&updated)
+	if err := q.UpdateFields(&TestModel{}, map[string]interface{}{"Age": Inc("Age", 1)}).Error(); !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(3), q.RowsAffected())
+	if assert.Equal(t, 3, len(updated)) {
+		for _, tm := range updated {
+			assert.Equal(t, "same", tm.Name)
+			assert.True(t, tm.Age == 4 || tm.Age == 5)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package qry
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/t2wu/qry/mdl"
+)
+
+var (
+	redactMu        sync.RWMutex
+	redactedColumns = make(map[string]map[string]bool) // table -> columns, "" being of every table
+	redactedTypes   = make(map[reflect.Type]bool)      // models whose sensitive fields are redacted
+)
+
+// RedactColumns has the values of columns of table logged as '***', table "" being every table.
+// Columns of fields tagged with `qry:"sensitive"` are redacted without it, once their model is
+// queried.
+//
+//	qry.RedactColumns("user", "password", "api_token")
+func RedactColumns(table string, columns ...string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	if redactedColumns[table] == nil {
+		redactedColumns[table] = make(map[string]bool)
+	}
+	for _, column := range columns {
+		redactedColumns[table][column] = true
+	}
+}
+
+// isRedactedColumn is whether the values of column are redacted, table "" being whichever table
+func isRedactedColumn(table, column string) bool {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	if redactedColumns[""][column] {
+		return true
+	}
+	if table != "" {
+		return redactedColumns[table][column]
+	}
+	for _, columns := range redactedColumns {
+		if columns[column] {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRedactedColumns() bool {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	return len(redactedColumns) > 0
+}
+
+// redactSensitiveFields redacts the columns of the sensitive fields of the models of v and the
+// models nested within, v being a model, a slice of them or a pointer to one
+func redactSensitiveFields(v interface{}) {
+	typ := modelTypeOf(v)
+	if typ == nil {
+		return
+	}
+
+	redactMu.RLock()
+	done := redactedTypes[typ]
+	redactMu.RUnlock()
+	if !done {
+		redactSensitiveFieldsOfType(typ, make(map[reflect.Type]bool))
+	}
+}
+
+// modelTypeOf is the struct type of the models of v, a model, a slice of them or a pointer to one,
+// nil if there is none
+func modelTypeOf(v interface{}) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	typ := reflect.TypeOf(v)
+	if m, ok := v.([]mdl.IModel); ok {
+		if len(m) == 0 || m[0] == nil {
+			return nil
+		}
+		typ = reflect.TypeOf(m[0])
+	}
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	return typ
+}
+
+func redactSensitiveFieldsOfType(typ reflect.Type, visited map[reflect.Type]bool) {
+	if visited[typ] {
+		return
+	}
+	visited[typ] = true
+
+	m, ok := reflect.New(typ).Interface().(mdl.IModel)
+	if !ok {
+		return
+	}
+
+	table := mdl.GetTableNameFromIModel(m)
+	for _, fieldName := range mdl.GetSensitiveFieldNames(m) {
+		if column, err := mdl.FieldNameToColumn(m, fieldName); err == nil {
+			RedactColumns(table, column)
+		}
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		fieldTyp := typ.Field(i).Type
+		for fieldTyp.Kind() == reflect.Ptr || fieldTyp.Kind() == reflect.Slice {
+			fieldTyp = fieldTyp.Elem()
+		}
+		if fieldTyp.Kind() == reflect.Struct && !typ.Field(i).Anonymous {
+			redactSensitiveFieldsOfType(fieldTyp, visited)
+		}
+	}
+
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	redactedTypes[typ] = true
+}
+
+// ------------------
+
+// redacted is in place of the value of a redacted column in Entry.Vars
+type redacted struct{}
+
+func (redacted) String() string {
+	return "***"
+}
+
+// redactVars returns vars with the values of redacted columns replaced, which are found by
+// the column each placeholder of sql is compared with, set to or inserted into
+func redactVars(sql string, vars []interface{}) []interface{} {
+	if len(vars) == 0 || !hasRedactedColumns() {
+		return vars
+	}
+
+	var redactedVars []interface{}
+	for i, column := range placeholderColumns(sql) {
+		if i >= len(vars) || column.name == "" || !isRedactedColumn(column.table, column.name) {
+			continue
+		}
+		if redactedVars == nil {
+			redactedVars = append([]interface{}{}, vars...)
+		}
+		redactedVars[i] = redacted{}
+	}
+	if redactedVars == nil {
+		return vars
+	}
+	return redactedVars
+}
+
+type sqlColumn struct {
+	table string // "" if the column isn't qualified
+	name  string
+}
+
+type sqlToken struct {
+	text        string // unquoted if it's an identifier
+	ident       bool
+	placeholder int // the index of the var + 1, 0 if it isn't a placeholder
+}
+
+// placeholderColumns returns the column of each placeholder of sql by the index of its var,
+// being empty if it's not known
+func placeholderColumns(sql string) map[int]sqlColumn {
+	tokens := tokenizeSQL(sql)
+	columns := make(map[int]sqlColumn)
+
+	insertTable, insertColumns, valuesAt := insertColumnsOf(tokens)
+	rowColumns := unionRowColumnsOf(tokens)
+	depth, position := 0, 0
+	for i, tok := range tokens {
+		if column, ok := rowColumns[i]; ok {
+			columns[tok.placeholder-1] = column
+			continue
+		}
+		if valuesAt > 0 && i > valuesAt {
+			switch tok.text {
+			case "(":
+				depth++
+				if depth == 1 {
+					position = 0
+				}
+			case ")":
+				depth--
+			case ",":
+				if depth == 1 {
+					position++
+				}
+			}
+			if tok.placeholder > 0 && depth == 1 && position < len(insertColumns) {
+				columns[tok.placeholder-1] = sqlColumn{table: insertTable, name: insertColumns[position]}
+				continue
+			}
+		}
+		if tok.placeholder > 0 {
+			columns[tok.placeholder-1] = comparedColumn(tokens[:i])
+		}
+	}
+	return columns
+}
+
+var sqlOperators = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"LIKE": true, "ILIKE": true, "IN": true, "NOT": true, "IS": true, "BETWEEN": true,
+}
+
+// comparedColumn is the column before the operator which ends tokens, such as a in a = ( ?, ?,
+// or in a BETWEEN ? AND
+func comparedColumn(tokens []sqlToken) sqlColumn {
+	i := len(tokens) - 1
+	if i >= 2 && strings.EqualFold(tokens[i].text, "AND") && tokens[i-1].placeholder > 0 &&
+		strings.EqualFold(tokens[i-2].text, "BETWEEN") {
+		i -= 2
+	}
+	for i >= 0 && (tokens[i].placeholder > 0 || tokens[i].text == "," || tokens[i].text == "(") {
+		i--
+	}
+	if i < 0 || !sqlOperators[strings.ToUpper(tokens[i].text)] {
+		return sqlColumn{}
+	}
+	for i >= 0 && !tokens[i].ident && sqlOperators[strings.ToUpper(tokens[i].text)] {
+		i--
+	}
+	if i < 0 || !tokens[i].ident {
+		return sqlColumn{}
+	}
+	column := sqlColumn{name: tokens[i].text}
+	if i >= 2 && tokens[i-1].text == "." && tokens[i-2].ident {
+		column.table = tokens[i-2].text
+	}
+	return column
+}
+
+// unionRowColumnsOf returns the columns of the placeholders of the rows of a union, such as
+// SELECT t.id, t.password FROM t WHERE false UNION ALL SELECT ?, ? UNION ALL SELECT ?, ?, by the
+// index of their tokens. A row's values are of the columns of the first SELECT at its position.
+func unionRowColumnsOf(tokens []sqlToken) map[int]sqlColumn {
+	columns := make(map[int]sqlColumn)
+	firstSelects := make(map[int][]sqlColumn) // the columns of the first SELECT of a union, by depth
+	depth := 0
+	for i, tok := range tokens {
+		switch tok.text {
+		case "(":
+			depth++
+			continue
+		case ")":
+			delete(firstSelects, depth)
+			depth--
+			continue
+		}
+		if !strings.EqualFold(tok.text, "SELECT") {
+			continue
+		}
+
+		items := selectItemsOf(tokens, i+1)
+		isRow := i >= 1 && (strings.EqualFold(tokens[i-1].text, "UNION") ||
+			i >= 2 && strings.EqualFold(tokens[i-1].text, "ALL") && strings.EqualFold(tokens[i-2].text, "UNION"))
+		if !isRow {
+			first := make([]sqlColumn, len(items))
+			for position, item := range items {
+				first[position] = selectedColumn(tokens[item[0]:item[1]])
+			}
+			firstSelects[depth] = first
+			continue
+		}
+
+		first := firstSelects[depth]
+		for position, item := range items {
+			if position >= len(first) {
+				break
+			}
+			for j := item[0]; j < item[1]; j++ {
+				if tokens[j].placeholder > 0 {
+					columns[j] = first[position]
+				}
+			}
+		}
+	}
+	return columns
+}
+
+// selectItemsOf returns the token ranges [from, to) of the items of the select list starting at
+// from, which ends by FROM, UNION or the closing parenthesis
+func selectItemsOf(tokens []sqlToken, from int) [][2]int {
+	items := make([][2]int, 0)
+	depth, start := 0, from
+	for i := from; i < len(tokens); i++ {
+		switch text := strings.ToUpper(tokens[i].text); {
+		case text == "(":
+			depth++
+		case text == ")" && depth == 0, depth == 0 && (text == "FROM" || text == "UNION" || text == "WHERE"):
+			return append(items, [2]int{start, i})
+		case text == ")":
+			depth--
+		case text == "," && depth == 0:
+			items = append(items, [2]int{start, i})
+			start = i + 1
+		}
+	}
+	return append(items, [2]int{start, len(tokens)})
+}
+
+// selectedColumn is the column of a select item such as t.password or password, empty otherwise
+func selectedColumn(item []sqlToken) sqlColumn {
+	switch {
+	case len(item) == 1 && item[0].ident:
+		return sqlColumn{name: item[0].text}
+	case len(item) == 3 && item[0].ident && item[1].text == "." && item[2].ident:
+		return sqlColumn{table: item[0].text, name: item[2].text}
+	}
+	return sqlColumn{}
+}
+
+// insertColumnsOf returns the table and columns of INSERT INTO table (columns...) VALUES, and
+// the index of VALUES, which is 0 if tokens is not such statement
+func insertColumnsOf(tokens []sqlToken) (string, []string, int) {
+	if len(tokens) < 4 || !strings.EqualFold(tokens[0].text, "INSERT") || !strings.EqualFold(tokens[1].text, "INTO") {
+		return "", nil, 0
+	}
+	i := 2
+	table := tokens[i].text
+	for i+2 < len(tokens) && tokens[i+1].text == "." { // schema.table
+		i += 2
+		table = tokens[i].text
+	}
+	i++
+	if i >= len(tokens) || tokens[i].text != "(" {
+		return "", nil, 0
+	}
+
+	columns := make([]string, 0)
+	for i++; i < len(tokens) && tokens[i].text != ")"; i++ {
+		if tokens[i].ident {
+			columns = append(columns, tokens[i].text)
+		}
+	}
+	for i++; i < len(tokens); i++ {
+		if strings.EqualFold(tokens[i].text, "VALUES") {
+			return table, columns, i
+		}
+	}
+	return "", nil, 0
+}
+
+// tokenizeSQL splits sql into identifiers (which are unquoted), placeholders (? or $n),
+// operators and punctuation, skipping string literals
+func tokenizeSQL(sql string) []sqlToken {
+	tokens := make([]sqlToken, 0)
+	runes := []rune(sql)
+	placeholders := 0
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' { // escaped quote
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+		case r == '"' || r == '`':
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: string(runes[i+1 : j]), ident: true})
+			i = j + 1
+		case r == '?':
+			placeholders++
+			tokens = append(tokens, sqlToken{text: "?", placeholder: placeholders})
+			i++
+		case r == '$' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			j := i + 1
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			n, _ := strconv.Atoi(string(runes[i+1 : j]))
+			tokens = append(tokens, sqlToken{text: string(runes[i:j]), placeholder: n})
+			i = j
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			word := string(runes[i:j])
+			tokens = append(tokens, sqlToken{text: word, ident: !sqlOperators[strings.ToUpper(word)]})
+			i = j
+		case strings.ContainsRune("<>!=", r):
+			j := i
+			for j < len(runes) && strings.ContainsRune("<>!=", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: string(runes[i:j])})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{text: string(r)})
+			i++
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,129 @@
+package qry
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Account struct {
+	mdl.BaseModel
+	Email    string `json:"email"`
+	Password string `json:"password" qry:"sensitive"`
+}
+
+// useRedactedColumns has the columns redacted be restored after t
+func useRedactedColumns(t *testing.T) {
+	redactMu.Lock()
+	prevColumns, prevTypes := redactedColumns, redactedTypes
+	redactedColumns, redactedTypes = make(map[string]map[string]bool), make(map[reflect.Type]bool)
+	redactMu.Unlock()
+	t.Cleanup(func() {
+		redactMu.Lock()
+		defer redactMu.Unlock()
+		redactedColumns, redactedTypes = prevColumns, prevTypes
+	})
+}
+
+func TestRedactVars_ShouldRedactPlaceholdersOfRedactedColumns(t *testing.T) {
+	useRedactedColumns(t)
+	RedactColumns("user", "password")
+	RedactColumns("", "token")
+
+	tests := []struct {
+		sql  string
+		vars []interface{}
+		want []string
+	}{
+		{`SELECT * FROM "user" WHERE "user"."email" = ? AND "user"."password" = ?`, []interface{}{"a@b.c", "pw"}, []string{"'a@b.c'", "'***'"}},
+		{`SELECT * FROM user WHERE name = '?' AND password IN (?,?) LIMIT ?`, []interface{}{"pw1", "pw2", 1}, []string{"'***'", "'***'", "1"}},
+		{`UPDATE "user" SET "password" = $2, "email" = $1 WHERE token = $3`, []interface{}{"a@b.c", "pw", "tk"}, []string{"'a@b.c'", "'***'", "'***'"}},
+		{"INSERT INTO `user` (`email`,`password`) VALUES (?,?),(?,?)", []interface{}{"a", "pw1", "b", "pw2"}, []string{"'a'", "'***'", "'b'", "'***'"}},
+		{`INSERT INTO "dog" ("name","password") VALUES (?,?)`, []interface{}{"a", "pw"}, []string{"'a'", "'pw'"}}, // not of user
+		{`SELECT * FROM "dog" WHERE "dog"."password" = ?`, []interface{}{"pw"}, []string{"'pw'"}},
+		{`SELECT * FROM "user" WHERE "user"."password" NOT BETWEEN ? AND ? AND "user"."email" BETWEEN ? AND ?`, []interface{}{"pw1", "pw2", "a", "b"}, []string{"'***'", "'***'", "'a'", "'b'"}},
+		{`UPDATE "user" SET email = v.email, password = v.password, updated_at = ? FROM (SELECT "user".id, "user".email, "user".password FROM "user" WHERE false UNION ALL SELECT ?, ?, ? UNION ALL SELECT ?, ?, ?) AS v WHERE "user".id = v.id`,
+			[]interface{}{"now", 1, "a", "pw1", 2, "b", "pw2"}, []string{"'now'", "1", "'a'", "'***'", "2", "'b'", "'***'"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, formatVars(redactVars(test.sql, test.vars)), test.sql)
+	}
+}
+
+func TestRedactColumns_ShouldLogRedactedPlaceholders(t *testing.T) {
+	useRedactedColumns(t)
+	RedactColumns("test_model", "real_name_column")
+
+	var buf bytes.Buffer
+	err := Q(db, C("Name =", "second")).WithLogHandler(NewJSONHandler(&buf, LevelDebug)).First(&TestModel{}).Error()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	lines := jsonLines(t, &buf)
+	if assert.NotEmpty(t, lines) {
+		assert.Equal(t, []interface{}{"'***'"}, lines[0]["vars"])
+	}
+	assert.NotContains(t, buf.String(), "second")
+}
+
+func TestSensitiveField_ShouldBeRedactedFromLogs(t *testing.T) {
+	useRedactedColumns(t)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+	if err := tx.AutoMigrate(&Account{}).Error; !assert.Nil(t, err) {
+		return
+	}
+
+	var buf bytes.Buffer
+	h := NewJSONHandler(&buf, LevelDebug)
+	account := Account{Email: "a@b.c", Password: "hunter2"}
+	if err := DB(tx).WithLogHandler(h).Create(&account).Error(); !assert.Nil(t, err) {
+		return
+	}
+	searched := Account{}
+	if err := Q(tx, C("Password =", "hunter2")).WithLogHandler(h).First(&searched).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, "hunter2", searched.Password)
+	assert.Contains(t, buf.String(), "a@b.c")
+	assert.Contains(t, buf.String(), "'***'")
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestSensitiveField_UpdateManyAndBetween_ShouldBeRedactedFromLogs(t *testing.T) {
+	useRedactedColumns(t)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+	if err := tx.AutoMigrate(&Account{}).Error; !assert.Nil(t, err) {
+		return
+	}
+	accounts := []mdl.IModel{&Account{Email: "a@b.c", Password: "hunter2"}, &Account{Email: "d@e.f", Password: "hunter3"}}
+	if err := DB(tx).CreateMany(accounts).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	var buf bytes.Buffer
+	h := NewJSONHandler(&buf, LevelDebug)
+	accounts[0].(*Account).Password = "swordfish1"
+	accounts[1].(*Account).Password = "swordfish2"
+	if err := DB(tx).WithLogHandler(h).UpdateMany(accounts, "Password").Error(); !assert.Nil(t, err) {
+		return
+	}
+	var count int
+	err := Q(tx, C("Password BETWEEN", []string{"swordfish0", "swordfish9"})).WithLogHandler(h).Count(&Account{}, &count).Error()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, 2, count)
+	assert.Contains(t, buf.String(), "'***'")
+	assert.NotContains(t, buf.String(), "swordfish")
+}
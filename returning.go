@@ -0,0 +1,77 @@
+package qry
+
+import (
+	"fmt"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+// execMaybeReturning executes stmt, and if returning is given, with "RETURNING" so the rows
+// affected are scanned into it. Returns rows affected.
+func execMaybeReturning(db executor, returning interface{}, stmt string, vals ...interface{}) (int64, error) {
+	if returning == nil {
+		return db.Exec(stmt, vals...)
+	}
+
+	rowsAffected, err := db.RawScan(returning, stmt+" RETURNING *", vals...)
+	if err != nil && !gorm.IsRecordNotFoundError(err) { // when scanning into a struct
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// deleteMaybeReturning deletes the rows of modelObj's table whose ids are in ids (a slice or a sub
+// query of them), and if returning is given, the rows deleted are scanned into it. Without
+// RETURNING (such as on MySQL), they're read before, and the ones read are deleted.
+// Returns rows affected.
+func deleteMaybeReturning(db executor, modelObj mdl.IModel, returning interface{}, ids interface{}) (int64, error) {
+	if returning != nil && !db.Dialect().Returning() {
+		readIDs, err := selectIDs(db, modelObj, ids)
+		if err != nil {
+			return 0, err
+		}
+		if err := readBackByIDs(db, modelObj, readIDs, returning); err != nil {
+			return 0, err
+		}
+		ids, returning = readIDs, nil
+	}
+
+	tblName := db.Dialect().Quote(mdl.GetTableNameFromIModel(modelObj))
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE %s.id IN (?)", tblName, tblName)
+	return execMaybeReturning(db, returning, stmt, ids)
+}
+
+// selectIDs returns the ids of the rows of modelObj's table whose ids are in ids (a slice or a
+// sub query of them), so the rows are affected by the ones selected
+func selectIDs(db executor, modelObj mdl.IModel, ids interface{}) ([]*datatype.UUID, error) {
+	tblName := db.Dialect().Quote(mdl.GetTableNameFromIModel(modelObj))
+	selected := make([]*datatype.UUID, 0)
+	err := db.Unscoped().Table(mdl.GetTableNameFromIModel(modelObj)).
+		Where(fmt.Sprintf("%s.id IN (?)", tblName), ids).Pluck("id", &selected)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	return selected, nil
+}
+
+// readBackCreated reads the created mdls into out, since Gorm's create only returns the id
+func readBackCreated(db executor, modelObjs []mdl.IModel, out interface{}) error {
+	ids := make([]*datatype.UUID, len(modelObjs))
+	for i, modelObj := range modelObjs {
+		ids[i] = modelObj.GetID()
+	}
+	return readBackByIDs(db, modelObjs[0], ids, out)
+}
+
+// readBackByIDs reads the rows of modelObj's table of ids into out, a mdl or a slice of them
+func readBackByIDs(db executor, modelObj mdl.IModel, ids []*datatype.UUID, out interface{}) error {
+	tblName := db.Dialect().Quote(mdl.GetTableNameFromIModel(modelObj))
+	err := db.Unscoped().Where(fmt.Sprintf("%s.id IN (?)", tblName), ids).Find(out)
+	if err != nil && !gorm.IsRecordNotFoundError(err) { // when read into a struct
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package qry
+
+import (
+	"reflect"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Save strategy for the whole graph
+// The current graph is loaded from the database and compared with the one given.
+// If peg, new elements are created, existing ones are updated (and traversed into), and
+// the ones no longer there are deleted (cascading like Delete does).
+// If pegassoc, new elements are pointed to this mdl and the ones no longer there are dissociated.
+// Many to many is not handled here.
+
+// SaveModelSyncPegAndPegAssoc saves modelObj and syncs its pegged and pegassoc fields with the
+// database. This should be run within a transaction.
+func SaveModelSyncPegAndPegAssoc(db *gorm.DB, modelObj mdl.IModel) error {
+	return saveModelSyncPegAndPegAssoc(newGormV1(db), modelObj)
+}
+
+func saveModelSyncPegAndPegAssoc(db executor, modelObj mdl.IModel) error {
+	oldModelObj := reflect.New(reflect.TypeOf(modelObj).Elem()).Interface().(mdl.IModel)
+	if err := db.Preload().Where("id = ?", modelObj.GetID()).First(oldModelObj); err != nil {
+		return err
+	}
+
+	return saveGraph(db, modelObj, oldModelObj)
+}
+
+func saveGraph(db executor, modelObj mdl.IModel, oldModelObj mdl.IModel) error {
+	// Only the mdl's own columns, nested fields are dealt with below
+	if err := saveOwnColumnsCheckVersion(db, modelObj); err != nil {
+		return err
+	}
+
+	// Gathered across fields, because the same nested mdl could be loaded into more than one
+	// field (or be moved from one to another)
+	pegged, oldPegged := make([]mdl.IModel, 0), make([]mdl.IModel, 0)
+	peggedAssoc, oldPeggedAssoc := make([]mdl.IModel, 0), make([]mdl.IModel, 0)
+
+	v := reflect.Indirect(reflect.ValueOf(modelObj))
+	oldV := reflect.Indirect(reflect.ValueOf(oldModelObj))
+	for i := 0; i < v.NumField(); i++ {
+		switch pegPegassocOrPegManyToMany(v.Type().Field(i).Tag) {
+		case "peg":
+			pegged = append(pegged, nestedModelsAtField(v.Field(i))...)
+			oldPegged = append(oldPegged, nestedModelsAtField(oldV.Field(i))...)
+		case "pegassoc":
+			peggedAssoc = append(peggedAssoc, nestedModelsAtField(v.Field(i))...)
+			oldPeggedAssoc = append(oldPeggedAssoc, nestedModelsAtField(oldV.Field(i))...)
+		}
+	}
+
+	if err := syncPegged(db, modelObj, pegged, oldPegged); err != nil {
+		return err
+	}
+
+	return syncPeggedAssoc(db, modelObj, peggedAssoc, oldPeggedAssoc)
+}
+
+func syncPegged(db executor, modelObj mdl.IModel, models, oldModels []mdl.IModel) error {
+	oldByKey := make(map[string]mdl.IModel)
+	for _, oldModel := range oldModels {
+		oldByKey[tableAndIDKey(oldModel)] = oldModel
+	}
+
+	toUpdate := make(map[string]mdl.IModel)
+	toCreate := make([]mdl.IModel, 0)
+	for _, m := range models {
+		if m.GetID() != nil {
+			key := tableAndIDKey(m)
+			if _, ok := toUpdate[key]; ok {
+				continue // the same one in another field
+			}
+			if _, ok := oldByKey[key]; ok {
+				toUpdate[key] = m
+				continue
+			}
+		}
+		toCreate = append(toCreate, m)
+	}
+
+	// Remove first, so nothing we create afterwards can run into the ones removed
+	for key, oldModel := range oldByKey {
+		if _, ok := toUpdate[key]; ok {
+			continue
+		}
+
+		if _, err := db.Unscoped().Delete(oldModel, nil); err != nil {
+			return err
+		}
+		if err := deleteModelFixManyToManyAndPegAndPegAssoc(db, oldModel); err != nil {
+			return err
+		}
+	}
+
+	// The foreign key isn't necessarily given (it's not in JSON), so it's set for both
+	for key, m := range toUpdate {
+		setParentID(m, modelObj)
+		if err := saveGraph(db, m, oldByKey[key]); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range toCreate {
+		setParentID(m, modelObj)
+
+		if err := removeIDForNonPegOrPeggedFieldsBeforeCreate(db, m); err != nil {
+			return err
+		}
+		if _, err := db.Create(m); err != nil {
+			return err
+		}
+		if err := createPeggedAssocFields(db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func syncPeggedAssoc(db executor, modelObj mdl.IModel, models, oldModels []mdl.IModel) error {
+	correspondingColumnName := mdl.GetTableNameFromIModel(modelObj) + "_id"
+
+	keys := make(map[string]bool)
+	for _, m := range models {
+		if m.GetID() != nil {
+			keys[tableAndIDKey(m)] = true
+		}
+	}
+
+	oldKeys := make(map[string]bool)
+	for _, oldModel := range oldModels {
+		key := tableAndIDKey(oldModel)
+		if oldKeys[key] {
+			continue
+		}
+		oldKeys[key] = true
+
+		if !keys[key] {
+			// Dissociate, the mdl itself stays intact
+			if err := db.Model(oldModel).Where("id = ?", oldModel.GetID()).
+				Update(correspondingColumnName, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, m := range models {
+		if m.GetID() == nil {
+			continue
+		}
+
+		key := tableAndIDKey(m)
+		if !oldKeys[key] {
+			oldKeys[key] = true // so it's not associated twice
+			if err := db.Model(m).Where("id = ?", m.GetID()).
+				Update(correspondingColumnName, modelObj.GetID()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func tableAndIDKey(m mdl.IModel) string {
+	return mdl.GetTableNameFromIModel(m) + ":" + m.GetID().String()
+}
+
+// nestedModelsAtField returns the mdls within a struct, struct pointer or slice (of structs or
+// struct pointers) field
+// An embedded struct which is never initialized is not considered there
+func nestedModelsAtField(fieldVal reflect.Value) []mdl.IModel {
+	ms := make([]mdl.IModel, 0)
+	switch fieldVal.Kind() {
+	case reflect.Slice:
+		for j := 0; j < fieldVal.Len(); j++ {
+			elem := fieldVal.Index(j)
+			if elem.Kind() == reflect.Ptr {
+				if m, ok := elem.Interface().(mdl.IModel); ok && !elem.IsNil() {
+					ms = append(ms, m)
+				}
+			} else if m, ok := elem.Addr().Interface().(mdl.IModel); ok {
+				ms = append(ms, m)
+			}
+		}
+	case reflect.Ptr:
+		if m, ok := fieldVal.Interface().(mdl.IModel); ok && !isNil(m) {
+			ms = append(ms, m)
+		}
+	case reflect.Struct:
+		if m, ok := fieldVal.Addr().Interface().(mdl.IModel); ok && !fieldVal.IsZero() {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// setParentID sets the foreign key (for example TestModelID) within the nested mdl m
+// to point to modelObj, if there is such field
+func setParentID(m mdl.IModel, modelObj mdl.IModel) {
+	fieldName := mdl.GetModelTypeNameFromIModel(modelObj) + "ID"
+	fieldVal := reflect.Indirect(reflect.ValueOf(m)).FieldByName(fieldName)
+	if fieldVal.IsValid() && fieldVal.CanSet() && fieldVal.Type() == reflect.TypeOf(&datatype.UUID{}) {
+		fieldVal.Set(reflect.ValueOf(modelObj.GetID()))
+	}
+}
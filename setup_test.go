@@ -3,13 +3,16 @@ package qry
 import (
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/dialect"
 	"github.com/t2wu/qry/mdl"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
 )
 
 const (
@@ -102,27 +105,101 @@ type UnNestedInner struct {
 	UnNestedID *datatype.UUID `gorm:"type:uuid;index;not null;" json:"-"`
 }
 
+type VersionedModel struct {
+	mdl.BaseModel
+
+	Name    string `json:"name"`
+	Version int    `json:"version" qry:"version"`
+
+	VersionedChildren []VersionedChild `json:"versionedChildren"`
+}
+
+type VersionedChild struct {
+	mdl.BaseModel
+
+	Name    string `json:"name"`
+	Version int    `json:"version" qry:"version"`
+
+	VersionedModelID *datatype.UUID `gorm:"type:uuid;index;" json:"-"`
+}
+
+// Kennel pegs its puppies by pointer
+type Kennel struct {
+	mdl.BaseModel
+
+	Name    string   `json:"name"`
+	Puppies []*Puppy `betterrest:"peg" json:"puppies"`
+}
+
+type Puppy struct {
+	mdl.BaseModel
+
+	Name string `json:"name"`
+
+	KennelID *datatype.UUID `gorm:"type:uuid;index;not null;" json:"-"`
+}
+
 var db *gorm.DB
 
-// Package level setup
-func TestMain(m *testing.M) {
+// testDSN is what db is opened with
+var testDSN string
+
+// openTestDB opens the database the tests run on, Postgres by default, or a temp-file SQLite
+// database with QRY_TEST_DIALECT=sqlite3
+// Returns a function to clean up after.
+func openTestDB() (*gorm.DB, func(), error) {
+	if os.Getenv("QRY_TEST_DIALECT") == dialect.SQLite.GetName() {
+		dir, err := os.MkdirTemp("", "qry")
+		if err != nil {
+			return nil, nil, err
+		}
+		testDSN = filepath.Join(dir, "test.db") + "?_foreign_keys=1"
+		db, err := gorm.Open("sqlite3", testDSN)
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, nil, err
+		}
+		dialect.Use(dialect.SQLite)
+		return db, func() { db.Close(); os.RemoveAll(dir) }, nil
+	}
 
-	dsn := "host=" + host + " port=" + port + " user=" + username +
+	testDSN = "host=" + host + " port=" + port + " user=" + username +
 		" dbname=" + dbname + " password=" + password + " sslmode=disable"
+	db, err := gorm.Open("postgres", testDSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, func() { db.Close() }, nil
+}
 
+// Package level setup
+func TestMain(m *testing.M) {
 	var err error
-	db, err = gorm.Open("postgres", dsn)
+	var cleanup func()
+	db, cleanup, err = openTestDB()
 	if err != nil {
 		panic("failed to connect database:" + err.Error())
 	}
 	db.SingularTable(true)
 
-	if err := db.AutoMigrate(&TestModel{}).AutoMigrate(&Dog{}).
+	db = db.AutoMigrate(&TestModel{})
+	if db.Dialect().GetName() == dialect.SQLite.GetName() {
+		// SQLite can't add a foreign key to a table, so it's created with it
+		db = db.Exec(`CREATE TABLE "cat" ("id" text PRIMARY KEY, "created_at" datetime, "updated_at" datetime,
+			"deleted_at" datetime, "name" varchar(255), "color" varchar(255),
+			"test_model_id" text REFERENCES "test_model"(id) ON DELETE SET NULL ON UPDATE SET NULL)`)
+	}
+	db = db.AutoMigrate(&Dog{}).
 		AutoMigrate(&DogToy{}).AutoMigrate(&UnNested{}).AutoMigrate(&UnNestedInner{}).
-		AutoMigrate(&Cat{}).
-		AddForeignKey("test_model_id", "test_model(id)", "SET NULL", "SET NULL").Error; err != nil {
+		AutoMigrate(&VersionedModel{}).AutoMigrate(&VersionedChild{}).AutoMigrate(&Cat{}).
+		AutoMigrate(&Kennel{}).AutoMigrate(&Puppy{})
+	if db.Dialect().GetName() != dialect.SQLite.GetName() {
+		db = db.AddForeignKey("test_model_id", "test_model(id)", "SET NULL", "SET NULL")
+	}
+	if err := db.Error; err != nil {
 		panic("failed to automigrate TestModel:" + err.Error())
 	}
+	db = db.New()
 
 	// Both dog toys are under green Dogs
 	// log.Println("datatype.NewUUIDFromStringNoErr(dogtoyuuid1):", datatype.NewUUIDFromStringNoErr(dogtoyuuid1))
@@ -262,6 +339,7 @@ func TestMain(m *testing.M) {
 		Delete(&Dog{}).Delete(&DogToy{}).Delete(&UnNested{}).Delete(&UnNestedInner{}).Error; err != nil {
 		panic("something wrong with removing data from the db:" + err.Error())
 	}
+	cleanup()
 
 	os.Exit(exitVal)
 }
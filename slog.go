@@ -0,0 +1,44 @@
+//go:build go1.21
+
+package qry
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogHandler returns a handler which logs to l, with the fields of an entry as attributes
+// (sql, vars, duration, rows, source, error and plan)
+func NewSlogHandler(l *slog.Logger) LogHandler {
+	return slogHandler{l: l}
+}
+
+type slogHandler struct {
+	l *slog.Logger
+}
+
+func (h slogHandler) Enabled(level Level) bool {
+	return h.l.Enabled(context.Background(), slog.Level(level))
+}
+
+func (h slogHandler) Handle(e Entry) {
+	attrs := make([]slog.Attr, 0, 6)
+	if e.SQL != "" {
+		attrs = append(attrs,
+			slog.String("sql", e.SQL),
+			slog.Any("vars", formatVars(e.Vars)),
+			slog.Duration("duration", e.Duration),
+			slog.Int64("rows", e.Rows),
+		)
+	}
+	if e.Source != "" {
+		attrs = append(attrs, slog.String("source", e.Source))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("error", e.Err))
+	}
+	if e.Plan != "" {
+		attrs = append(attrs, slog.Any("plan", jsonOf(e.Plan)))
+	}
+	h.l.LogAttrs(context.Background(), slog.Level(e.Level), e.Message, attrs...)
+}
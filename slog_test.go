@@ -0,0 +1,35 @@
+//go:build go1.21
+
+package qry
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSlogHandler_ShouldLogFieldsAsAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	err := Q(db, C("Name =", "second")).WithLogHandler(NewSlogHandler(l)).First(&TestModel{}).Error()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	lines := jsonLines(t, &buf)
+	if assert.NotEmpty(t, lines) {
+		assert.Equal(t, "DEBUG", lines[0]["level"])
+		assert.Equal(t, "sql", lines[0]["msg"])
+		assert.Contains(t, lines[0]["sql"], "SELECT")
+		assert.Equal(t, float64(1), lines[0]["rows"])
+		assert.Contains(t, lines[0], "duration")
+		assert.Contains(t, lines[0]["source"], "slog_test.go")
+	}
+
+	h := NewSlogHandler(slog.New(slog.NewJSONHandler(&buf, nil)))
+	assert.False(t, h.Enabled(LevelDebug))
+	assert.True(t, h.Enabled(LevelWarn))
+}
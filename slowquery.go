@@ -0,0 +1,259 @@
+package qry
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/t2wu/qry/mdl"
+)
+
+// SlowQuery is what's logged of statements slower than Threshold, at LevelWarn with the
+// statement, its vars, the duration and the file:line of the query
+type SlowQuery struct {
+	Threshold time.Duration // 0 logs none
+
+	// ExplainRate is the fraction of slow statements which are explained (by the EXPLAIN of the
+	// dialect, such as EXPLAIN (FORMAT JSON) on Postgres), with the plan in Entry.Plan.
+	// 0 explains none and 1 explains every one. The statement is explained after the query, and not
+	// if it fails.
+	ExplainRate float64
+}
+
+var (
+	slowQueryMu sync.RWMutex
+	slowQuery   SlowQuery
+)
+
+// UseSlowQuery sets what's logged of slow statements of every query
+//
+//	qry.UseSlowQuery(qry.SlowQuery{Threshold: 200 * time.Millisecond, ExplainRate: 0.1})
+func UseSlowQuery(s SlowQuery) {
+	slowQueryMu.Lock()
+	defer slowQueryMu.Unlock()
+	slowQuery = s
+}
+
+// CurrentSlowQuery returns what's logged of slow statements
+func CurrentSlowQuery() SlowQuery {
+	slowQueryMu.RLock()
+	defer slowQueryMu.RUnlock()
+	return slowQuery
+}
+
+// ------------------
+
+// observedStatement is a statement run by a terminal
+type observedStatement struct {
+	sql      string
+	vars     []interface{}
+	duration time.Duration
+	rows     int64 // rows affected, -1 if it's a read (its rows are not known as they're read later)
+	err      error
+}
+
+// terminalRun is what a terminal runs, which each statement run with its context is reported to
+type terminalRun struct {
+	op        string // the terminal, such as Find
+	table     string // the table of the model of the terminal
+	source    string // file:line of the query
+	ctx       context.Context
+	handler   LogHandler
+	slowQuery SlowQuery
+	hooks     []Hook
+	comment   string // put before each statement (see SQLComment)
+	nPlusOne  NPlusOne
+
+	mu     sync.Mutex
+	slow   []observedStatement
+	shapes shapes
+}
+
+type terminalRunKey struct{}
+
+// observed is whether statements of a terminal are to be reported to its run
+func observed() bool {
+	return CurrentSlowQuery().Threshold > 0 || len(CurrentHooks()) > 0 || CurrentSQLComment().Enabled ||
+		CurrentNPlusOne().Threshold > 0
+}
+
+// newTerminalRun returns the run of terminal op on modelObjs, which is called by the function
+// calling it
+func newTerminalRun(op string, modelObjs interface{}, h LogHandler) *terminalRun {
+	r := &terminalRun{
+		op:        op,
+		handler:   h,
+		slowQuery: CurrentSlowQuery(),
+		hooks:     CurrentHooks(),
+		nPlusOne:  CurrentNPlusOne(),
+	}
+	if typ := modelTypeOf(modelObjs); typ != nil {
+		if m, ok := reflect.New(typ).Interface().(mdl.IModel); ok {
+			r.table = mdl.GetTableNameFromIModel(m)
+		}
+	}
+	r.source = callerSource(3)
+	return r
+}
+
+func withTerminalRun(ctx context.Context, r *terminalRun) context.Context {
+	r.ctx = ctx
+	if c := CurrentSQLComment(); c.Enabled {
+		r.comment = sqlCommentOf(c, ctx, r.source)
+	}
+	return context.WithValue(ctx, terminalRunKey{}, r)
+}
+
+func terminalRunOf(ctx context.Context) *terminalRun {
+	if ctx == nil {
+		return nil
+	}
+	r, _ := ctx.Value(terminalRunKey{}).(*terminalRun)
+	return r
+}
+
+// begin reports a statement about to be run to the run of ctx, if any, returning the function
+// to report it once it's run with the rows affected (-1 for a read) and its error
+func begin(ctx context.Context, sql string, vars []interface{}) func(rows int64, err error) {
+	r := terminalRunOf(ctx)
+	if r == nil {
+		return func(int64, error) {}
+	}
+
+	var info *StatementInfo
+	if len(r.hooks) > 0 {
+		info = &StatementInfo{
+			Context: r.ctx,
+			Op:      r.op,
+			Table:   r.table,
+			Source:  r.source,
+			SQL:     sql,
+			Vars:    redactVars(sql, vars),
+		}
+		for _, h := range r.hooks {
+			h.BeforeStatement(info)
+		}
+	}
+
+	start := time.Now()
+	return func(rows int64, err error) {
+		s := observedStatement{sql: sql, vars: vars, duration: time.Since(start), rows: rows, err: err}
+		if info != nil {
+			info.Start, info.Duration, info.Rows, info.Err = start, s.duration, rows, err
+			for i := len(r.hooks) - 1; i >= 0; i-- {
+				r.hooks[i].AfterStatement(info)
+			}
+		}
+		r.observe(s)
+	}
+}
+
+func (r *terminalRun) observe(s observedStatement) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nPlusOne.Threshold > 0 {
+		r.shapes.add(s.sql)
+	}
+	if r.slowQuery.Threshold > 0 && s.duration >= r.slowQuery.Threshold {
+		r.slow = append(r.slow, s)
+	}
+}
+
+// end logs the slow statements, explained on db, and the N+1 statements
+func (r *terminalRun) end(db executor) {
+	r.mu.Lock()
+	slow := r.slow
+	r.slow = nil
+	var detections []NPlusOneDetection
+	if r.nPlusOne.Threshold > 0 {
+		detections = r.shapes.detect(r, r.nPlusOne.Threshold)
+		r.shapes = shapes{}
+	}
+	r.mu.Unlock()
+
+	for _, d := range detections {
+		handle(r.handler, Entry{Level: LevelWarn, Message: d.String(), Source: r.source})
+		if r.nPlusOne.OnDetect != nil {
+			r.nPlusOne.OnDetect(d)
+		}
+	}
+
+	for _, s := range slow {
+		e := Entry{
+			Level:    LevelWarn,
+			Message:  "slow query",
+			SQL:      s.sql,
+			Vars:     redactVars(s.sql, s.vars),
+			Duration: s.duration,
+			Rows:     s.rows,
+			Source:   r.source,
+			Err:      s.err,
+		}
+		if s.err == nil && r.slowQuery.ExplainRate > 0 && rand.Float64() < r.slowQuery.ExplainRate {
+			if plan, err := explain(db, s); err == nil {
+				e.Plan = plan
+			} else {
+				e.Plan = fmt.Sprintf("cannot explain: %s", err)
+			}
+		}
+		handle(r.handler, e)
+	}
+}
+
+// explainable are statements which EXPLAIN doesn't run (without ANALYZE)
+var explainable = regexp.MustCompile(`(?i)^\s*(SELECT|INSERT|UPDATE|DELETE|WITH)\b`)
+
+// explain returns the plan of s, which is the JSON of the database, or rows as a JSON array
+// of objects if there is more than one (such as on SQLite)
+func explain(db executor, s observedStatement) (string, error) {
+	if !explainable.MatchString(s.sql) {
+		return "", fmt.Errorf("statement is not explainable")
+	}
+
+	rows, err := db.Rows(db.Dialect().Explain(s.sql), s.vars...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	objs := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		for i := range vals {
+			vals[i] = new(sql.NullString)
+		}
+		if err := rows.Scan(vals...); err != nil {
+			return "", err
+		}
+		obj := make(map[string]interface{})
+		for i, col := range cols {
+			if v := vals[i].(*sql.NullString); v.Valid {
+				obj[col] = v.String
+			} else {
+				obj[col] = nil
+			}
+		}
+		objs = append(objs, obj)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if len(objs) == 1 && len(cols) == 1 {
+		if plan, ok := objs[0][cols[0]].(string); ok {
+			return plan, nil
+		}
+	}
+	b, err := json.Marshal(objs)
+	return string(b), err
+}
@@ -0,0 +1,97 @@
+package qry
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func useSlowQuery(t *testing.T, s SlowQuery) {
+	prev := CurrentSlowQuery()
+	UseSlowQuery(s)
+	t.Cleanup(func() { UseSlowQuery(prev) })
+}
+
+func slowQueryLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	slow := make([]map[string]interface{}, 0)
+	for _, line := range jsonLines(t, buf) {
+		if line["msg"] == "slow query" {
+			slow = append(slow, line)
+		}
+	}
+	return slow
+}
+
+func TestSlowQuery_WhenOverThreshold_ShouldLogWithPlan(t *testing.T) {
+	useSlowQuery(t, SlowQuery{Threshold: time.Nanosecond, ExplainRate: 1})
+
+	var buf bytes.Buffer
+	err := Q(db, C("Name =", "second")).WithLogHandler(NewJSONHandler(&buf, LevelWarn)).First(&TestModel{}).Error()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	slow := slowQueryLines(t, &buf)
+	if !assert.NotEmpty(t, slow) {
+		return
+	}
+	assert.Equal(t, "WARN", slow[0]["level"])
+	assert.Contains(t, slow[0]["sql"], "SELECT")
+	assert.Equal(t, []interface{}{"'second'"}, slow[0]["vars"])
+	assert.Contains(t, slow[0]["source"], "slowquery_test.go")
+	if assert.Contains(t, slow[0], "plan") {
+		_, isString := slow[0]["plan"].(string) // "cannot explain: ..." otherwise
+		assert.False(t, isString, slow[0]["plan"])
+	}
+}
+
+func TestSlowQuery_WithoutExplainRate_ShouldLogWithoutPlan(t *testing.T) {
+	useSlowQuery(t, SlowQuery{Threshold: time.Nanosecond})
+	dbv2 := openGormV2(t)
+
+	var buf bytes.Buffer
+	tm := TestModel{Name: "first"}
+	if err := Q(dbv2).WithLogHandler(NewJSONHandler(&buf, LevelWarn)).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+
+	slow := slowQueryLines(t, &buf)
+	if assert.NotEmpty(t, slow) {
+		assert.Contains(t, slow[0]["sql"], "INSERT")
+		assert.Equal(t, float64(1), slow[0]["rows"])
+		assert.NotContains(t, slow[0], "plan")
+	}
+}
+
+func TestSlowQuery_OnWriteOutsideTransaction_ShouldLog(t *testing.T) {
+	useSlowQuery(t, SlowQuery{Threshold: time.Nanosecond})
+
+	var buf bytes.Buffer
+	tm := TestModel{Name: "outside"}
+	if err := Q(db).WithLogHandler(NewJSONHandler(&buf, LevelWarn)).Create(&tm).Error(); !assert.Nil(t, err) {
+		return
+	}
+	defer Q(db).Delete(&tm)
+
+	slow := slowQueryLines(t, &buf)
+	if assert.NotEmpty(t, slow) {
+		assert.Contains(t, slow[0]["sql"], "INSERT")
+		assert.Equal(t, float64(1), slow[0]["rows"])
+	}
+}
+
+func TestSlowQuery_WhenUnderThresholdOrDryRun_ShouldNotLog(t *testing.T) {
+	useSlowQuery(t, SlowQuery{Threshold: time.Hour, ExplainRate: 1})
+
+	var buf bytes.Buffer
+	q := DB(db).WithLogHandler(NewJSONHandler(&buf, LevelWarn))
+	assert.Nil(t, q.Q(C("Name =", "second")).First(&TestModel{}).Error())
+	assert.Empty(t, slowQueryLines(t, &buf))
+
+	UseSlowQuery(SlowQuery{Threshold: time.Nanosecond, ExplainRate: 1})
+	_, _, err := q.Q(C("Name =", "second")).ToSQL(&TestModel{}, QueryTypeFirst)
+	assert.Nil(t, err)
+	assert.Empty(t, slowQueryLines(t, &buf))
+}
@@ -0,0 +1,77 @@
+package qry
+
+import (
+	"sync"
+	"time"
+)
+
+// Span is a statement recorded by SpanRecorder
+type Span struct {
+	Name   string // qry. and the terminal, such as qry.Find
+	Op     string
+	Table  string
+	Source string
+	SQL    string
+
+	Start time.Time
+	End   time.Time
+	Rows  int64 // -1 if it's a read
+	Err   error
+}
+
+// Duration is how long the statement took
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// SpanRecorder is a hook which records a span of each statement in memory, such as to look
+// into in tests or serve from a debug endpoint
+type SpanRecorder struct {
+	max int
+
+	mu    sync.Mutex
+	spans []Span
+}
+
+// NewSpanRecorder returns a recorder which keeps the last max spans, every one if max is 0
+func NewSpanRecorder(max int) *SpanRecorder {
+	return &SpanRecorder{max: max}
+}
+
+func (r *SpanRecorder) BeforeStatement(s *StatementInfo) {
+}
+
+func (r *SpanRecorder) AfterStatement(s *StatementInfo) {
+	span := Span{
+		Name:   "qry." + s.Op,
+		Op:     s.Op,
+		Table:  s.Table,
+		Source: s.Source,
+		SQL:    s.SQL,
+		Start:  s.Start,
+		End:    s.Start.Add(s.Duration),
+		Rows:   s.Rows,
+		Err:    s.Err,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+	if r.max > 0 && len(r.spans) > r.max {
+		r.spans = append([]Span{}, r.spans[len(r.spans)-r.max:]...)
+	}
+}
+
+// Spans returns the spans recorded, oldest first
+func (r *SpanRecorder) Spans() []Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Span{}, r.spans...)
+}
+
+// Reset removes the spans recorded
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
@@ -0,0 +1,114 @@
+package qry
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SQLComment is the comment put before every statement run by a terminal, so it can be told
+// which query runs it (such as in pg_stat_activity), in the style of sqlcommenter:
+//
+//	/* file=service.go:120,route=/devices,trace=4bf92f3577b34da6 */ SELECT ...
+//
+// file is where the query is run from, followed by the tags of its context (see WithContext),
+// sorted by key.
+type SQLComment struct {
+	Enabled bool
+
+	// Tags returns more tags of the context, such as the trace of a span, in addition to those
+	// given by WithCommentTags
+	Tags func(ctx context.Context) map[string]string
+}
+
+var (
+	sqlCommentMu sync.RWMutex
+	sqlComment   SQLComment
+)
+
+// UseSQLComment sets the comment of the statements of every query
+//
+//	qry.UseSQLComment(qry.SQLComment{Enabled: true})
+func UseSQLComment(c SQLComment) {
+	sqlCommentMu.Lock()
+	defer sqlCommentMu.Unlock()
+	sqlComment = c
+}
+
+// CurrentSQLComment returns the comment of the statements of every query
+func CurrentSQLComment() SQLComment {
+	sqlCommentMu.RLock()
+	defer sqlCommentMu.RUnlock()
+	return sqlComment
+}
+
+type commentTagsKey struct{}
+
+// WithCommentTags returns ctx with the tags of keyvals, key and value in turn, which are commented
+// on the statements of the queries given it by WithContext
+//
+//	ctx = qry.WithCommentTags(ctx, "route", "/devices")
+func WithCommentTags(ctx context.Context, keyvals ...string) context.Context {
+	tags := make(map[string]string)
+	for k, v := range commentTagsOf(ctx) {
+		tags[k] = v
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		tags[keyvals[i]] = keyvals[i+1]
+	}
+	return context.WithValue(ctx, commentTagsKey{}, tags)
+}
+
+func commentTagsOf(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(commentTagsKey{}).(map[string]string)
+	return tags
+}
+
+// sqlCommentOf is the comment of statements run from source with ctx
+func sqlCommentOf(c SQLComment, ctx context.Context, source string) string {
+	tags := make(map[string]string)
+	if c.Tags != nil {
+		for k, v := range c.Tags(ctx) {
+			tags[k] = v
+		}
+	}
+	for k, v := range commentTagsOf(ctx) {
+		tags[k] = v
+	}
+	delete(tags, "file")
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys)+1)
+	if source != "" {
+		pairs = append(pairs, "file="+escapeCommentTag(filepath.Base(source)))
+	}
+	for _, k := range keys {
+		pairs = append(pairs, escapeCommentTag(k)+"="+escapeCommentTag(tags[k]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "/* " + strings.Join(pairs, ",") + " */"
+}
+
+var commentTagReplacer = strings.NewReplacer("%", "%25", ",", "%2C", "=", "%3D", "*/", "*%2F", "/*", "%2F*")
+
+// escapeCommentTag escapes what would end the comment or be taken as another tag
+func escapeCommentTag(s string) string {
+	return commentTagReplacer.Replace(s)
+}
+
+// commented is query with the comment of the run of ctx, if any
+func commented(ctx context.Context, query string) string {
+	if r := terminalRunOf(ctx); r != nil && r.comment != "" {
+		return r.comment + " " + query
+	}
+	return query
+}
@@ -0,0 +1,180 @@
+package qry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingConn is the connection of db which records the statements run on it
+type recordingConn struct {
+	*sql.DB
+
+	mu         sync.Mutex
+	statements []string
+}
+
+func (c *recordingConn) record(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, query)
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	c.record(query)
+	return c.DB.ExecContext(ctx, query, args...)
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	c.record(query)
+	return c.DB.QueryContext(ctx, query, args...)
+}
+
+func (c *recordingConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	c.record(query)
+	return c.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (c *recordingConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+func (c *recordingConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.QueryContext(context.Background(), query, args...)
+}
+
+func (c *recordingConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.QueryRowContext(context.Background(), query, args...)
+}
+
+func openRecordingDB(t *testing.T) (*gorm.DB, *recordingConn) {
+	conn := &recordingConn{DB: db.DB()}
+	rdb, err := gorm.Open(db.Dialect().GetName(), conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdb.SingularTable(true)
+	return rdb, conn
+}
+
+// recordingDriver is the driver of db which records the statements prepared, including the ones
+// within a transaction (which recordingConn doesn't see)
+type recordingDriver struct {
+	driver.Driver
+
+	mu         sync.Mutex
+	statements []string
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingDriverConn{Conn: conn, d: d}, nil
+}
+
+type recordingDriverConn struct {
+	driver.Conn
+	d *recordingDriver
+}
+
+func (c *recordingDriverConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.mu.Lock()
+	c.d.statements = append(c.d.statements, query)
+	c.d.mu.Unlock()
+	return c.Conn.Prepare(query)
+}
+
+var recordingDriverCounter uint64
+
+// openDriverRecordingDB opens the database of db with recordingDriver
+func openDriverRecordingDB(t *testing.T) (*gorm.DB, *recordingDriver) {
+	d := &recordingDriver{Driver: db.DB().Driver()}
+	name := fmt.Sprintf("qry-recording-%d", atomic.AddUint64(&recordingDriverCounter, 1))
+	sql.Register(name, d)
+
+	sqlDB, err := sql.Open(name, testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdb, err := gorm.Open(db.Dialect().GetName(), sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	rdb.SingularTable(true)
+	return rdb, d
+}
+
+func useSQLComment(t *testing.T, c SQLComment) {
+	prev := CurrentSQLComment()
+	UseSQLComment(c)
+	t.Cleanup(func() { UseSQLComment(prev) })
+}
+
+func TestSQLComment_ShouldBePutBeforeStatements(t *testing.T) {
+	useSQLComment(t, SQLComment{Enabled: true, Tags: func(ctx context.Context) map[string]string {
+		return map[string]string{"trace": "4bf92f35"}
+	}})
+	rdb, conn := openRecordingDB(t)
+
+	ctx := WithCommentTags(context.Background(), "route", "/devices")
+	ctx = WithCommentTags(ctx, "odd", "a,b=*/")
+	err := Q(rdb, C("Name =", "second")).WithContext(ctx).First(&TestModel{}).Error()
+	if !assert.Nil(t, err) || !assert.NotEmpty(t, conn.statements) {
+		return
+	}
+
+	for _, statement := range conn.statements {
+		assert.Regexp(t, `^/\* file=sqlcomment_test.go:\d+,odd=a%2Cb%3D\*%2F,route=/devices,trace=4bf92f35 \*/ SELECT `, statement)
+	}
+}
+
+func TestSQLComment_OnWritesOutsideTransaction_ShouldBePut(t *testing.T) {
+	useSQLComment(t, SQLComment{Enabled: true})
+	rdb, d := openDriverRecordingDB(t)
+
+	tm := TestModel{Name: "outside"}
+	if !assert.Nil(t, Q(rdb).Create(&tm).Error()) {
+		return
+	}
+	tm.Age = 7
+	assert.Nil(t, Q(rdb).Save(&tm).Error())
+	assert.Nil(t, Q(rdb).Delete(&tm).Error())
+
+	verbs := make(map[string]bool)
+	for _, statement := range d.statements {
+		for _, verb := range []string{"INSERT", "UPDATE", "DELETE"} {
+			if strings.Contains(statement, verb+" ") {
+				verbs[verb] = true
+				assert.Regexp(t, `^/\* file=sqlcomment_test.go:\d+ \*/ `+verb, statement)
+			}
+		}
+	}
+	assert.Equal(t, map[string]bool{"INSERT": true, "UPDATE": true, "DELETE": true}, verbs)
+}
+
+func TestSQLComment_WhenNotEnabled_ShouldNotBePut(t *testing.T) {
+	rdb, conn := openRecordingDB(t)
+
+	assert.Nil(t, Q(rdb, C("Name =", "second")).First(&TestModel{}).Error())
+	assert.NotEmpty(t, conn.statements)
+	for _, statement := range conn.statements {
+		assert.False(t, strings.HasPrefix(statement, "/*"), statement)
+	}
+}
+
+func TestSQLCommentOf_WithoutTags_ShouldOnlyHaveFile(t *testing.T) {
+	c := SQLComment{Enabled: true}
+	assert.Equal(t, "/* file=service.go:12 */", sqlCommentOf(c, context.Background(), "/src/app/service.go:12"))
+	assert.Equal(t, "", sqlCommentOf(c, context.Background(), ""))
+}
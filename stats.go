@@ -0,0 +1,148 @@
+package qry
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// statsSamples is the most durations kept of a fingerprint or a source for its P95
+const statsSamples = 1024
+
+// StatsCollector is a hook which aggregates the statements run by every query by their
+// Fingerprint and by the source of their query, as pg_stat_statements does but for the
+// statements of this process only
+//
+//	stats := qry.NewStatsCollector()
+//	qry.UseHooks(stats)
+//	...
+//	for _, s := range stats.Snapshot().ByFingerprint { ... }
+type StatsCollector struct {
+	mu            sync.Mutex
+	byFingerprint map[string]*statementStats
+	bySource      map[string]*statementStats
+	since         time.Time
+}
+
+type statementStats struct {
+	count   int64
+	errors  int64
+	total   time.Duration
+	samples []time.Duration // a reservoir sample of the durations
+}
+
+// StatementStats is the statistics of the statements of a fingerprint or a source
+type StatementStats struct {
+	Key string // the fingerprint or the source (file:line of the query)
+
+	Count     int64
+	Errors    int64
+	ErrorRate float64 // Errors of Count
+	Total     time.Duration
+	Mean      time.Duration
+	P95       time.Duration // estimated by a sample of up to 1024 durations
+}
+
+// StatsSnapshot is the statistics since the collector was created or reset, the most total
+// duration first
+type StatsSnapshot struct {
+	Since         time.Time
+	ByFingerprint []StatementStats
+	BySource      []StatementStats
+}
+
+// NewStatsCollector returns a collector to be used as a hook (see UseHooks)
+func NewStatsCollector() *StatsCollector {
+	c := &StatsCollector{}
+	c.Reset()
+	return c
+}
+
+func (c *StatsCollector) BeforeStatement(s *StatementInfo) {
+}
+
+func (c *StatsCollector) AfterStatement(s *StatementInfo) {
+	fingerprint := Fingerprint(s.SQL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(c.byFingerprint, fingerprint, s)
+	c.add(c.bySource, s.Source, s)
+}
+
+func (c *StatsCollector) add(m map[string]*statementStats, key string, s *StatementInfo) {
+	stats, ok := m[key]
+	if !ok {
+		stats = &statementStats{}
+		m[key] = stats
+	}
+
+	stats.count++
+	if s.Err != nil {
+		stats.errors++
+	}
+	stats.total += s.Duration
+	if len(stats.samples) < statsSamples {
+		stats.samples = append(stats.samples, s.Duration)
+	} else if i := rand.Int63n(stats.count); i < statsSamples {
+		stats.samples[i] = s.Duration
+	}
+}
+
+// Snapshot returns the statistics so far
+func (c *StatsCollector) Snapshot() StatsSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return StatsSnapshot{
+		Since:         c.since,
+		ByFingerprint: snapshotOf(c.byFingerprint),
+		BySource:      snapshotOf(c.bySource),
+	}
+}
+
+// Reset removes the statistics so far
+func (c *StatsCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byFingerprint = make(map[string]*statementStats)
+	c.bySource = make(map[string]*statementStats)
+	c.since = NowFunc()
+}
+
+func snapshotOf(m map[string]*statementStats) []StatementStats {
+	snapshot := make([]StatementStats, 0, len(m))
+	for key, stats := range m {
+		snapshot = append(snapshot, StatementStats{
+			Key:       key,
+			Count:     stats.count,
+			Errors:    stats.errors,
+			ErrorRate: float64(stats.errors) / float64(stats.count),
+			Total:     stats.total,
+			Mean:      stats.total / time.Duration(stats.count),
+			P95:       percentile(stats.samples, 0.95),
+		})
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Total != snapshot[j].Total {
+			return snapshot[i].Total > snapshot[j].Total
+		}
+		return snapshot[i].Key < snapshot[j].Key
+	})
+	return snapshot
+}
+
+// percentile is the duration which p of samples are no longer than (by the nearest rank)
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,88 @@
+package qry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsCollector_ShouldAggregateByFingerprintAndSource(t *testing.T) {
+	c := NewStatsCollector()
+	for i := 1; i <= 20; i++ {
+		c.AfterStatement(&StatementInfo{SQL: `SELECT * FROM "dog" WHERE "id" IN (?,?)`, Source: "a.go:1", Duration: time.Duration(i) * time.Millisecond})
+	}
+	c.AfterStatement(&StatementInfo{SQL: `SELECT * FROM "dog" WHERE "id" IN (?)`, Source: "b.go:2", Duration: time.Millisecond, Err: errors.New("bad")})
+	c.AfterStatement(&StatementInfo{SQL: `DELETE FROM "dog"`, Source: "b.go:2", Duration: time.Second})
+
+	snapshot := c.Snapshot()
+	if assert.Len(t, snapshot.ByFingerprint, 2) {
+		assert.Equal(t, StatementStats{Key: `DELETE FROM "dog"`, Count: 1, Total: time.Second, Mean: time.Second, P95: time.Second}, snapshot.ByFingerprint[0])
+
+		selects := snapshot.ByFingerprint[1]
+		assert.Equal(t, `SELECT * FROM "dog" WHERE "id" IN (?)`, selects.Key)
+		assert.Equal(t, int64(21), selects.Count)
+		assert.Equal(t, int64(1), selects.Errors)
+		assert.InDelta(t, 1.0/21, selects.ErrorRate, 1e-9)
+		assert.Equal(t, 211*time.Millisecond, selects.Total)
+		assert.Equal(t, 19*time.Millisecond, selects.P95)
+	}
+
+	if assert.Len(t, snapshot.BySource, 2) {
+		assert.Equal(t, "b.go:2", snapshot.BySource[0].Key)
+		assert.Equal(t, int64(2), snapshot.BySource[0].Count)
+		assert.Equal(t, 0.5, snapshot.BySource[0].ErrorRate)
+		assert.Equal(t, "a.go:1", snapshot.BySource[1].Key)
+		assert.Equal(t, 10500*time.Microsecond, snapshot.BySource[1].Mean)
+	}
+
+	c.Reset()
+	assert.Empty(t, c.Snapshot().ByFingerprint)
+}
+
+func TestStatsCollector_AsHook_ShouldCollectStatementsOfQueries(t *testing.T) {
+	c := NewStatsCollector()
+	useHooks(t, c)
+
+	for _, name := range []string{"first", "second"} {
+		assert.Nil(t, Q(db, C("Name =", name)).Find(&[]TestModel{}).Error())
+	}
+
+	snapshot := c.Snapshot()
+	if assert.NotEmpty(t, snapshot.ByFingerprint) {
+		assert.Contains(t, snapshot.ByFingerprint[0].Key, "SELECT")
+	}
+	if assert.Len(t, snapshot.BySource, 1) {
+		assert.Contains(t, snapshot.BySource[0].Key, "stats_test.go")
+	}
+	for _, s := range snapshot.ByFingerprint {
+		if s.Key == `SELECT * FROM "test_model" WHERE "test_model"."deleted_at" IS NULL AND (("test_model".real_name_column = ?)) ORDER BY "test_model".created_at DESC` {
+			assert.Equal(t, int64(2), s.Count)
+			return
+		}
+	}
+	t.Error("no statement of test_model", snapshot.ByFingerprint)
+}
+
+func TestStatsCollector_OnWritesOutsideTransaction_ShouldCollect(t *testing.T) {
+	c := NewStatsCollector()
+	useHooks(t, c)
+
+	tms := []*TestModel{{Name: "a"}, {Name: "b"}}
+	for _, tm := range tms {
+		if !assert.Nil(t, Q(db).Create(tm).Error()) {
+			return
+		}
+		defer Q(db).Delete(tm)
+	}
+
+	for _, s := range c.Snapshot().ByFingerprint {
+		if strings.HasPrefix(s.Key, `INSERT INTO "test_model"`) {
+			assert.Equal(t, int64(2), s.Count)
+			return
+		}
+	}
+	t.Error("no insert into test_model", c.Snapshot().ByFingerprint)
+}
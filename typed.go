@@ -0,0 +1,118 @@
+package qry
+
+import (
+	"context"
+
+	"github.com/t2wu/qry/mdl"
+)
+
+// ModelPtr is a pointer to a model of T, which is an mdl.IModel
+type ModelPtr[T any] interface {
+	*T
+	mdl.IModel
+}
+
+// TypedQuery is a query of the models of T, built and run as Q does, but typed at compile time
+//
+//	dogs, err := qry.For[Dog]().Where(qry.C("Color =", "green")).Order("Name", qry.OrderAsc).Find(db)
+type TypedQuery[T any, PT ModelPtr[T]] struct {
+	where  []*PredicateRelationBuilder
+	field  *string
+	order  Order
+	limit  *int
+	offset *int
+	ctx    context.Context
+}
+
+// For returns a query of the models of T, which *T is
+func For[T any, PT ModelPtr[T]]() *TypedQuery[T, PT] {
+	return &TypedQuery[T, PT]{}
+}
+
+// Where adds the criteria, as those given to Q
+func (t *TypedQuery[T, PT]) Where(preds ...*PredicateRelationBuilder) *TypedQuery[T, PT] {
+	t.where = append(t.where, preds...)
+	return t
+}
+
+func (t *TypedQuery[T, PT]) Order(field string, order Order) *TypedQuery[T, PT] {
+	t.field, t.order = &field, order
+	return t
+}
+
+func (t *TypedQuery[T, PT]) Limit(limit int) *TypedQuery[T, PT] {
+	t.limit = &limit
+	return t
+}
+
+func (t *TypedQuery[T, PT]) Offset(offset int) *TypedQuery[T, PT] {
+	t.offset = &offset
+	return t
+}
+
+// WithContext runs the query with ctx, as IQuery.WithContext
+func (t *TypedQuery[T, PT]) WithContext(ctx context.Context) *TypedQuery[T, PT] {
+	t.ctx = ctx
+	return t
+}
+
+// query is the IQuery on db which t is built as
+func (t *TypedQuery[T, PT]) query(db interface{}) IQuery {
+	args := make([]interface{}, 0, len(t.where))
+	for _, p := range t.where {
+		args = append(args, p)
+	}
+
+	q := Q(db, args...)
+	if t.ctx != nil {
+		q = q.WithContext(t.ctx)
+	}
+	if t.field != nil {
+		q = q.Order(*t.field, t.order)
+	}
+	if t.limit != nil {
+		q = q.Limit(*t.limit)
+	}
+	if t.offset != nil {
+		q = q.Offset(*t.offset)
+	}
+	return q
+}
+
+// Find returns the models which are found on db, a *gorm.DB of Gorm v1 or v2
+func (t *TypedQuery[T, PT]) Find(db interface{}) ([]T, error) {
+	modelObjs := make([]T, 0)
+	if err := t.query(db).Find(&modelObjs).Error(); err != nil {
+		return nil, err
+	}
+	return modelObjs, nil
+}
+
+// First returns the first model which is found on db, or ErrNotFound
+func (t *TypedQuery[T, PT]) First(db interface{}) (*T, error) {
+	modelObj := PT(new(T))
+	if err := t.query(db).First(modelObj).Error(); err != nil {
+		return nil, err
+	}
+	return (*T)(modelObj), nil
+}
+
+// Count returns the number of models which are found on db
+func (t *TypedQuery[T, PT]) Count(db interface{}) (int, error) {
+	var no int
+	if err := t.query(db).Count(PT(new(T)), &no).Error(); err != nil {
+		return 0, err
+	}
+	return no, nil
+}
+
+// Delete deletes the models which are found on db, returning how many are deleted. Without
+// criteria, it's an UnsafeDeleteError.
+func (t *TypedQuery[T, PT]) Delete(db interface{}) (int64, error) {
+	q := t.query(db).Delete(PT(new(T)))
+	rowsAffected := q.RowsAffected()
+	if err := q.Error(); err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
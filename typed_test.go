@@ -0,0 +1,84 @@
+package qry
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFor_Find_ShouldBeTyped(t *testing.T) {
+	tms, err := For[TestModel]().Where(C("Name =", "same")).Order("Age", OrderAsc).Find(db)
+	if !assert.Nil(t, err) || !assert.Len(t, tms, 3) {
+		return
+	}
+	assert.Equal(t, uuid3, tms[0].ID.String())
+	assert.Equal(t, "same", tms[2].Name)
+	assert.Len(t, tms[0].Dogs, 2)
+}
+
+func TestFor_Find_WithLimitOffsetAndNestedCriteria(t *testing.T) {
+	tms, err := For[TestModel]().Where(C("Dogs.Color =", "green")).Order("Age", OrderAsc).Limit(1).Offset(1).Find(db)
+	if assert.Nil(t, err) && assert.Len(t, tms, 1) {
+		assert.Equal(t, uuid5, tms[0].ID.String())
+	}
+}
+
+func TestFor_First_ShouldBeTypedOrNotFound(t *testing.T) {
+	tm, err := For[TestModel]().Where(C("Name =", "second")).First(db)
+	if assert.Nil(t, err) {
+		assert.Equal(t, uuid2, tm.ID.String())
+	}
+
+	tm, err = For[TestModel]().Where(C("Name =", "notexist")).First(db)
+	assert.Nil(t, tm)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFor_Count(t *testing.T) {
+	no, err := For[TestModel]().Where(C("Age >=", 3)).Count(db)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, no)
+}
+
+func TestFor_Delete_ShouldDeleteWhatsFound(t *testing.T) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	deleted, err := For[Dog]().Where(C("Color =", "green")).Delete(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	no, err := For[Dog]().Where(C("Color =", "green")).Count(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, no)
+}
+
+func TestFor_Delete_WithoutCriteria_ShouldBeUnsafe(t *testing.T) {
+	_, err := For[Dog]().Delete(db)
+	var unsafeErr *UnsafeDeleteError
+	assert.ErrorAs(t, err, &unsafeErr)
+}
+
+func TestFor_WithContext_ShouldBeCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := For[TestModel]().WithContext(ctx).Find(db)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFor_Source_ShouldBeTheCallerOfTheTypedQuery(t *testing.T) {
+	var buf bytes.Buffer
+	prev := CurrentLogHandler()
+	UseLogHandler(NewJSONHandler(&buf, LevelDebug))
+	defer UseLogHandler(prev)
+
+	_, err := For[TestModel]().Where(C("Name =", "second")).First(db)
+	assert.Nil(t, err)
+	lines := jsonLines(t, &buf)
+	if assert.NotEmpty(t, lines) {
+		assert.Contains(t, lines[0]["source"], "typed_test.go")
+	}
+}
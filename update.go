@@ -0,0 +1,520 @@
+package qry
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/t2wu/qry/datatype"
+	"github.com/t2wu/qry/dialect"
+	"github.com/t2wu/qry/mdl"
+
+	"github.com/jinzhu/gorm"
+)
+
+// UpdateExpr is a value in UpdateFields which is computed by the database instead of being
+// set as it is, such as Inc("Age", 1) or Now()
+type UpdateExpr interface {
+	// BuildUpdateStringAndValues outputs the right-hand side of "column = ..." and its values
+	// modelObj is the mdl the updated field belongs to (the inner one if the field is nested)
+	BuildUpdateStringAndValues(modelObj mdl.IModel) (string, []interface{}, error)
+}
+
+// UpdateExprEvaluator is an UpdateExpr which can also be computed on the mdl itself, for
+// stores which are not a database (such as qrymem)
+type UpdateExprEvaluator interface {
+	// EvaluateOn outputs the value the updated field of modelObj would be set to
+	EvaluateOn(modelObj mdl.IModel) (interface{}, error)
+}
+
+// Inc sets the updated field to the value of field (of the same mdl) plus delta
+// Use a negative delta to decrement.
+func Inc(field string, delta interface{}) UpdateExpr {
+	return &incExpr{field: field, delta: delta}
+}
+
+type incExpr struct {
+	field string
+	delta interface{}
+}
+
+func (e *incExpr) BuildUpdateStringAndValues(modelObj mdl.IModel) (string, []interface{}, error) {
+	col, err := fieldToColumn(modelObj, e.field)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s + ?", col), []interface{}{e.delta}, nil
+}
+
+func (e *incExpr) EvaluateOn(modelObj mdl.IModel) (interface{}, error) {
+	if _, err := fieldToColumn(modelObj, e.field); err != nil {
+		return nil, err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(modelObj)).FieldByName(e.field)
+	delta := reflect.ValueOf(e.delta)
+	sum := reflect.New(v.Type()).Elem()
+	switch {
+	case v.CanInt() && delta.CanInt():
+		sum.SetInt(v.Int() + delta.Int())
+	case v.CanInt() && delta.CanUint():
+		sum.SetInt(v.Int() + int64(delta.Uint()))
+	case v.CanUint() && delta.CanInt():
+		sum.SetUint(uint64(int64(v.Uint()) + delta.Int()))
+	case v.CanUint() && delta.CanUint():
+		sum.SetUint(v.Uint() + delta.Uint())
+	case v.CanFloat() && delta.CanFloat():
+		sum.SetFloat(v.Float() + delta.Float())
+	case v.CanFloat() && delta.CanInt():
+		sum.SetFloat(v.Float() + float64(delta.Int()))
+	default:
+		return nil, newBuilderError("cannot increment field \"%s\" by %v", e.field, e.delta)
+	}
+	return sum.Interface(), nil
+}
+
+// Now sets the updated field to the database's current time
+func Now() UpdateExpr {
+	return &nowExpr{}
+}
+
+type nowExpr struct{}
+
+func (e *nowExpr) BuildUpdateStringAndValues(modelObj mdl.IModel) (string, []interface{}, error) {
+	return "CURRENT_TIMESTAMP", []interface{}{}, nil
+}
+
+func (e *nowExpr) EvaluateOn(modelObj mdl.IModel) (interface{}, error) {
+	return gorm.NowFunc(), nil
+}
+
+// updateFieldsFromPredicateRelation turns something like C("Age =", 3).And("Name =", "same")
+// into field -> value for update
+func updateFieldsFromPredicateRelation(rel *PredicateRelation) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	for _, logic := range rel.Logics {
+		if logic != PredicateLogicAND {
+			return nil, newBuilderError("only AND is allowed in update")
+		}
+	}
+
+	for _, pr := range rel.PredOrRels {
+		switch c := pr.(type) {
+		case *Predicate:
+			if c.Cond != PredicateCondEQ {
+				return nil, newBuilderError("only \"=\" is allowed in update")
+			}
+			fields[c.Field] = c.Value
+		case *PredicateRelation:
+			inner, err := updateFieldsFromPredicateRelation(c)
+			if err != nil {
+				return nil, err
+			}
+			for field, value := range inner {
+				fields[field] = value
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// updateFieldsCore updates fields on mdl selected by subQueryOfIDs, which selects ids of modelObj
+// Fields of nested mdls are updated on their own table, going through the foreign keys
+// If modelObj is versioned, its version is incremented, and if checkVersion it has to be current
+// nestedCriteria are the criteria of the query on nested mdls by designator (such as "Dogs"), which
+// the nested mdls updated have to meet as well
+// If returning is given, the updated rows of modelObj's own table are scanned into it
+// Returns rows affected
+func updateFieldsCore(db executor, modelObj mdl.IModel, subQueryOfIDs interface{}, fields map[string]interface{},
+	nestedCriteria map[string][]*PredicateRelation, checkVersion bool, returning interface{}) (int64, error) {
+	if len(fields) == 0 {
+		return 0, newBuilderError("update must have at least one field")
+	}
+
+	// Field "Dogs.Color" is updated on designator "Dogs"
+	designatorToFields := make(map[string][]string)
+	for field := range fields {
+		if _, err := mdl.FieldNameToColumn(modelObj, field); err != nil {
+			return 0, err
+		}
+
+		designator := ""
+		if strings.Contains(field, ".") {
+			designator = field[:strings.LastIndex(field, ".")]
+		}
+		designatorToFields[designator] = append(designatorToFields[designator], field)
+	}
+
+	// Innermost first. Otherwise the outer criteria may no longer match by the time
+	// the inner ones are updated
+	designators := make([]string, 0, len(designatorToFields))
+	for designator := range designatorToFields {
+		designators = append(designators, designator)
+	}
+	level := func(designator string) int {
+		if designator == "" {
+			return 0
+		}
+		return strings.Count(designator, ".") + 1
+	}
+	sort.Slice(designators, func(i, j int) bool {
+		if level(designators[i]) != level(designators[j]) {
+			return level(designators[i]) > level(designators[j])
+		}
+		return designators[i] < designators[j]
+	})
+
+	// Versioning is of the top-level mdl only, unless the version is set explicitly
+	version, versioned := mdl.GetVersion(modelObj)
+	if versionFieldName, ok := mdl.GetVersionFieldName(modelObj); ok {
+		if _, ok := fields[versionFieldName]; ok {
+			versioned = false
+		}
+	}
+	versionIncremented := false
+
+	// Without RETURNING (such as on MySQL), the rows are updated by their ids selected before,
+	// and read back after
+	var readBack interface{}
+	if returning != nil && !db.Dialect().Returning() && len(designatorToFields[""]) > 0 {
+		ids, err := selectIDs(db, modelObj, subQueryOfIDs)
+		if err != nil {
+			return 0, err
+		}
+		subQueryOfIDs, readBack, returning = ids, returning, nil
+	}
+
+	var rowsAffected int64
+	for _, designator := range designators {
+		currModelObj := modelObj
+		if designator != "" {
+			var err error
+			if currModelObj, err = mdl.GetInnerModelIfValid(modelObj, designator); err != nil {
+				return 0, err
+			}
+		}
+
+		setStr, setVals, err := buildSetStringAndValues(currModelObj, designatorToFields[designator], fields)
+		if err != nil {
+			return 0, err
+		}
+
+		whereStr, whereVals, err := buildWhereNestedInIDs(db.Dialect(), modelObj, designator, subQueryOfIDs, nestedCriteria)
+		if err != nil {
+			return 0, err
+		}
+
+		tblName := db.Dialect().Quote(mdl.GetTableNameFromIModel(currModelObj))
+		if versioned && designator == "" {
+			col, err := versionColumn(modelObj)
+			if err != nil {
+				return 0, err
+			}
+			setStr += fmt.Sprintf(", %s = %s + 1", col, col)
+			if checkVersion {
+				whereStr += fmt.Sprintf(" AND %s.%s = ?", tblName, col)
+				whereVals = append(whereVals, version)
+			}
+		}
+
+		stmt := fmt.Sprintf("UPDATE %s SET %s WHERE %s", tblName, setStr, whereStr)
+		var currReturning interface{}
+		if designator == "" {
+			currReturning = returning
+		}
+		currRowsAffected, err := execMaybeReturning(db, currReturning, stmt, append(setVals, whereVals...)...)
+		if err != nil {
+			return 0, err
+		}
+		if versioned && designator == "" && checkVersion {
+			if currRowsAffected == 0 {
+				return 0, &StaleObjectError{Table: mdl.GetTableNameFromIModel(currModelObj), ID: modelObj.GetID(), Version: version}
+			}
+			versionIncremented = true
+		}
+		rowsAffected += currRowsAffected
+	}
+
+	if versionIncremented {
+		mdl.SetVersion(modelObj, version+1)
+	}
+
+	if readBack != nil {
+		if err := readBackByIDs(db, modelObj, subQueryOfIDs.([]*datatype.UUID), readBack); err != nil {
+			return 0, err
+		}
+	}
+
+	return rowsAffected, nil
+}
+
+func buildSetStringAndValues(modelObj mdl.IModel, fields []string, fieldToValue map[string]interface{}) (string, []interface{}, error) {
+	sort.Strings(fields)
+
+	sets := make([]string, 0, len(fields))
+	vals := make([]interface{}, 0, len(fields))
+	hasUpdatedAt := false
+	for _, field := range fields {
+		toks := strings.Split(field, ".")
+		name := toks[len(toks)-1]
+		if name == "UpdatedAt" {
+			hasUpdatedAt = true
+		}
+
+		typ, err := mdl.GetModelFieldTypeInModelIfValid(modelObj, name)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, ok := reflect.New(typ).Interface().(mdl.IModel); ok {
+			return "", nil, newBuilderError("field \"%s\" is not a column", field)
+		}
+
+		col, err := fieldToColumn(modelObj, name)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if expr, ok := fieldToValue[field].(UpdateExpr); ok {
+			s, exprVals, err := expr.BuildUpdateStringAndValues(modelObj)
+			if err != nil {
+				return "", nil, err
+			}
+			sets = append(sets, fmt.Sprintf("%s = %s", col, s))
+			vals = append(vals, exprVals...)
+		} else {
+			sets = append(sets, fmt.Sprintf("%s = ?", col))
+			vals = append(vals, fieldToValue[field])
+		}
+	}
+
+	// Keep the same behavior as Gorm's update
+	if _, err := fieldToColumn(modelObj, "UpdatedAt"); err == nil && !hasUpdatedAt {
+		sets = append(sets, "updated_at = ?")
+		vals = append(vals, gorm.NowFunc())
+	}
+
+	return strings.Join(sets, ", "), vals, nil
+}
+
+// buildWhereNestedInIDs builds the where clause for the mdl designated within modelObj, whose
+// (grand) parent's ids are selected by subQueryOfIDs, and which (with its parents) meets
+// nestedCriteria on its designator
+// For example, with designator "Dogs.DogToys" it outputs
+// "dog_toy".dog_id IN (SELECT "dog".id FROM "dog" WHERE "dog".test_model_id IN (subQueryOfIDs))
+func buildWhereNestedInIDs(d dialect.Dialect, modelObj mdl.IModel, designator string, subQueryOfIDs interface{},
+	nestedCriteria map[string][]*PredicateRelation) (string, []interface{}, error) {
+	if designator == "" {
+		tblName := d.Quote(mdl.GetTableNameFromIModel(modelObj))
+		if _, ok := subQueryOfIDs.([]*datatype.UUID); !ok && d.UpdateJoin() {
+			// The table updated can't be selected from in a sub query, but from a derived table of it
+			// which is materialized rather than merged into the sub query (so NO_MERGE)
+			return fmt.Sprintf("%s.id IN (SELECT /*+ NO_MERGE(ids) */ id FROM (?) AS ids)", tblName), []interface{}{subQueryOfIDs}, nil
+		}
+		return fmt.Sprintf("%s.id IN (?)", tblName), []interface{}{subQueryOfIDs}, nil
+	}
+
+	tableNameAt := func(toks []string) (string, error) {
+		if len(toks) == 0 {
+			return mdl.GetTableNameFromIModel(modelObj), nil
+		}
+		return mdl.GetModelTableNameInModelIfValid(modelObj, strings.Join(toks, "."))
+	}
+
+	toks := strings.Split(designator, ".")
+	var where string
+	vals := []interface{}{subQueryOfIDs}
+	for i := range toks {
+		upperTableName, err := tableNameAt(toks[:i])
+		if err != nil {
+			return "", nil, err
+		}
+		currTableName, err := tableNameAt(toks[:i+1])
+		if err != nil {
+			return "", nil, err
+		}
+
+		if i == 0 {
+			where = fmt.Sprintf("%s.%s_id IN (?)", d.Quote(currTableName), upperTableName)
+		} else {
+			where = fmt.Sprintf("%s.%s_id IN (SELECT %s.id FROM %s WHERE %s)",
+				d.Quote(currTableName), upperTableName, d.Quote(upperTableName), d.Quote(upperTableName), where)
+		}
+
+		for _, rel := range nestedCriteria[strings.Join(toks[:i+1], ".")] {
+			s, relVals, err := rel.buildQueryStringAndValues(modelObj, d)
+			if err != nil {
+				return "", nil, err
+			}
+			where += fmt.Sprintf(" AND (%s)", s)
+			vals = append(vals, relVals...)
+		}
+	}
+
+	return where, vals, nil
+}
+
+// updateManyChunkSize is the number of mdls updated in one statement by UpdateMany
+// Each one is a term of a compound SELECT, and SQLite allows up to 500 of them
+const updateManyChunkSize = 250
+
+// updateManyCore updates fields of each mdl to its own values, one statement per chunk
+// Returns rows affected
+func updateManyCore(db executor, modelObjs []mdl.IModel, fields []string) (int64, error) {
+	if len(fields) == 0 {
+		return 0, newBuilderError("update many must have at least one field")
+	}
+
+	typ := reflect.TypeOf(modelObjs[0])
+	cols := make([]string, len(fields))
+	for i, field := range fields {
+		if strings.Contains(field, ".") {
+			return 0, newBuilderError("dot notation in update many")
+		}
+
+		ftyp, err := mdl.GetModelFieldTypeInModelIfValid(modelObjs[0], field)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := reflect.New(ftyp).Interface().(mdl.IModel); ok {
+			return 0, newBuilderError("field \"%s\" is not a column", field)
+		}
+
+		if cols[i], err = fieldToColumn(modelObjs[0], field); err != nil {
+			return 0, err
+		}
+	}
+
+	// Versioned, unless the version is set explicitly
+	versionCol := ""
+	if versionFieldName, ok := mdl.GetVersionFieldName(modelObjs[0]); ok {
+		var err error
+		if versionCol, err = fieldToColumn(modelObjs[0], versionFieldName); err != nil {
+			return 0, err
+		}
+		for _, field := range fields {
+			if field == versionFieldName {
+				versionCol = ""
+			}
+		}
+	}
+
+	// A duplicate would be updated to either one of the values, and can't be told from a stale one
+	ids := make(map[string]bool, len(modelObjs))
+	for _, modelObj := range modelObjs {
+		if reflect.TypeOf(modelObj) != typ {
+			return 0, newBuilderError("update many must have mdls of the same type")
+		}
+		if modelObj.GetID() == nil {
+			return 0, newBuilderError("modelObj to update cannot have an ID of nil")
+		}
+		if ids[modelObj.GetID().String()] {
+			return 0, newBuilderError("update many has more than one mdl of ID %s", modelObj.GetID().String())
+		}
+		ids[modelObj.GetID().String()] = true
+	}
+
+	var rowsAffected int64
+	for start := 0; start < len(modelObjs); start += updateManyChunkSize {
+		end := start + updateManyChunkSize
+		if end > len(modelObjs) {
+			end = len(modelObjs)
+		}
+
+		stmt, vals := buildUpdateManyStatement(db.Dialect(), modelObjs[start:end], fields, cols, versionCol)
+		currRowsAffected, err := db.Exec(stmt, vals...)
+		if err != nil {
+			return 0, err
+		}
+		if versionCol != "" && currRowsAffected != int64(end-start) {
+			// Can't tell which one, so the whole thing is rolled back
+			return 0, &StaleObjectError{Table: mdl.GetTableNameFromIModel(modelObjs[0])}
+		}
+		rowsAffected += currRowsAffected
+	}
+
+	if versionCol != "" {
+		for _, modelObj := range modelObjs {
+			version, _ := mdl.GetVersion(modelObj)
+			mdl.SetVersion(modelObj, version+1)
+		}
+	}
+
+	return rowsAffected, nil
+}
+
+// buildUpdateManyStatement builds something like
+// UPDATE "dog" SET color = v.color FROM (SELECT "dog".id, "dog".color FROM "dog" WHERE false
+// UNION ALL SELECT ?, ? UNION ALL SELECT ?, ?) AS v WHERE "dog".id = v.id
+// which is UPDATE ... FROM (VALUES ...) v, except that the empty SELECT gives the values their
+// column names and types. (Postgres takes placeholders within VALUES as text, and there is no
+// uuid = text.)
+// If versionCol is given, only the ones still at their versions are updated, and the versions
+// are incremented
+// Where an UPDATE joins (such as on MySQL), it's UPDATE "dog" JOIN (...) AS v ON "dog".id = v.id
+// SET "dog".color = v.color instead.
+func buildUpdateManyStatement(d dialect.Dialect, modelObjs []mdl.IModel, fields []string, cols []string, versionCol string) (string, []interface{}) {
+	tblName := d.Quote(mdl.GetTableNameFromIModel(modelObjs[0]))
+
+	// Joined (as MySQL does), the columns set are qualified, as they're of both tables
+	setCol := func(col string) string {
+		if d.UpdateJoin() {
+			return fmt.Sprintf("%s.%s", tblName, col)
+		}
+		return col
+	}
+
+	selects := make([]string, 0, len(cols)+1)
+	selects = append(selects, fmt.Sprintf("%s.id", tblName))
+	sets := make([]string, 0, len(cols)+1)
+	hasUpdatedAt := false
+	for _, col := range cols {
+		selects = append(selects, fmt.Sprintf("%s.%s", tblName, col))
+		sets = append(sets, fmt.Sprintf("%s = v.%s", setCol(col), col))
+		if col == "updated_at" {
+			hasUpdatedAt = true
+		}
+	}
+
+	where := fmt.Sprintf("%s.id = v.id", tblName)
+	numVals := len(fields) + 1
+	if versionCol != "" {
+		selects = append(selects, fmt.Sprintf("%s.%s", tblName, versionCol))
+		sets = append(sets, fmt.Sprintf("%s = %s.%s + 1", setCol(versionCol), tblName, versionCol))
+		where += fmt.Sprintf(" AND %s.%s = v.%s", tblName, versionCol, versionCol)
+		numVals++
+	}
+
+	// Keep the same behavior as Gorm's update
+	setVals := make([]interface{}, 0, 1)
+	if _, err := fieldToColumn(modelObjs[0], "UpdatedAt"); err == nil && !hasUpdatedAt {
+		sets = append(sets, fmt.Sprintf("%s = ?", setCol("updated_at")))
+		setVals = append(setVals, gorm.NowFunc())
+	}
+
+	placeholders := "SELECT " + strings.TrimSuffix(strings.Repeat("?, ", numVals), ", ")
+	rows := make([]string, len(modelObjs))
+	rowVals := make([]interface{}, 0, len(modelObjs)*numVals)
+	for i, modelObj := range modelObjs {
+		rows[i] = placeholders
+		v := reflect.Indirect(reflect.ValueOf(modelObj))
+		rowVals = append(rowVals, modelObj.GetID())
+		for _, field := range fields {
+			rowVals = append(rowVals, v.FieldByName(field).Interface())
+		}
+		if versionCol != "" {
+			version, _ := mdl.GetVersion(modelObj)
+			rowVals = append(rowVals, version)
+		}
+	}
+	values := fmt.Sprintf("(SELECT %s FROM %s WHERE false UNION ALL %s) AS v",
+		strings.Join(selects, ", "), tblName, strings.Join(rows, " UNION ALL "))
+
+	if d.UpdateJoin() {
+		stmt := fmt.Sprintf("UPDATE %s JOIN %s ON %s SET %s", tblName, values, where, strings.Join(sets, ", "))
+		return stmt, append(rowVals, setVals...)
+	}
+	stmt := fmt.Sprintf("UPDATE %s SET %s FROM %s WHERE %s", tblName, strings.Join(sets, ", "), values, where)
+	return stmt, append(setVals, rowVals...)
+}
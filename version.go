@@ -0,0 +1,125 @@
+package qry
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/t2wu/qry/mdl"
+)
+
+// Optimistic locking
+// A mdl is versioned if it is mdl.IVersioned or has an int field tagged with `qry:"version"`.
+// Saving or updating a versioned mdl by its ID only succeeds if the record is still at the
+// version the mdl has, and the version is incremented. Otherwise it's ErrStaleObject.
+
+// saveModelCheckVersion saves modelObj, and the nested mdls Gorm saves along with it, checking and
+// incrementing the versions of the ones versioned
+// This should be run within a transaction.
+func saveModelCheckVersion(db executor, modelObj mdl.IModel) error {
+	return saveCheckVersions(db, modelObj, modelsSavedWith(modelObj))
+}
+
+// saveOwnColumnsCheckVersion saves modelObj's own columns only (no nested mdls), checking and
+// incrementing its version if it is versioned
+// This should be run within a transaction.
+func saveOwnColumnsCheckVersion(db executor, modelObj mdl.IModel) error {
+	return saveCheckVersions(db.WithoutAssociations(), modelObj, []mdl.IModel{modelObj})
+}
+
+// saveCheckVersions saves modelObj after incrementing the versions of the mdls saved (modelObj
+// and whichever nested mdls are saved with it) which are versioned. If any of them is stale,
+// nothing is saved.
+func saveCheckVersions(db executor, modelObj mdl.IModel, saved []mdl.IModel) error {
+	versions := make(map[mdl.IModel]int)
+	restore := func() {
+		for m, version := range versions {
+			mdl.SetVersion(m, version)
+		}
+	}
+
+	for _, m := range saved {
+		version, versioned := mdl.GetVersion(m)
+		if !versioned || m.GetID() == nil { // no ID is create
+			continue
+		}
+		if err := incrementVersionIfCurrent(db, m, version); err != nil {
+			restore()
+			return err
+		}
+		versions[m] = version
+		mdl.SetVersion(m, version+1)
+	}
+
+	if err := db.Save(modelObj); err != nil {
+		restore()
+		return err
+	}
+
+	return nil
+}
+
+// modelsSavedWith returns modelObj and the nested mdls Gorm saves along with it, which are the
+// ones of the associations not tagged with association_autoupdate:false, all the way down
+func modelsSavedWith(modelObj mdl.IModel) []mdl.IModel {
+	ms := []mdl.IModel{modelObj}
+	seen := map[mdl.IModel]bool{modelObj: true}
+	for i := 0; i < len(ms); i++ {
+		v := reflect.Indirect(reflect.ValueOf(ms[i]))
+		for j := 0; j < v.NumField(); j++ {
+			field := v.Type().Field(j)
+			if field.Anonymous || strings.Contains(field.Tag.Get("gorm"), "association_autoupdate:false") {
+				continue
+			}
+			for _, m := range nestedModelsAtField(v.Field(j)) {
+				if !seen[m] {
+					seen[m] = true
+					ms = append(ms, m)
+				}
+			}
+		}
+	}
+	return ms
+}
+
+// anyVersioned returns whether any of modelObjs is versioned
+func anyVersioned(modelObjs []mdl.IModel) bool {
+	for _, modelObj := range modelObjs {
+		if _, versioned := mdl.GetVersion(modelObj); versioned {
+			return true
+		}
+	}
+	return false
+}
+
+// incrementVersionIfCurrent increments the version of modelObj's record if it is still at version
+// This also locks the record until the end of the transaction
+func incrementVersionIfCurrent(db executor, modelObj mdl.IModel, version int) error {
+	col, err := versionColumn(modelObj)
+	if err != nil {
+		return err
+	}
+
+	tblName := mdl.GetTableNameFromIModel(modelObj)
+	quoted := db.Dialect().Quote(tblName)
+	stmt := fmt.Sprintf("UPDATE %s SET %s = %s + 1 WHERE %s.id = ? AND %s.%s = ?",
+		quoted, col, col, quoted, quoted, col)
+	rowsAffected, err := db.Exec(stmt, modelObj.GetID(), version)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return &StaleObjectError{Table: tblName, ID: modelObj.GetID(), Version: version}
+	}
+
+	return nil
+}
+
+// versionColumn returns the column of the version field of modelObj
+func versionColumn(modelObj mdl.IModel) (string, error) {
+	fieldName, ok := mdl.GetVersionFieldName(modelObj)
+	if !ok {
+		return "", newBuilderError("\"%s\" is not versioned", mdl.GetTableNameFromIModel(modelObj))
+	}
+	return fieldToColumn(modelObj, fieldName)
+}